@@ -10,6 +10,8 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/Molorius/ulp-c/pkg/asm"
 	"github.com/Molorius/ulp-forth/pkg/forth"
@@ -21,16 +23,30 @@ const CmdReserved = "reserved"
 const CmdAssembly = "assembly"
 const CmdCustomAssembly = "custom_assembly"
 const CmdSubroutineThreading = "subroutine"
+const CmdDirectThreading = "direct"
+const CmdMixedThreading = "mixed"
+const CmdSequences = "sequences"
+const CmdUncheckedStack = "unchecked-stack"
+const CmdStackDepth = "stack-depth"
+const CmdTiming = "timing"
+const CmdMap = "map"
+const CmdMapJson = "map-json"
+const CmdHeader = "header"
+const CmdEntry = "entry"
+const CmdExport = "export"
+const CmdTarget = "target"
 
 // buildCmd represents the build command
 var buildCmd = &cobra.Command{
 	Use:   "build",
 	Short: "Build the forth code",
 	Long: `Executes the input forth files then cross compiles
-the "MAIN" word as executable ULP code.
+the "MAIN" word as executable ULP code. Input files
+ending in .S or .s are added to the output assembly.
 
 Example:
-ulp-forth build --assembly --reserved 1024 file1.f file2.f`,
+ulp-forth build --assembly --reserved 1024 file1.f file2.f
+ulp-forth build --export READ_SENSOR,RESET lib.f main.S`,
 	Run: func(cmd *cobra.Command, args []string) {
 		vm := forth.VirtualMachine{}
 		err := vm.Setup()
@@ -38,45 +54,78 @@ ulp-forth build --assembly --reserved 1024 file1.f file2.f`,
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = vm.BuiltinEsp32()
+		targetName, _ := cmd.Flags().GetString(CmdTarget)
+		target, err := forth.ParseTarget(targetName)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		for _, arg := range args {
-			f, err := os.Open(arg)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
+		buildAssembly, _ := cmd.Flags().GetBool(CmdAssembly)
+		buildCustomAsm, _ := cmd.Flags().GetBool(CmdCustomAssembly)
+		if target != forth.TargetEsp32 && buildCustomAsm {
+			fmt.Printf("--%s only supports the esp32, use --%s for the %s\n", CmdCustomAssembly, CmdAssembly, target)
+			os.Exit(1)
+		}
+		if target.Riscv() {
+			// the esp-idf assembles the RISC-V, ulp-c only knows the ULP-FSM
+			unsupported := []string{CmdSubroutineThreading, CmdDirectThreading, CmdMixedThreading, CmdExport, CmdMap, CmdMapJson, CmdTiming}
+			for _, flag := range unsupported {
+				if cmd.Flags().Changed(flag) {
+					fmt.Printf("--%s is not supported by the %s target\n", flag, target)
+					os.Exit(1)
+				}
 			}
-			defer f.Close()
-			err = vm.ExecuteFile(f)
-			if err != nil {
-				fmt.Println(err)
+			if !buildAssembly {
+				fmt.Printf("the %s target requires --%s, the esp-idf assembles the output\n", target, CmdAssembly)
 				os.Exit(1)
 			}
 		}
-		ulp := forth.Ulp{}
-		var assembly string
-		subroutine, _ := cmd.Flags().GetBool(CmdSubroutineThreading)
-		if subroutine {
-			assembly, err = ulp.BuildAssemblySrt(&vm, "MAIN")
-		} else {
-			assembly, err = ulp.BuildAssembly(&vm, "MAIN")
+		err = vm.BuiltinTarget(target)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
+		ulp, assembly, err := crossCompile(cmd, &vm, target, args)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		sequences, _ := cmd.Flags().GetBool(CmdSequences)
+		if sequences {
+			for _, seq := range ulp.Sequences {
+				fmt.Printf("%s saved %d bytes from %d uses of %s\n", seq.Name, seq.Saved, seq.Count, seq.Cells)
+			}
+		}
+		timing, _ := cmd.Flags().GetBool(CmdTiming)
+		if timing {
+			timings, err := ulp.Timing()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			for _, t := range timings {
+				if t.Exits {
+					fmt.Printf("%s: %s cycles\n", t.Entry.Name, t.Cycles)
+				} else {
+					fmt.Printf("%s: never exits\n", t.Entry.Name)
+				}
+				for _, loop := range t.Loops {
+					fmt.Printf("    loop at %s: %s cycles\n", loop.Start, loop.Cycles)
+				}
+			}
+		}
 
-		buildAssembly, _ := cmd.Flags().GetBool(CmdAssembly)
-		buildCustomAsm, _ := cmd.Flags().GetBool(CmdCustomAssembly)
 		output, _ := cmd.Flags().GetString(CmdOutput)
-		reduce := true // always reduce
+		reduce := false // the assembler reduces in a random order, keep the output the same between builds
 		reserved, _ := cmd.Flags().GetInt(CmdReserved)
 		assembler := asm.Assembler{}
 		var out []byte
-		if buildAssembly {
+		if buildAssembly && target.Riscv() {
+			if output == "" {
+				output = "out.S"
+			}
+			out = []byte(assembly)
+		} else if buildAssembly {
 			if output == "" {
 				output = "out.S"
 			}
@@ -86,7 +135,12 @@ ulp-forth build --assembly --reserved 1024 file1.f file2.f`,
 				fmt.Println(err)
 				os.Exit(1)
 			}
-			out = built
+			built, err = target.TranslateAssembly(built)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			out = forth.SortAssemblyLabels(built)
 		} else if buildCustomAsm {
 			if output == "" {
 				output = "out.nonportable.S"
@@ -101,7 +155,87 @@ ulp-forth build --assembly --reserved 1024 file1.f file2.f`,
 				fmt.Println(err)
 				os.Exit(1)
 			}
-			out = built
+			out, err = target.TranslateBinary(built)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		if !buildCustomAsm { // the stack size is only known after assembling
+			stackBytes := assembler.Compiler.Stack.Size
+			if target.Riscv() {
+				stackBytes = ulp.RiscvStackBytes()
+			}
+			stackDepth, _ := cmd.Flags().GetBool(CmdStackDepth)
+			warnings, err := ulp.Depth.Check(stackBytes)
+			if stackDepth {
+				if ulp.Depth.Bounded() {
+					fmt.Printf("data stack: %d cells, return stack: %d cells, stack space: %d cells\n", ulp.Depth.Data, ulp.Depth.Return, stackBytes/4)
+				}
+				for _, warning := range warnings {
+					fmt.Println("warning:", warning)
+				}
+			}
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		mapName, _ := cmd.Flags().GetString(CmdMap)
+		mapJson, _ := cmd.Flags().GetString(CmdMapJson)
+		if mapName != "" || mapJson != "" {
+			if buildCustomAsm { // assemble to find the addresses
+				_, err := assembler.BuildFile(assembly, "forth.S", reserved, reduce)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+			}
+			_, ulpMap := assemblerMap(ulp, &assembler)
+			if mapName != "" {
+				f, err := os.Create(mapName)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				defer f.Close()
+				err = ulpMap.WriteText(f)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+			}
+			if mapJson != "" {
+				f, err := os.Create(mapJson)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				defer f.Close()
+				err = ulpMap.WriteJSON(f)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+			}
+		}
+
+		if buildAssembly { // the esp32 needs to know the shared memory
+			header, _ := cmd.Flags().GetString(CmdHeader)
+			if header == "" {
+				header = strings.TrimSuffix(output, filepath.Ext(output)) + ".h"
+			}
+			h, err := ulp.BuildHeader()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			err = os.WriteFile(header, []byte(h), 0644)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
 		}
 
 		f, err := os.Create(output)
@@ -120,9 +254,106 @@ func init() {
 	buildCmd.Flags().String(CmdOutput, "", "Name of the output file.")
 	buildCmd.Flags().IntP(CmdReserved, "r", 8176, "Number of reserved bytes for the ULP, for use with --assembly flag. Note that the espressif linker reserves an extra 12 bytes.")
 
-	buildCmd.Flags().Bool(CmdAssembly, false, "Output assembly that can be compiled by the main assemblers, set the --reserved flag before using.")
+	buildCmd.Flags().Bool(CmdAssembly, false, "Output assembly that can be compiled by the main assemblers, set the --reserved flag before using. Required by the RISC-V targets.")
 	buildCmd.Flags().Bool(CmdCustomAssembly, false, "Output assembly only for use by ulp-asm, another project by this author.")
 	buildCmd.MarkFlagsMutuallyExclusive(CmdCustomAssembly, CmdAssembly)
+	buildCmd.Flags().String(CmdHeader, "", "Name of the C header written with --assembly that declares the shared memory. Defaults to the output name ending in .h.")
 
+	buildCmd.Flags().String(CmdTarget, "esp32", "The chip to build for, one of "+strings.Join(forth.TargetNames(), ", ")+".")
 	buildCmd.Flags().Bool(CmdSubroutineThreading, false, "Use the subroutine threading model. Faster but larger.")
+	buildCmd.Flags().Bool(CmdDirectThreading, false, "Use the direct threading model. Faster than token threading but larger.")
+	buildCmd.Flags().Bool(CmdMixedThreading, false, "Use subroutine threading for the words marked HOT, or the words in loops if none are marked, and token threading for the rest.")
+	buildCmd.MarkFlagsMutuallyExclusive(CmdSubroutineThreading, CmdDirectThreading, CmdMixedThreading)
+	buildCmd.Flags().String(CmdEntry, "MAIN", "Name of the word to cross compile and run.")
+	buildCmd.Flags().StringSlice(CmdExport, nil, "Build a library of these words instead of a program. Each can be called from other assembly with the label forth_NAME.")
+	buildCmd.MarkFlagsMutuallyExclusive(CmdEntry, CmdExport)
+	buildCmd.Flags().Bool(CmdUncheckedStack, false, "Don't fail the build when the stack depth differs between paths through a word.")
+	buildCmd.Flags().Bool(CmdStackDepth, false, "Print the worst case depth of the data and return stacks.")
+	buildCmd.Flags().Bool(CmdTiming, false, "Print the best and worst case number of ULP cycles for each word.")
+	buildCmd.Flags().String(CmdMap, "", "Name of a file to write the address, section and size of every word to.")
+	buildCmd.Flags().String(CmdMapJson, "", "Name of a file to write the address, section and size of every word to as JSON.")
+	buildCmd.Flags().Bool(CmdSequences, false, "Print the repeated sequences that were factored into new words and the bytes saved by each.")
+}
+
+// Execute the input forth files then cross compile the entry word,
+// or the exported words, with the threading model chosen by the
+// flags. Input files ending in .S or .s are added to the output.
+func crossCompile(cmd *cobra.Command, vm *forth.VirtualMachine, target forth.Target, args []string) (*forth.Ulp, string, error) {
+	extraAssembly := make([]string, 0)
+	for _, arg := range args {
+		ext := filepath.Ext(arg)
+		if ext == ".S" || ext == ".s" { // assembly to put in the output
+			content, err := os.ReadFile(arg)
+			if err != nil {
+				return nil, "", err
+			}
+			extraAssembly = append(extraAssembly, string(content))
+			continue
+		}
+		f, err := os.Open(arg)
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		err = vm.ExecuteFile(f)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	unchecked, _ := cmd.Flags().GetBool(CmdUncheckedStack)
+	ulp := forth.Ulp{CheckStack: !unchecked}
+	var assembly string
+	var err error
+	subroutine, _ := cmd.Flags().GetBool(CmdSubroutineThreading)
+	direct, _ := cmd.Flags().GetBool(CmdDirectThreading)
+	mixed, _ := cmd.Flags().GetBool(CmdMixedThreading)
+	entry, _ := cmd.Flags().GetString(CmdEntry)
+	exports, _ := cmd.Flags().GetStringSlice(CmdExport)
+	switch {
+	case target.Riscv():
+		assembly, err = ulp.BuildAssemblyRiscv(vm, entry)
+	case len(exports) != 0 && mixed:
+		err = fmt.Errorf("--%s does not support --%s", CmdExport, CmdMixedThreading)
+	case len(exports) != 0 && subroutine:
+		assembly, err = ulp.BuildLibrarySrt(vm, exports)
+	case len(exports) != 0 && direct:
+		assembly, err = ulp.BuildLibraryDirect(vm, exports)
+	case len(exports) != 0:
+		assembly, err = ulp.BuildLibrary(vm, exports)
+	case subroutine:
+		assembly, err = ulp.BuildAssemblySrt(vm, entry)
+	case direct:
+		assembly, err = ulp.BuildAssemblyDirect(vm, entry)
+	case mixed:
+		assembly, err = ulp.BuildAssemblyMixed(vm, entry)
+	default:
+		assembly, err = ulp.BuildAssembly(vm, entry)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	for _, extra := range extraAssembly {
+		assembly += "\r\n" + extra
+	}
+	return &ulp, assembly, nil
+}
+
+// Map the program using the labels and sections of
+// an assembler that has built it. Also returns the
+// byte address of every label.
+func assemblerMap(ulp *forth.Ulp, assembler *asm.Assembler) (map[string]int, forth.UlpMap) {
+	c := assembler.Compiler
+	labels := make(map[string]int)
+	for name, label := range c.Labels {
+		labels[name] = label.Value
+	}
+	sections := []forth.UlpSection{
+		{Name: ".boot", Offset: c.Boot.Offset, Size: c.Boot.Size},
+		{Name: ".text", Offset: c.Text.Offset, Size: c.Text.Size},
+		{Name: ".boot.data", Offset: c.BootData.Offset, Size: c.BootData.Size},
+		{Name: ".data", Offset: c.Data.Offset, Size: c.Data.Size},
+		{Name: ".bss", Offset: c.Bss.Offset, Size: c.Bss.Size},
+		{Name: ".stack", Offset: c.Stack.Offset, Size: c.Stack.Size},
+	}
+	return labels, ulp.Map(labels, sections)
 }
@@ -0,0 +1,143 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/Molorius/ulp-c/pkg/asm"
+	"github.com/Molorius/ulp-forth/pkg/forth"
+	"github.com/Molorius/ulp-forth/pkg/sim"
+	"github.com/spf13/cobra"
+)
+
+const CmdBreak = "break"
+const CmdGdb = "gdb"
+
+// debugCmd represents the debug command
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Build the forth code and step through it in the emulator",
+	Long: `Executes the input forth files, cross compiles the
+"MAIN" word, then runs it in the ULP emulator under a debugger.
+Commands are read from stdin: breakpoints are set on word
+names, the program can be stepped a word or an instruction at a
+time, and the data and return stacks are shown with addresses
+decoded back into word names. Enter "help" for the commands.
+
+With --gdb the commands come from a client of the GDB remote
+serial protocol on a local TCP port instead. It has not been
+tested with GDB itself, which has no ULP-FSM architecture.
+Addresses are in bytes and the labels can be listed with
+"monitor symbols".
+
+Exits with the same codes as the sim command.
+
+Example:
+ulp-forth debug file1.f file2.f
+ulp-forth debug --break SQUARE --subroutine test.f
+ulp-forth debug --gdb localhost:3333 test.f`,
+	Run: func(cmd *cobra.Command, args []string) {
+		vm := forth.VirtualMachine{}
+		err := vm.Setup()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+		err = vm.BuiltinTarget(forth.TargetEsp32)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+		ulp, assembly, err := crossCompile(cmd, &vm, forth.TargetEsp32, args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+		reserved, _ := cmd.Flags().GetInt(CmdReserved)
+		assembler := asm.Assembler{}
+		bin, err := assembler.BuildFile(assembly, "forth.S", reserved, false)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+		labels, ulpMap := assemblerMap(ulp, &assembler)
+
+		maxCycles, _ := cmd.Flags().GetUint64(CmdMaxCycles)
+		timeout, _ := cmd.Flags().GetDuration(CmdTimeout)
+		s := sim.Simulator{
+			Out:       os.Stdout,
+			MaxCycles: maxCycles,
+			Timeout:   timeout,
+		}
+		err = s.Load(bin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+		d := sim.NewDebugger(&s, labels, ulpMap)
+		breaks, _ := cmd.Flags().GetStringSlice(CmdBreak)
+		for _, name := range breaks {
+			err = d.Break(name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(simExitError)
+			}
+		}
+		gdb, _ := cmd.Flags().GetString(CmdGdb)
+		if gdb != "" {
+			err = serveGdb(gdb, d)
+		} else {
+			d.Status(os.Stdout)
+			err = d.Repl(os.Stdin, os.Stdout)
+		}
+		if errors.Is(err, sim.ErrCycleLimit) || errors.Is(err, sim.ErrTimeout) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitLimit)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+
+	debugCmd.Flags().IntP(CmdReserved, "r", 8176, "Number of reserved bytes for the ULP.")
+	debugCmd.Flags().Bool(CmdSubroutineThreading, false, "Use the subroutine threading model.")
+	debugCmd.Flags().Bool(CmdDirectThreading, false, "Use the direct threading model.")
+	debugCmd.Flags().Bool(CmdMixedThreading, false, "Use subroutine threading for the hot words and token threading for the rest.")
+	debugCmd.MarkFlagsMutuallyExclusive(CmdSubroutineThreading, CmdDirectThreading, CmdMixedThreading)
+	debugCmd.Flags().String(CmdEntry, "MAIN", "Name of the word to cross compile and run.")
+	debugCmd.Flags().Bool(CmdUncheckedStack, false, "Don't fail the build when the stack depth differs between paths through a word.")
+	debugCmd.Flags().Uint64(CmdMaxCycles, 0, "Stop after this many ULP cycles, 0 for no limit. The ULP runs at about 8 million cycles per second.")
+	debugCmd.Flags().Duration(CmdTimeout, 0, "Stop a command after this much real time, 0 for no limit.")
+	debugCmd.Flags().StringSlice(CmdBreak, nil, "Names of words to set breakpoints on before starting.")
+	debugCmd.Flags().String(CmdGdb, "", "Serve the GDB remote serial protocol on this address, such as localhost:3333, instead of reading commands from stdin.")
+}
+
+// Wait for GDB to connect to the address then serve it.
+func serveGdb(addr string, d *sim.Debugger) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	fmt.Fprintf(os.Stderr, "waiting for gdb on %s\n", listener.Addr())
+	conn, err := listener.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return sim.NewGdbServer(d).Serve(conn)
+}
@@ -10,11 +10,14 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Molorius/ulp-forth/pkg/forth"
 	"github.com/spf13/cobra"
 )
 
+const CmdTrace = "trace"
+
 // runCmd represents the run command
 var runCmd = &cobra.Command{
 	Use:   "run",
@@ -22,9 +25,15 @@ var runCmd = &cobra.Command{
 	Long: `Executes the input forth files then sets up in interpreter
 for testing. This runs purely on the host device.
 
+With --trace every executed word is printed with the depth of
+the return stack and the data stack after it runs, the words
+inside of a definition are indented. TRACE-ON and TRACE-OFF
+turn the trace on and off from forth.
+
 Examples:
 ulp-forth run
-ulp-forth run file1.f`,
+ulp-forth run file1.f
+ulp-forth run --trace file1.f`,
 	Run: func(cmd *cobra.Command, args []string) {
 		vm := forth.VirtualMachine{}
 		err := vm.Setup()
@@ -32,11 +41,18 @@ ulp-forth run file1.f`,
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		err = vm.BuiltinEsp32()
+		targetName, _ := cmd.Flags().GetString(CmdTarget)
+		target, err := forth.ParseTarget(targetName)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = vm.BuiltinTarget(target)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		vm.Trace, _ = cmd.Flags().GetBool(CmdTrace)
 		for _, arg := range args {
 			f, err := os.Open(arg)
 			if err != nil {
@@ -81,4 +97,6 @@ ulp-forth run file1.f`,
 
 func init() {
 	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().String(CmdTarget, "esp32", "The chip to load the hardware words for, one of "+strings.Join(forth.TargetNames(), ", ")+".")
+	runCmd.Flags().Bool(CmdTrace, false, "Print every executed word with the return stack depth and the data stack.")
 }
@@ -0,0 +1,130 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Molorius/ulp-c/pkg/asm"
+	"github.com/Molorius/ulp-forth/pkg/forth"
+	"github.com/Molorius/ulp-forth/pkg/sim"
+	"github.com/spf13/cobra"
+)
+
+const CmdMaxCycles = "max-cycles"
+const CmdTimeout = "timeout"
+const CmdVcd = "vcd"
+
+// The exit codes of the sim command.
+const (
+	simExitError = 1 // the program failed to build or the emulator failed
+	simExitLimit = 2 // the program didn't finish before a limit
+)
+
+// simCmd represents the sim command
+var simCmd = &cobra.Command{
+	Use:   "sim",
+	Short: "Build the forth code and run it in the emulator",
+	Long: `Executes the input forth files, cross compiles the
+"MAIN" word, then runs it in the ULP emulator. Numbers and
+characters printed by the ULP are written to stdout. Input files
+ending in .S or .s are added to the output assembly.
+
+Exits with 0 when the program finishes with ESP.DONE, 1 if it
+fails to build or run, and 2 if it reaches the cycle limit or
+the timeout.
+
+Example:
+ulp-forth sim file1.f file2.f
+ulp-forth sim --subroutine --max-cycles 1000000 test.f
+ulp-forth sim --vcd blink.vcd --max-cycles 24000000 blink.f`,
+	Run: func(cmd *cobra.Command, args []string) {
+		vm := forth.VirtualMachine{}
+		err := vm.Setup()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+		err = vm.BuiltinTarget(forth.TargetEsp32)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+		_, assembly, err := crossCompile(cmd, &vm, forth.TargetEsp32, args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+		reserved, _ := cmd.Flags().GetInt(CmdReserved)
+		assembler := asm.Assembler{}
+		bin, err := assembler.BuildFile(assembly, "forth.S", reserved, false)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+
+		maxCycles, _ := cmd.Flags().GetUint64(CmdMaxCycles)
+		timeout, _ := cmd.Flags().GetDuration(CmdTimeout)
+		s := sim.Simulator{
+			Out:       os.Stdout,
+			MaxCycles: maxCycles,
+			Timeout:   timeout,
+		}
+		err = s.Load(bin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+		err = s.Run()
+		vcd, _ := cmd.Flags().GetString(CmdVcd)
+		if vcd != "" {
+			// write the waveform even if a limit was reached
+			vcdErr := writeVcd(vcd, &s)
+			if vcdErr != nil {
+				fmt.Fprintln(os.Stderr, vcdErr)
+				os.Exit(simExitError)
+			}
+		}
+		if errors.Is(err, sim.ErrCycleLimit) || errors.Is(err, sim.ErrTimeout) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitLimit)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(simExitError)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(simCmd)
+
+	simCmd.Flags().IntP(CmdReserved, "r", 8176, "Number of reserved bytes for the ULP.")
+	simCmd.Flags().Bool(CmdSubroutineThreading, false, "Use the subroutine threading model.")
+	simCmd.Flags().Bool(CmdDirectThreading, false, "Use the direct threading model.")
+	simCmd.Flags().Bool(CmdMixedThreading, false, "Use subroutine threading for the hot words and token threading for the rest.")
+	simCmd.MarkFlagsMutuallyExclusive(CmdSubroutineThreading, CmdDirectThreading, CmdMixedThreading)
+	simCmd.Flags().String(CmdEntry, "MAIN", "Name of the word to cross compile and run.")
+	simCmd.Flags().Bool(CmdUncheckedStack, false, "Don't fail the build when the stack depth differs between paths through a word.")
+	simCmd.Flags().Uint64(CmdMaxCycles, 80_000_000, "Stop after this many ULP cycles, 0 for no limit. The ULP runs at about 8 million cycles per second.")
+	simCmd.Flags().Duration(CmdTimeout, 10*time.Second, "Stop after this much real time, 0 for no limit.")
+	simCmd.Flags().String(CmdVcd, "", "Name of a file to write the RTC GPIO output changes to as a VCD waveform.")
+}
+
+// Write the output changes of the simulation to a VCD file.
+func writeVcd(name string, s *sim.Simulator) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.Rtc.WriteVcd(f, s.Cycles)
+}
@@ -169,6 +169,57 @@ func TestPrimitives(t *testing.T) {
 			asm:    wrapMain("4 0 DO I . LOOP"),
 			expect: "0 1 2 3 ",
 		},
+		{
+			name:   "constant folding",
+			asm:    wrapMain("1 2 + 3 * u. 7 2 U/MOD u. u. 5 1 2 SWAP - + u."),
+			expect: "9 3 1 6 ",
+		},
+		{
+			name:   "forth inlining",
+			asm:    ": ONE 1 ; : SQUARE DUP * ; : MAIN ONE ONE + u. 3 SQUARE u. ESP.DONE ;",
+			expect: "2 9 ",
+		},
+		{
+			name:   "assembly inlining",
+			asm:    ": MAIN 1 2 SWAP DROP DUP + u. 0 BEGIN DUP u. 1 + DUP 3 = UNTIL DROP ESP.DONE ;",
+			expect: "4 0 1 2 ",
+		},
+		{
+			name:   "peephole",
+			asm:    "VARIABLE V : MAIN 7 V ! 1 V @ DUP DROP SWAP SWAP u. u. ESP.DONE ;",
+			expect: "7 1 ",
+		},
+		{
+			name:   "fallthrough",
+			asm:    ": C IF 3 ELSE 4 THEN u. ; : B DUP IF 1 ELSE 2 THEN u. C ; : MAIN 1 B 0 B ESP.DONE ;",
+			expect: "1 3 2 4 ",
+		},
+		{
+			name: "sequence compression",
+			asm: `
+				VARIABLE V
+				: A V @ 1+ V ! 5 u. ;
+				: B V @ 1+ V ! 6 u. ;
+				: C V @ 1+ V ! 7 u. ;
+				: MAIN 0 V ! A B C A B C V @ u. ESP.DONE ;
+			`,
+			expect: "5 6 7 5 6 7 6 ",
+		},
+		{
+			name: "HOT",
+			asm: `
+				: ONE 1 u. ;
+				: TWICE ( xt -- ) DUP EXECUTE EXECUTE ;
+				: POLL ONE ['] ONE TWICE 2 u. ; HOT
+				: MAIN POLL POLL ESP.DONE ;
+			`,
+			expect: "1 1 1 2 1 1 1 2 ",
+		},
+		{
+			name:   "flow control analysis",
+			asm:    "0 CONSTANT DEBUG : MAIN 1 IF 1 u. ELSE 2 u. THEN 0 IF 3 u. ELSE 4 u. THEN DEBUG IF 5 u. THEN ESP.DONE ;",
+			expect: "1 4 ",
+		},
 		{
 			name: "tail call",
 			asm: `
@@ -242,55 +293,57 @@ func runOutputTest(code string, expected string, t *testing.T, r *asm.Runner) {
 		}
 	})
 
-	// run the test on ulp with token threaded implementation
-	t.Run("token threaded", func(t *testing.T) {
-		parallel(r, t)
-		// set up the virtual machine
-		var buff bytes.Buffer
-		vm := VirtualMachine{Out: &buff}
-		err := vm.Setup()
-		if err != nil {
-			t.Fatalf("failed to set up vm: %s", err)
-		}
-		// run the code through the interpreter
-		err = vm.Execute([]byte(code))
-		if err != nil {
-			t.Fatalf("failed to execute test code: %s", err)
-		}
-		ulp := Ulp{}
-		// cross compile "main"
-		assembly, err := ulp.BuildAssembly(&vm, "main")
-		if err != nil {
-			t.Fatalf("failed to generate assembly: %s", err)
-		}
-		// run the cross compiled test on emulator and hardware
-		r.RunTest(t, assembly, expected)
-	})
+	for _, b := range outputBuilders {
+		t.Run(b.name, func(t *testing.T) {
+			parallel(r, t)
+			// set up the virtual machine
+			var buff bytes.Buffer
+			vm := VirtualMachine{Out: &buff}
+			err := vm.Setup()
+			if err != nil {
+				t.Fatalf("failed to set up vm: %s", err)
+			}
+			if b.riscv {
+				// HALT is defined by the library
+				err = vm.BuiltinTarget(TargetEsp32s2Riscv)
+				if err != nil {
+					t.Fatalf("failed to set up the library: %s", err)
+				}
+			}
+			// run the code through the interpreter
+			err = vm.Execute([]byte(code))
+			if err != nil {
+				t.Fatalf("failed to execute test code: %s", err)
+			}
+			ulp := Ulp{}
+			// cross compile "main"
+			assembly, err := b.build(&ulp, &vm, "main")
+			if err != nil {
+				t.Fatalf("failed to generate assembly: %s", err)
+			}
+			if b.riscv {
+				// run the cross compiled test on the emulator
+				runRiscvTest(t, assembly, expected)
+			} else {
+				// run the cross compiled test on emulator and hardware
+				r.RunTest(t, assembly, expected)
+			}
+		})
+	}
+}
 
-	// run the test on ulp with subroutine threaded implementation
-	t.Run("subroutine threaded", func(t *testing.T) {
-		parallel(r, t)
-		// set up the virtual machine
-		var buff bytes.Buffer
-		vm := VirtualMachine{Out: &buff}
-		err := vm.Setup()
-		if err != nil {
-			t.Fatalf("failed to set up vm: %s", err)
-		}
-		// run the code through the interpreter
-		err = vm.Execute([]byte(code))
-		if err != nil {
-			t.Fatalf("failed to execute test code: %s", err)
-		}
-		ulp := Ulp{}
-		// cross compile "main"
-		assembly, err := ulp.BuildAssemblySrt(&vm, "main")
-		if err != nil {
-			t.Fatalf("failed to generate assembly: %s", err)
-		}
-		// run the cross compiled test on emulator and hardware
-		r.RunTest(t, assembly, expected)
-	})
+// The ways to cross compile the output tests.
+var outputBuilders = []struct {
+	name  string
+	build func(*Ulp, *VirtualMachine, string) (string, error)
+	riscv bool // run on the ulp-risc-v test emulator
+}{
+	{name: "token threaded", build: (*Ulp).BuildAssembly},
+	{name: "subroutine threaded", build: (*Ulp).BuildAssemblySrt},
+	{name: "direct threaded", build: (*Ulp).BuildAssemblyDirect},
+	// the words in loops are subroutine threaded
+	{name: "mixed threaded", build: (*Ulp).BuildAssemblyMixed},
+	{name: "riscv", build: (*Ulp).BuildAssemblyRiscv, riscv: true},
 }
 
 // Run the tests in parallel.
@@ -80,3 +80,8 @@ EXIT [ IMMEDIATE
 \ then the subroutine threaded assembly.
 : ASSEMBLY-BOTH
     BL WORD --CREATE-ASSEMBLY-BOTH ;
+
+\ Parse the next word, create a new ULP-RISC-V assembly
+\ definition. It returns to the caller at the end.
+: ASSEMBLY-RISCV
+    BL WORD --CREATE-ASSEMBLY-RISCV ;
@@ -185,6 +185,7 @@
 : I 0 POSTPONE LITERAL POSTPONE RPICK ; IMMEDIATE
 : J 2 POSTPONE LITERAL POSTPONE RPICK ; IMMEDIATE
 : ?DUP DUP IF DUP THEN ;
+TRUE LAST SET-UNCHECKED-STACK \ leaves 1 or 2 cells
 : XOR ( a b -- c )
     \ [a ^ b] = [a|b] - [a&b]
     2DUP ( A B A B )
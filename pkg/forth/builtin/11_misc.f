@@ -16,3 +16,10 @@
 1 CONSTANT TOKEN_NEXT_NORMAL
 2 CONSTANT TOKEN_NEXT_SKIP_R2
 3 CONSTANT TOKEN_NEXT_SKIP_LOAD
+
+\ HOT marks the most recent definition to use subroutine
+\ threading when building with mixed threading. Every word
+\ that it calls is subroutine threaded too.
+: HOT ( -- )
+    TRUE LAST SET-HOT
+;
@@ -72,6 +72,25 @@ type CellAddress struct {
 }
 
 func (c CellAddress) Execute(vm *VirtualMachine) error {
+	if !vm.Trace {
+		return c.execute(vm)
+	}
+	w, ok := c.Entry.Word.(*WordForth)
+	nested := ok && !w.Entry.Flag.Data
+	if nested {
+		vm.traceEnter(c)
+	}
+	err := c.execute(vm)
+	if nested {
+		vm.traceLevel -= 1
+	}
+	if vm.Trace { // not after TRACE-OFF
+		vm.traceExit(c, err)
+	}
+	return err
+}
+
+func (c CellAddress) execute(vm *VirtualMachine) error {
 	switch w := c.Entry.Word.(type) {
 	case *WordForth:
 		if c.Offset >= len(w.Cells) {
@@ -103,10 +122,18 @@ func (c CellAddress) BuildExecution(u *Ulp) (string, error) {
 	}
 
 	switch u.compileTarget {
-	case UlpCompileTargetToken:
+	case UlpCompileTargetToken, UlpCompileTargetDirect:
 		return fmt.Sprintf(".int %s", name), nil
 	case UlpCompileTargetSubroutine:
+		if u.mixed != nil && c.Offset == 0 && !c.UpperByte {
+			name = u.mixed.call(c.Entry)
+		}
 		return fmt.Sprintf("jump %s", name), nil
+	case UlpCompileTargetRiscv:
+		if c.Entry.Flag.isExit {
+			return strings.Join(riscvExit, "\r\n"), nil // faster than calling EXIT
+		}
+		return fmt.Sprintf("jal ra, %s", name), nil
 	default:
 		return "", fmt.Errorf("unknown compile target %d, please file a bug report", u.compileTarget)
 	}
@@ -123,6 +150,20 @@ func (c CellAddress) OutputReference(u *Ulp) (string, error) {
 	return name, nil
 }
 
+// The byte address used by the ULP-RISC-V assembly,
+// the upper byte of a cell is at the next byte.
+func (c CellAddress) riscvReference() string {
+	name := "__body" + c.Entry.ulpName
+	offset := c.Offset * 4
+	if c.UpperByte {
+		offset += 0x8000 * 4
+	}
+	if offset != 0 {
+		name = fmt.Sprintf("%s+%d", name, offset)
+	}
+	return name
+}
+
 func (c CellAddress) IsRecursive(check *WordForth) bool {
 	return c.Entry.Word.IsRecursive(check)
 }
@@ -194,11 +235,18 @@ func (c CellLiteral) BuildExecution(u *Ulp) (string, error) {
 		name = "__body" + name
 	}
 	switch u.compileTarget {
-	case UlpCompileTargetToken:
+	case UlpCompileTargetToken, UlpCompileTargetDirect:
 		ref := c.reference(name)
 		return fmt.Sprintf(".int %s", ref), nil
 	case UlpCompileTargetSubroutine:
 		return fmt.Sprintf("move r0, %s\r\n%sjump __add_to_stack", name, safeCall()), nil
+	case UlpCompileTargetRiscv:
+		address, ok := c.cell.(CellAddress)
+		if ok {
+			// the address follows the call, it's divided into cells
+			return fmt.Sprintf(".balign 4\r\njal t0, __push_address\r\n.int %s", address.riscvReference()), nil
+		}
+		return fmt.Sprintf("li a0, %s\r\naddi s0, s0, -4\r\nsw a0, 0(s0)", name), nil
 	default:
 		return "", fmt.Errorf("unknown compile target %d, please file a bug report", u.compileTarget)
 	}
@@ -278,8 +326,12 @@ func (c *CellBranch) BuildExecution(u *Ulp) (string, error) {
 	switch u.compileTarget {
 	case UlpCompileTargetToken:
 		return fmt.Sprintf(".int %s + 0x8000", c.dest.name(u)), nil
+	case UlpCompileTargetDirect:
+		return fmt.Sprintf(".int __branch\r\n.int %s", c.dest.name(u)), nil
 	case UlpCompileTargetSubroutine:
 		return fmt.Sprintf("move r2, %s\r\njump r2", c.dest.name(u)), nil
+	case UlpCompileTargetRiscv:
+		return fmt.Sprintf("j %s", c.dest.name(u)), nil
 	default:
 		return "", fmt.Errorf("unknown compile target %d, please file a bug report", u.compileTarget)
 	}
@@ -327,8 +379,12 @@ func (c *CellBranch0) BuildExecution(u *Ulp) (string, error) {
 	switch u.compileTarget {
 	case UlpCompileTargetToken:
 		return fmt.Sprintf(".int %s + 0x4000", c.dest.name(u)), nil
+	case UlpCompileTargetDirect:
+		return fmt.Sprintf(".int __branch0\r\n.int %s", c.dest.name(u)), nil
 	case UlpCompileTargetSubroutine:
 		return fmt.Sprintf("move r1, %s\r\n%sjump __branch_if", c.dest.name(u), safeCall()), nil
+	case UlpCompileTargetRiscv:
+		return fmt.Sprintf("lw a0, 0(s0)\r\naddi s0, s0, 4\r\nbeqz a0, %s", c.dest.name(u)), nil
 	default:
 		return "", fmt.Errorf("unknown compile target %d, please file a bug report", u.compileTarget)
 	}
@@ -349,7 +405,8 @@ func (c *CellBranch0) String() string {
 // Used during an optimization pass to add
 // tail calls.
 type CellTailCall struct {
-	dest *WordForth
+	dest         *WordForth
+	fallsThrough bool // the destination is placed directly after this
 }
 
 func (c *CellTailCall) Execute(vm *VirtualMachine) error {
@@ -362,12 +419,23 @@ func (c *CellTailCall) AddToList(u *Ulp) error {
 }
 
 func (c *CellTailCall) BuildExecution(u *Ulp) (string, error) {
+	if c.fallsThrough {
+		return "", nil // continue directly into the destination
+	}
 	switch u.compileTarget {
 	case UlpCompileTargetToken:
 		return fmt.Sprintf(".int %s + 0x8000", c.dest.Entry.BodyLabel()), nil
+	case UlpCompileTargetDirect:
+		return fmt.Sprintf(".int __branch\r\n.int %s", c.dest.Entry.BodyLabel()), nil
 	case UlpCompileTargetSubroutine:
 		// put the address after the docol
-		return fmt.Sprintf("move r2, %s\r\njump r2", c.dest.Entry.BodyLabel()), nil
+		body := c.dest.Entry.BodyLabel()
+		if u.mixed != nil {
+			body += MixedSuffix // both words are hot
+		}
+		return fmt.Sprintf("move r2, %s\r\njump r2", body), nil
+	case UlpCompileTargetRiscv:
+		return fmt.Sprintf("j %s", c.dest.Entry.BodyLabel()), nil
 	default:
 		return "", fmt.Errorf("unknown compile target %d, please file a bug report", u.compileTarget)
 	}
@@ -384,3 +452,43 @@ func (c *CellTailCall) IsRecursive(check *WordForth) bool {
 func (c *CellTailCall) String() string {
 	return fmt.Sprintf("TailCall{%s}", c.dest.Entry.Name)
 }
+
+// Used during an optimization pass to copy the
+// assembly of a primitive directly into the
+// calling word. Only used when subroutine threading
+// and on the ULP-RISC-V.
+type CellInline struct {
+	word *WordPrimitive
+}
+
+func (c *CellInline) Execute(vm *VirtualMachine) error {
+	return fmt.Errorf("cannot directly execute an inlined primitive, please file a bug report")
+}
+
+func (c *CellInline) AddToList(u *Ulp) error {
+	// the primitive is not called so it does not need to be added
+	return nil
+}
+
+func (c *CellInline) BuildExecution(u *Ulp) (string, error) {
+	switch u.compileTarget {
+	case UlpCompileTargetSubroutine:
+		return strings.Join(c.word.UlpSrt.Asm, "\r\n"), nil
+	case UlpCompileTargetRiscv:
+		return strings.Join(c.word.UlpRiscv.Asm, "\r\n"), nil
+	default:
+		return "", fmt.Errorf("cannot inline primitive with compile target %d, please file a bug report", u.compileTarget)
+	}
+}
+
+func (c *CellInline) OutputReference(u *Ulp) (string, error) {
+	return "", fmt.Errorf("cannot refer to an inlined primitive, please file a bug report")
+}
+
+func (c *CellInline) IsRecursive(check *WordForth) bool {
+	return false
+}
+
+func (c *CellInline) String() string {
+	return fmt.Sprintf("Inline{%s}", c.word.Entry.Name)
+}
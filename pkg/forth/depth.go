@@ -0,0 +1,308 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The worst case depth of the stacks while
+// running the cross compiled program, in cells.
+type StackDepth struct {
+	Data      int                // the deepest the data stack can get
+	Return    int                // the deepest the return stack can get
+	Recursive []*DictionaryEntry // recursive words, the stacks can grow without bound
+	Unknown   []*DictionaryEntry // words that the depth could not be found for
+}
+
+// Check if both depths are known.
+func (d *StackDepth) Bounded() bool {
+	return len(d.Recursive) == 0 && len(d.Unknown) == 0
+}
+
+// The number of cells needed in the stack section.
+// The return stack never writes to the first cell and
+// some primitives store one cell past the data stack.
+func (d *StackDepth) Cells() int {
+	return d.Data + d.Return + 2
+}
+
+// Check that the stacks fit in the space left
+// after the code and data. Returns an error if
+// they can collide and warnings for any stack
+// usage that could not be checked.
+func (d *StackDepth) Check(stackBytes int) ([]string, error) {
+	warnings := make([]string, 0)
+	if len(d.Recursive) != 0 {
+		warnings = append(warnings, "unbounded recursion in "+entryNames(d.Recursive)+", the stacks could collide with __data_end")
+	}
+	if len(d.Unknown) != 0 {
+		warnings = append(warnings, "could not find the stack depth of "+entryNames(d.Unknown)+", the stacks could collide with __data_end")
+	}
+	available := stackBytes / 4
+	if d.Cells() > available {
+		return warnings, fmt.Errorf("the data stack (%d cells) and return stack (%d cells) could collide with __data_end, %d cells are needed but only %d are free", d.Data, d.Return, d.Cells(), available)
+	}
+	return warnings, nil
+}
+
+func entryNames(entries []*DictionaryEntry) string {
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// The most that a word can change the stacks by.
+type depthSummary struct {
+	grow   int  // the most the data stack grows by when the word exits
+	peak   int  // the most the data stack grows by while the word runs
+	rGrow  int  // the change in the return stack when the word exits
+	rPeak  int  // the most the return stack grows by while the word runs
+	known  bool // the depth could be found
+	called bool // calling this pushes a return address
+}
+
+// Finds the worst case stack depths, starting at the
+// entry word and following every word that it calls.
+// Unlike the EffectChecker the depth is allowed to
+// differ between paths, the deepest path is used.
+type DepthAnalyzer struct {
+	summaries map[*WordForth]depthSummary // the summary of each word
+	active    map[*WordForth]bool         // the words currently being analyzed
+	depth     StackDepth
+	mixed     *mixedThreading // the hot words, if built with mixed threading
+}
+
+// Find the worst case depth of the stacks when running
+// the entry word, which is called with empty stacks.
+func (a *DepthAnalyzer) Analyze(entry *DictionaryEntry) StackDepth {
+	a.summaries = make(map[*WordForth]depthSummary)
+	a.active = make(map[*WordForth]bool)
+	a.depth = StackDepth{}
+	w, ok := entry.Word.(*WordForth)
+	if !ok {
+		a.unknown(entry)
+		return a.depth
+	}
+	s := a.word(w)
+	if s.known {
+		a.depth.Data = s.peak
+		a.depth.Return = s.rPeak
+	}
+	return a.depth
+}
+
+// Find the worst case depth of the stacks when running any
+// of the exported words. Each is called with a return address
+// on the return stack, the depth of the data stack is on top
+// of the cells passed in by the caller.
+func (a *DepthAnalyzer) AnalyzeExports(entries []*DictionaryEntry) StackDepth {
+	a.summaries = make(map[*WordForth]depthSummary)
+	a.active = make(map[*WordForth]bool)
+	a.depth = StackDepth{}
+	for _, entry := range entries {
+		w, ok := entry.Word.(*WordForth)
+		if !ok {
+			a.unknown(entry)
+			continue
+		}
+		s := a.word(w)
+		if s.known {
+			a.depth.Data = max(a.depth.Data, s.peak)
+			a.depth.Return = max(a.depth.Return, s.rPeak+1)
+		}
+	}
+	return a.depth
+}
+
+func (a *DepthAnalyzer) unknown(entry *DictionaryEntry) {
+	for _, e := range a.depth.Unknown {
+		if e == entry {
+			return
+		}
+	}
+	a.depth.Unknown = append(a.depth.Unknown, entry)
+}
+
+func (a *DepthAnalyzer) recursive(entry *DictionaryEntry) {
+	for _, e := range a.depth.Recursive {
+		if e == entry {
+			return
+		}
+	}
+	a.depth.Recursive = append(a.depth.Recursive, entry)
+}
+
+// Get the summary of a cell that is executed.
+func (a *DepthAnalyzer) cell(c Cell) depthSummary {
+	switch cell := c.(type) {
+	case CellLiteral:
+		return depthSummary{grow: 1, peak: 1, known: true}
+	case CellAddress:
+		switch w := cell.Entry.Word.(type) {
+		case *WordPrimitive:
+			return a.primitive(w)
+		case *WordForth:
+			s := a.word(w)
+			s.called = true
+			return s
+		}
+		a.unknown(cell.Entry)
+		return depthSummary{}
+	case *CellTailCall:
+		return a.word(cell.dest) // reuses the caller's return address
+	case *CellInline:
+		return a.primitive(cell.word)
+	case *CellBranch0:
+		return depthSummary{grow: -1, known: true}
+	}
+	return depthSummary{known: true}
+}
+
+func (a *DepthAnalyzer) primitive(w *WordPrimitive) depthSummary {
+	if !w.Effect.Known {
+		a.unknown(w.Entry)
+		return depthSummary{}
+	}
+	s := depthSummary{
+		grow:  w.Effect.Out - w.Effect.In,
+		known: true,
+	}
+	s.peak = max(0, s.grow)
+	if w.Entry.Flag.usesReturnStack && !w.Entry.Flag.isExit {
+		if !w.ReturnEffect.Known {
+			a.unknown(w.Entry)
+			return depthSummary{}
+		}
+		s.rGrow = w.ReturnEffect.Out - w.ReturnEffect.In
+		s.rPeak = max(0, s.rGrow)
+	}
+	return s
+}
+
+// Find the summary of a forth word.
+func (a *DepthAnalyzer) word(w *WordForth) depthSummary {
+	s, ok := a.summaries[w]
+	if ok {
+		return s
+	}
+	if a.active[w] {
+		a.recursive(w.Entry)
+		return depthSummary{}
+	}
+	if w.Entry.Flag.Data || w.Entry.Flag.isDeferred {
+		a.unknown(w.Entry)
+		return depthSummary{}
+	}
+	a.active[w] = true
+	s = a.walk(w)
+	delete(a.active, w)
+	a.summaries[w] = s
+	return s
+}
+
+// Follow every path through a forth word, keeping the deepest.
+func (a *DepthAnalyzer) walk(w *WordForth) depthSummary {
+	dests := make(map[*CellDestination]int)
+	for i, c := range w.Cells {
+		if dest, ok := c.(*CellDestination); ok {
+			dests[dest] = i
+		}
+	}
+	type state struct {
+		depth  int
+		rDepth int
+	}
+	// the deepest stacks seen at the start of each cell
+	states := make([]state, len(w.Cells))
+	visited := make([]bool, len(w.Cells))
+	// the number of times each cell got deeper, a cell that
+	// keeps getting deeper is in a loop that grows the stack
+	deeper := make([]int, len(w.Cells))
+	type path struct {
+		index int
+		state
+	}
+	s := depthSummary{known: true}
+	exits := false
+	work := []path{{0, state{0, 0}}}
+	for len(work) > 0 {
+		p := work[len(work)-1]
+		work = work[:len(work)-1]
+		if p.index < 0 || p.index >= len(w.Cells) {
+			a.unknown(w.Entry) // left the word without exiting
+			return depthSummary{}
+		}
+		if visited[p.index] {
+			old := states[p.index]
+			if p.depth <= old.depth && p.rDepth <= old.rDepth {
+				continue
+			}
+			deeper[p.index] += 1
+			if deeper[p.index] > len(w.Cells) {
+				a.unknown(w.Entry)
+				return depthSummary{}
+			}
+			p.depth = max(p.depth, old.depth)
+			p.rDepth = max(p.rDepth, old.rDepth)
+		}
+		visited[p.index] = true
+		states[p.index] = p.state
+
+		c := w.Cells[p.index]
+		cs := a.cell(c)
+		if !cs.known {
+			return depthSummary{}
+		}
+		call := 0
+		if cs.called {
+			call = 1 // the return address
+		}
+		if a.mixed != nil && a.mixed.crosses(w, c) {
+			call += 1 // the return into the other threading model
+		}
+		s.peak = max(s.peak, p.depth+cs.peak)
+		s.rPeak = max(s.rPeak, p.rDepth+call+cs.rPeak)
+		next := state{p.depth + cs.grow, p.rDepth + cs.rGrow}
+
+		isExit := false
+		following := []int{p.index + 1}
+		switch cell := c.(type) {
+		case CellAddress:
+			isExit = cell.Entry.Flag.isExit
+		case *CellTailCall:
+			isExit = true
+		case *CellBranch:
+			following = []int{-1}
+			d, ok := dests[cell.dest]
+			if ok {
+				following[0] = d
+			}
+		case *CellBranch0:
+			d, ok := dests[cell.dest]
+			if !ok {
+				d = -1
+			}
+			following = append(following, d)
+		}
+		if isExit {
+			if !exits || next.depth > s.grow {
+				s.grow = next.depth
+			}
+			exits = true
+			continue
+		}
+		for _, f := range following {
+			work = append(work, path{f, next})
+		}
+	}
+	return s
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStackDepth(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		data      int
+		ret       int
+		recursive string // the recursive word expected
+		unknown   string // the word expected to be unknown
+	}{
+		{
+			name: "data stack",
+			code: "VARIABLE X : MAIN X @ X @ X @ + + X ! ;",
+			data: 3,
+		},
+		{
+			name: "return stack",
+			code: "VARIABLE X : MAIN X @ >R X @ >R R> R> + X ! ;",
+			data: 2,
+			ret:  3, // VM.INIT calls MAIN
+		},
+		{
+			name: "deepest branch",
+			code: "VARIABLE X : MAIN X @ IF X @ X @ X @ + + ELSE X @ THEN X ! ;",
+			data: 3,
+			ret:  2, // the repeated X @ is factored out
+		},
+		{
+			name:      "recursion",
+			code:      "VARIABLE X : R X @ IF X @ RECURSE DROP THEN ; : MAIN R ;",
+			recursive: "R",
+		},
+		{
+			name:    "undeclared assembly",
+			code:    "C\" sub r3, r3, 1\" 1 ASSEMBLY Y : MAIN Y ;",
+			unknown: "Y",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			vm := VirtualMachine{Out: &buff}
+			err := vm.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = vm.Execute([]byte(tt.code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ulp := Ulp{}
+			_, err = ulp.BuildAssembly(&vm, "MAIN")
+			if err != nil {
+				t.Fatal(err)
+			}
+			depth := ulp.Depth
+			if tt.recursive != "" || tt.unknown != "" {
+				if depth.Bounded() {
+					t.Fatalf("expected an unbounded depth")
+				}
+				if tt.recursive != "" && entryNames(depth.Recursive) != tt.recursive {
+					t.Errorf("expected %s to be recursive, got %s", tt.recursive, entryNames(depth.Recursive))
+				}
+				if tt.unknown != "" && entryNames(depth.Unknown) != tt.unknown {
+					t.Errorf("expected %s to be unknown, got %s", tt.unknown, entryNames(depth.Unknown))
+				}
+				return
+			}
+			if !depth.Bounded() {
+				t.Fatalf("expected a bounded depth, recursive: %s unknown: %s", entryNames(depth.Recursive), entryNames(depth.Unknown))
+			}
+			if depth.Data != tt.data || depth.Return != tt.ret {
+				t.Errorf("expected data %d return %d, got data %d return %d", tt.data, tt.ret, depth.Data, depth.Return)
+			}
+			_, err = depth.Check(4 * (depth.Cells() - 1))
+			if err == nil {
+				t.Errorf("expected the stacks to collide")
+			}
+		})
+	}
+}
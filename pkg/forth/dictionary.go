@@ -63,7 +63,7 @@ func (d *Dictionary) AddEntry(entry *DictionaryEntry) error {
 	name := entry.Name
 	if name != "" {
 		previous, _ := d.FindName(name)
-		if previous != nil {
+		if previous != nil && !d.vm.loadingTarget {
 			fmt.Fprintf(d.vm.Out, "Redefining %s ", name)
 		}
 	}
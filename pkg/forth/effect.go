@@ -0,0 +1,203 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import "fmt"
+
+// The number of cells a word takes from
+// the stack and the number it leaves.
+type StackEffect struct {
+	In    int  // The number of cells taken from the stack.
+	Out   int  // The number of cells left on the stack.
+	Known bool // The effect was declared or inferred.
+}
+
+func stackEffect(in int, out int) StackEffect {
+	return StackEffect{In: in, Out: out, Known: true}
+}
+
+func (e StackEffect) String() string {
+	if !e.Known {
+		return "( ? )"
+	}
+	return fmt.Sprintf("( %d -- %d )", e.In, e.Out)
+}
+
+// Checks that the stack depth is the same on every
+// path through a word. Forth words have their effect
+// inferred from their cells, primitives use their
+// declared effect. Words that call anything with an
+// unknown effect are not checked.
+type EffectChecker struct {
+	effects map[*WordForth]StackEffect // the inferred effect of each word
+	active  map[*WordForth]bool        // the words currently being inferred
+}
+
+// Check every word that can be reached from the entry word,
+// which is called with an empty stack.
+func (e *EffectChecker) Check(entry *DictionaryEntry) error {
+	w, ok := entry.Word.(*WordForth)
+	if !ok {
+		return nil
+	}
+	_, err := e.infer(w, true)
+	return err
+}
+
+// Check every word that can be reached from an exported
+// word, which can take cells from the caller's stack.
+func (e *EffectChecker) CheckExport(entry *DictionaryEntry) error {
+	w, ok := entry.Word.(*WordForth)
+	if !ok {
+		return nil
+	}
+	_, err := e.infer(w, false)
+	return err
+}
+
+// Get the effect of a cell that is executed.
+func (e *EffectChecker) cellEffect(c Cell) (StackEffect, *DictionaryEntry, error) {
+	switch cell := c.(type) {
+	case CellLiteral:
+		return stackEffect(0, 1), nil, nil
+	case CellAddress:
+		effect, err := e.entryEffect(cell.Entry)
+		return effect, cell.Entry, err
+	case *CellTailCall:
+		effect, err := e.infer(cell.dest, false)
+		return effect, cell.dest.Entry, err
+	case *CellInline:
+		return cell.word.Effect, cell.word.Entry, nil
+	case *CellBranch0:
+		return stackEffect(1, 0), nil, nil
+	}
+	return stackEffect(0, 0), nil, nil
+}
+
+func (e *EffectChecker) entryEffect(entry *DictionaryEntry) (StackEffect, error) {
+	switch w := entry.Word.(type) {
+	case *WordPrimitive:
+		return w.Effect, nil
+	case *WordForth:
+		return e.infer(w, false)
+	}
+	return StackEffect{}, nil
+}
+
+// Infer the stack effect of a forth word. If root is set the
+// word is called with an empty stack so it cannot take any cells.
+func (e *EffectChecker) infer(w *WordForth, root bool) (StackEffect, error) {
+	if e.effects == nil {
+		e.effects = make(map[*WordForth]StackEffect)
+		e.active = make(map[*WordForth]bool)
+	}
+	effect, ok := e.effects[w]
+	if ok {
+		return effect, nil
+	}
+	if w.Entry.Flag.Data || w.Entry.Flag.isDeferred || w.Entry.Flag.uncheckedStack || e.active[w] {
+		return StackEffect{}, nil // can't be known, or is recursive
+	}
+	e.active[w] = true
+	defer delete(e.active, w)
+
+	// the depth at each destination, relative to the start of the word
+	dests := make(map[*CellDestination]int)
+	for i, c := range w.Cells {
+		if dest, ok := c.(*CellDestination); ok {
+			dests[dest] = i
+		}
+	}
+	depths := make([]int, len(w.Cells))
+	visited := make([]bool, len(w.Cells))
+	exitDepth := 0
+	exits := false
+	lowest := 0
+	type path struct {
+		index int
+		depth int
+	}
+	work := []path{{0, 0}}
+	for len(work) > 0 {
+		p := work[len(work)-1]
+		work = work[:len(work)-1]
+		if p.index >= len(w.Cells) {
+			continue // ran off the end of the word
+		}
+		if visited[p.index] {
+			if depths[p.index] != p.depth {
+				return StackEffect{}, EntryError(w.Entry, "stack depth is %d on one path and %d on another in position %d", depths[p.index], p.depth, p.index)
+			}
+			continue
+		}
+		visited[p.index] = true
+		depths[p.index] = p.depth
+
+		c := w.Cells[p.index]
+		cellEffect, callee, err := e.cellEffect(c)
+		if err != nil {
+			return StackEffect{}, err
+		}
+		if !cellEffect.Known {
+			e.effects[w] = StackEffect{}
+			return StackEffect{}, nil
+		}
+		depth := p.depth - cellEffect.In
+		if depth < lowest {
+			lowest = depth
+			if root && callee != nil {
+				return StackEffect{}, EntryError(callee, "stack underflow, takes %d cells but only %d are on the stack", cellEffect.In, p.depth)
+			}
+			if root {
+				return StackEffect{}, EntryError(w.Entry, "stack underflow in position %d", p.index)
+			}
+		}
+		depth += cellEffect.Out
+
+		// find the cells that can run next
+		next := []int{p.index + 1}
+		isExit := false
+		switch cell := c.(type) {
+		case CellAddress:
+			isExit = cell.Entry.Flag.isExit
+		case *CellTailCall:
+			isExit = true
+		case *CellBranch:
+			next = []int{-1}
+			d, ok := dests[cell.dest]
+			if ok {
+				next[0] = d
+			}
+		case *CellBranch0:
+			d, ok := dests[cell.dest]
+			if ok {
+				next = append(next, d)
+			} else {
+				next = append(next, -1)
+			}
+		}
+		if isExit {
+			if exits && exitDepth != depth {
+				return StackEffect{}, EntryError(w.Entry, "exits with stack depth %d on one path and %d on another", exitDepth, depth)
+			}
+			exits = true
+			exitDepth = depth
+			continue
+		}
+		for _, n := range next {
+			if n == -1 { // branches outside of the word
+				e.effects[w] = StackEffect{}
+				return StackEffect{}, nil
+			}
+			work = append(work, path{n, depth})
+		}
+	}
+	effect = stackEffect(-lowest, exitDepth-lowest)
+	e.effects[w] = effect
+	return effect, nil
+}
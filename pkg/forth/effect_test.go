@@ -0,0 +1,171 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestStackEffect(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string // the code that defines TEST
+		expect string // the expected effect of TEST
+		err    bool   // expect TEST to be rejected
+	}{
+		{
+			name:   "primitives",
+			code:   ": TEST + DUP ;",
+			expect: "( 2 -- 2 )",
+		},
+		{
+			name:   "literals",
+			code:   ": TEST 1 2 3 ;",
+			expect: "( 0 -- 3 )",
+		},
+		{
+			name:   "forth words",
+			code:   ": SQUARE DUP * ; : TEST SQUARE SQUARE SWAP ;",
+			expect: "( 2 -- 2 )",
+		},
+		{
+			name:   "if else",
+			code:   ": TEST IF 1 ELSE 2 THEN ;",
+			expect: "( 1 -- 1 )",
+		},
+		{
+			name:   "loop",
+			code:   ": TEST 10 0 DO I DROP LOOP ;",
+			expect: "( 0 -- 0 )",
+		},
+		{
+			name:   "early exit",
+			code:   ": TEST IF 1 EXIT THEN 2 ;",
+			expect: "( 1 -- 1 )",
+		},
+		{
+			name:   "declared assembly",
+			code:   "C\" sub r3, r3, 1\" 1 ASSEMBLY X 0 1 LAST SET-STACK-EFFECT : TEST X X ;",
+			expect: "( 0 -- 2 )",
+		},
+		{
+			name:   "undeclared assembly",
+			code:   "C\" sub r3, r3, 1\" 1 ASSEMBLY X : TEST X X ;",
+			expect: "( ? )",
+		},
+		{
+			name:   "unchecked word",
+			code:   ": TEST ?DUP IF 1 THEN ;",
+			expect: "( ? )",
+		},
+		{
+			name: "different if depths",
+			code: ": TEST IF 1 THEN ;",
+			err:  true,
+		},
+		{
+			name: "different exit depths",
+			code: ": TEST IF 1 2 EXIT THEN 3 ;",
+			err:  true,
+		},
+		{
+			name: "unbalanced loop",
+			code: ": TEST BEGIN 1 AGAIN ;",
+			err:  true,
+		},
+		{
+			name: "unbalanced called word",
+			code: ": X IF 1 THEN ; : TEST 0 X ;",
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			vm := VirtualMachine{Out: &buff}
+			err := vm.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = vm.Execute([]byte(tt.code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry, err := vm.Dictionary.FindName("TEST")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checker := EffectChecker{}
+			effect, err := checker.infer(entry.Word.(*WordForth), false)
+			if tt.err {
+				var entryErr DictionaryEntryError
+				if !errors.As(err, &entryErr) {
+					t.Fatalf("expected a DictionaryEntryError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if effect.String() != tt.expect {
+				t.Errorf("expected %s got %s", tt.expect, effect)
+			}
+		})
+	}
+}
+
+func TestStackEffectBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		err  bool
+	}{
+		{
+			name: "balanced",
+			code: ": MAIN 1 2 + DROP ;",
+		},
+		{
+			name: "underflow",
+			code: ": MAIN 1 + DROP ;",
+			err:  true,
+		},
+		{
+			name: "different depths",
+			code: ": MAIN 1 IF 2 THEN ;",
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			vm := VirtualMachine{Out: &buff}
+			err := vm.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = vm.Execute([]byte(tt.code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ulp := Ulp{CheckStack: true}
+			_, err = ulp.BuildAssembly(&vm, "MAIN")
+			if !tt.err {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var entryErr DictionaryEntryError
+			if !errors.As(err, &entryErr) {
+				t.Fatalf("expected a DictionaryEntryError, got %v", err)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@
 \ License, v. 2.0. If a copy of the MPL was not distributed with this
 \ file, You can obtain one at https://mozilla.org/MPL/2.0/.
 
+0x3ff48000 CONSTANT DR_REG_RTCCNTL_BASE
+
 0x3FF4800C CONSTANT RTC_CNTL_TIME_UPDATE_REG
 31 CONSTANT RTC_CNTL_TIME_UPDATE_S
 30 CONSTANT RTC_CNTL_TIME_VALID_S
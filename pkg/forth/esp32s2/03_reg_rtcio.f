@@ -0,0 +1,90 @@
+\ Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+\ This Source Code Form is subject to the terms of the Mozilla Public
+\ License, v. 2.0. If a copy of the MPL was not distributed with this
+\ file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+\ This file contains RTCIO_* constants for the esp32-s2.
+
+0x3F408400 CONSTANT RTCIO_RTC_GPIO_OUT_REG
+10 CONSTANT RTCIO_RTC_GPIO_OUT_DATA_S
+
+0x3F408404 CONSTANT RTCIO_RTC_GPIO_OUT_W1TS_REG
+10 CONSTANT RTCIO_RTC_GPIO_OUT_DATA_W1TS_S
+
+0x3F408408 CONSTANT RTCIO_RTC_GPIO_OUT_W1TC_REG
+10 CONSTANT RTCIO_RTC_GPIO_OUT_DATA_W1TC_S
+
+0x3F40840C CONSTANT RTCIO_RTC_GPIO_ENABLE_REG
+
+0x3F408410 CONSTANT RTCIO_RTC_GPIO_ENABLE_W1TS_REG
+10 CONSTANT RTCIO_RTC_GPIO_ENABLE_W1TS_S
+
+0x3F408414 CONSTANT RTCIO_RTC_GPIO_ENABLE_W1TC_REG
+10 CONSTANT RTCIO_RTC_GPIO_ENABLE_W1TC_S
+
+0x3F408418 CONSTANT RTCIO_RTC_GPIO_STATUS_REG
+0x3F40841C CONSTANT RTCIO_RTC_GPIO_STATUS_W1TS_REG
+0x3F408420 CONSTANT RTCIO_RTC_GPIO_STATUS_W1TC_REG
+
+0x3F408424 CONSTANT RTCIO_RTC_GPIO_IN_REG
+10 CONSTANT RTCIO_RTC_GPIO_IN_NEXT_S
+
+0x3F408428 CONSTANT RTCIO_RTC_GPIO_PIN0_REG
+0x3F40842C CONSTANT RTCIO_RTC_GPIO_PIN1_REG
+0x3F408430 CONSTANT RTCIO_RTC_GPIO_PIN2_REG
+0x3F408434 CONSTANT RTCIO_RTC_GPIO_PIN3_REG
+0x3F408438 CONSTANT RTCIO_RTC_GPIO_PIN4_REG
+0x3F40843C CONSTANT RTCIO_RTC_GPIO_PIN5_REG
+0x3F408440 CONSTANT RTCIO_RTC_GPIO_PIN6_REG
+0x3F408444 CONSTANT RTCIO_RTC_GPIO_PIN7_REG
+0x3F408448 CONSTANT RTCIO_RTC_GPIO_PIN8_REG
+0x3F40844C CONSTANT RTCIO_RTC_GPIO_PIN9_REG
+0x3F408450 CONSTANT RTCIO_RTC_GPIO_PIN10_REG
+0x3F408454 CONSTANT RTCIO_RTC_GPIO_PIN11_REG
+0x3F408458 CONSTANT RTCIO_RTC_GPIO_PIN12_REG
+0x3F40845C CONSTANT RTCIO_RTC_GPIO_PIN13_REG
+0x3F408460 CONSTANT RTCIO_RTC_GPIO_PIN14_REG
+0x3F408464 CONSTANT RTCIO_RTC_GPIO_PIN15_REG
+0x3F408468 CONSTANT RTCIO_RTC_GPIO_PIN16_REG
+0x3F40846C CONSTANT RTCIO_RTC_GPIO_PIN17_REG
+0x3F408470 CONSTANT RTCIO_RTC_GPIO_PIN18_REG
+0x3F408474 CONSTANT RTCIO_RTC_GPIO_PIN19_REG
+0x3F408478 CONSTANT RTCIO_RTC_GPIO_PIN20_REG
+0x3F40847C CONSTANT RTCIO_RTC_GPIO_PIN21_REG
+
+\ the pad registers of RTC_GPIO0 to RTC_GPIO14
+0x3F408484 CONSTANT RTCIO_TOUCH_PAD0_REG
+0x3F408488 CONSTANT RTCIO_TOUCH_PAD1_REG
+0x3F40848C CONSTANT RTCIO_TOUCH_PAD2_REG
+0x3F408490 CONSTANT RTCIO_TOUCH_PAD3_REG
+0x3F408494 CONSTANT RTCIO_TOUCH_PAD4_REG
+0x3F408498 CONSTANT RTCIO_TOUCH_PAD5_REG
+0x3F40849C CONSTANT RTCIO_TOUCH_PAD6_REG
+0x3F4084A0 CONSTANT RTCIO_TOUCH_PAD7_REG
+0x3F4084A4 CONSTANT RTCIO_TOUCH_PAD8_REG
+0x3F4084A8 CONSTANT RTCIO_TOUCH_PAD9_REG
+0x3F4084AC CONSTANT RTCIO_TOUCH_PAD10_REG
+0x3F4084B0 CONSTANT RTCIO_TOUCH_PAD11_REG
+0x3F4084B4 CONSTANT RTCIO_TOUCH_PAD12_REG
+0x3F4084B8 CONSTANT RTCIO_TOUCH_PAD13_REG
+0x3F4084BC CONSTANT RTCIO_TOUCH_PAD14_REG
+
+\ the pad registers of RTC_GPIO15 to RTC_GPIO21
+0x3F4084C0 CONSTANT RTCIO_XTAL_32P_PAD_REG
+0x3F4084C4 CONSTANT RTCIO_XTAL_32N_PAD_REG
+0x3F4084C8 CONSTANT RTCIO_PAD_DAC1_REG
+0x3F4084CC CONSTANT RTCIO_PAD_DAC2_REG
+0x3F4084D0 CONSTANT RTCIO_RTC_PAD19_REG
+0x3F4084D4 CONSTANT RTCIO_RTC_PAD20_REG
+0x3F4084D8 CONSTANT RTCIO_RTC_PAD21_REG
+
+\ every pad register has the same fields
+29 CONSTANT RTCIO_PAD_DRV_S
+28 CONSTANT RTCIO_PAD_RDE_S
+27 CONSTANT RTCIO_PAD_RUE_S
+19 CONSTANT RTCIO_PAD_MUX_SEL_S
+17 CONSTANT RTCIO_PAD_FUN_SEL_S
+16 CONSTANT RTCIO_PAD_SLP_SEL_S
+15 CONSTANT RTCIO_PAD_SLP_IE_S
+14 CONSTANT RTCIO_PAD_SLP_OE_S
+13 CONSTANT RTCIO_PAD_FUN_IE_S
@@ -0,0 +1,29 @@
+\ Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+\ This Source Code Form is subject to the terms of the Mozilla Public
+\ License, v. 2.0. If a copy of the MPL was not distributed with this
+\ file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+\ This file contains RTC_CNTL_* constants for the esp32-s3.
+
+0x60008000 CONSTANT DR_REG_RTCCNTL_BASE
+
+0x6000800C CONSTANT RTC_CNTL_TIME_UPDATE_REG
+31 CONSTANT RTC_CNTL_TIME_UPDATE_S
+
+0x60008010 CONSTANT RTC_CNTL_TIME0_REG
+0x60008014 CONSTANT RTC_CNTL_TIME1_REG
+0x600080D0 CONSTANT RTC_CNTL_LOW_POWER_ST_REG
+27 CONSTANT RTC_CNTL_MAIN_STATE_IN_IDLE_S
+19 CONSTANT RTC_CNTL_RTC_RDY_FOR_WAKEUP_S
+
+\ The upper 16 bits of the RTC register addresses,
+\ used by the ULP-RISC-V. Cells only hold the lower 16 bits.
+0x6000 CONSTANT DR_REG_RTC_HIGH
+
+0x60008018 CONSTANT RTC_CNTL_STATE0_REG
+0 CONSTANT RTC_CNTL_SW_CPU_INT_S
+
+0x60008104 CONSTANT RTC_CNTL_COCPU_CTRL_REG
+14 CONSTANT RTC_CNTL_COCPU_SHUT_2_CLK_DIS_S
+22 CONSTANT RTC_CNTL_COCPU_SHUT_RESET_EN_S
+25 CONSTANT RTC_CNTL_COCPU_DONE_S
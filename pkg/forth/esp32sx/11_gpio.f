@@ -0,0 +1,654 @@
+\ Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+\ This Source Code Form is subject to the terms of the Mozilla Public
+\ License, v. 2.0. If a copy of the MPL was not distributed with this
+\ file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+\ The RTC_GPIO numbers of the esp32-s2 and esp32-s3 are the
+\ same as the GPIO numbers, and every pad register has the same fields.
+
+\ words to enable RTC_GPIO
+
+: --CREATE-ENABLE ( pad-reg "<spaces>name" -- )
+    DUP >R RTCIO_PAD_MUX_SEL_S 1 1
+    R> RTCIO_PAD_FUN_SEL_S 2 0
+    2WRITE_RTC_REG
+;
+
+RTCIO_TOUCH_PAD0_REG    --CREATE-ENABLE RTC_GPIO0.ENABLE
+RTCIO_TOUCH_PAD1_REG    --CREATE-ENABLE RTC_GPIO1.ENABLE
+RTCIO_TOUCH_PAD2_REG    --CREATE-ENABLE RTC_GPIO2.ENABLE
+RTCIO_TOUCH_PAD3_REG    --CREATE-ENABLE RTC_GPIO3.ENABLE
+RTCIO_TOUCH_PAD4_REG    --CREATE-ENABLE RTC_GPIO4.ENABLE
+RTCIO_TOUCH_PAD5_REG    --CREATE-ENABLE RTC_GPIO5.ENABLE
+RTCIO_TOUCH_PAD6_REG    --CREATE-ENABLE RTC_GPIO6.ENABLE
+RTCIO_TOUCH_PAD7_REG    --CREATE-ENABLE RTC_GPIO7.ENABLE
+RTCIO_TOUCH_PAD8_REG    --CREATE-ENABLE RTC_GPIO8.ENABLE
+RTCIO_TOUCH_PAD9_REG    --CREATE-ENABLE RTC_GPIO9.ENABLE
+RTCIO_TOUCH_PAD10_REG   --CREATE-ENABLE RTC_GPIO10.ENABLE
+RTCIO_TOUCH_PAD11_REG   --CREATE-ENABLE RTC_GPIO11.ENABLE
+RTCIO_TOUCH_PAD12_REG   --CREATE-ENABLE RTC_GPIO12.ENABLE
+RTCIO_TOUCH_PAD13_REG   --CREATE-ENABLE RTC_GPIO13.ENABLE
+RTCIO_TOUCH_PAD14_REG   --CREATE-ENABLE RTC_GPIO14.ENABLE
+RTCIO_XTAL_32P_PAD_REG  --CREATE-ENABLE RTC_GPIO15.ENABLE
+RTCIO_XTAL_32N_PAD_REG  --CREATE-ENABLE RTC_GPIO16.ENABLE
+RTCIO_PAD_DAC1_REG      --CREATE-ENABLE RTC_GPIO17.ENABLE
+RTCIO_PAD_DAC2_REG      --CREATE-ENABLE RTC_GPIO18.ENABLE
+RTCIO_RTC_PAD19_REG     --CREATE-ENABLE RTC_GPIO19.ENABLE
+RTCIO_RTC_PAD20_REG     --CREATE-ENABLE RTC_GPIO20.ENABLE
+RTCIO_RTC_PAD21_REG     --CREATE-ENABLE RTC_GPIO21.ENABLE
+
+\ words to enable output on RTC_GPIO
+
+: --CREATE-OUTPUTENABLE ( n "<spaces>name" -- )
+    RTCIO_RTC_GPIO_ENABLE_W1TS_REG
+    SWAP RTCIO_RTC_GPIO_ENABLE_W1TS_S +
+    1 1
+    WRITE_RTC_REG
+;
+
+0  --CREATE-OUTPUTENABLE RTC_GPIO0.OUTPUT_ENABLE
+1  --CREATE-OUTPUTENABLE RTC_GPIO1.OUTPUT_ENABLE
+2  --CREATE-OUTPUTENABLE RTC_GPIO2.OUTPUT_ENABLE
+3  --CREATE-OUTPUTENABLE RTC_GPIO3.OUTPUT_ENABLE
+4  --CREATE-OUTPUTENABLE RTC_GPIO4.OUTPUT_ENABLE
+5  --CREATE-OUTPUTENABLE RTC_GPIO5.OUTPUT_ENABLE
+6  --CREATE-OUTPUTENABLE RTC_GPIO6.OUTPUT_ENABLE
+7  --CREATE-OUTPUTENABLE RTC_GPIO7.OUTPUT_ENABLE
+8  --CREATE-OUTPUTENABLE RTC_GPIO8.OUTPUT_ENABLE
+9  --CREATE-OUTPUTENABLE RTC_GPIO9.OUTPUT_ENABLE
+10 --CREATE-OUTPUTENABLE RTC_GPIO10.OUTPUT_ENABLE
+11 --CREATE-OUTPUTENABLE RTC_GPIO11.OUTPUT_ENABLE
+12 --CREATE-OUTPUTENABLE RTC_GPIO12.OUTPUT_ENABLE
+13 --CREATE-OUTPUTENABLE RTC_GPIO13.OUTPUT_ENABLE
+14 --CREATE-OUTPUTENABLE RTC_GPIO14.OUTPUT_ENABLE
+15 --CREATE-OUTPUTENABLE RTC_GPIO15.OUTPUT_ENABLE
+16 --CREATE-OUTPUTENABLE RTC_GPIO16.OUTPUT_ENABLE
+17 --CREATE-OUTPUTENABLE RTC_GPIO17.OUTPUT_ENABLE
+18 --CREATE-OUTPUTENABLE RTC_GPIO18.OUTPUT_ENABLE
+19 --CREATE-OUTPUTENABLE RTC_GPIO19.OUTPUT_ENABLE
+20 --CREATE-OUTPUTENABLE RTC_GPIO20.OUTPUT_ENABLE
+21 --CREATE-OUTPUTENABLE RTC_GPIO21.OUTPUT_ENABLE
+
+\ words to disable output on RTC_GPIO
+
+: --CREATE-OUTPUTDISABLE ( n "<spaces>name" -- )
+    RTCIO_RTC_GPIO_ENABLE_W1TC_REG
+    SWAP RTCIO_RTC_GPIO_ENABLE_W1TC_S +
+    1 1
+    WRITE_RTC_REG
+;
+
+0  --CREATE-OUTPUTDISABLE RTC_GPIO0.OUTPUT_DISABLE
+1  --CREATE-OUTPUTDISABLE RTC_GPIO1.OUTPUT_DISABLE
+2  --CREATE-OUTPUTDISABLE RTC_GPIO2.OUTPUT_DISABLE
+3  --CREATE-OUTPUTDISABLE RTC_GPIO3.OUTPUT_DISABLE
+4  --CREATE-OUTPUTDISABLE RTC_GPIO4.OUTPUT_DISABLE
+5  --CREATE-OUTPUTDISABLE RTC_GPIO5.OUTPUT_DISABLE
+6  --CREATE-OUTPUTDISABLE RTC_GPIO6.OUTPUT_DISABLE
+7  --CREATE-OUTPUTDISABLE RTC_GPIO7.OUTPUT_DISABLE
+8  --CREATE-OUTPUTDISABLE RTC_GPIO8.OUTPUT_DISABLE
+9  --CREATE-OUTPUTDISABLE RTC_GPIO9.OUTPUT_DISABLE
+10 --CREATE-OUTPUTDISABLE RTC_GPIO10.OUTPUT_DISABLE
+11 --CREATE-OUTPUTDISABLE RTC_GPIO11.OUTPUT_DISABLE
+12 --CREATE-OUTPUTDISABLE RTC_GPIO12.OUTPUT_DISABLE
+13 --CREATE-OUTPUTDISABLE RTC_GPIO13.OUTPUT_DISABLE
+14 --CREATE-OUTPUTDISABLE RTC_GPIO14.OUTPUT_DISABLE
+15 --CREATE-OUTPUTDISABLE RTC_GPIO15.OUTPUT_DISABLE
+16 --CREATE-OUTPUTDISABLE RTC_GPIO16.OUTPUT_DISABLE
+17 --CREATE-OUTPUTDISABLE RTC_GPIO17.OUTPUT_DISABLE
+18 --CREATE-OUTPUTDISABLE RTC_GPIO18.OUTPUT_DISABLE
+19 --CREATE-OUTPUTDISABLE RTC_GPIO19.OUTPUT_DISABLE
+20 --CREATE-OUTPUTDISABLE RTC_GPIO20.OUTPUT_DISABLE
+21 --CREATE-OUTPUTDISABLE RTC_GPIO21.OUTPUT_DISABLE
+
+\ words to enable input on RTC_GPIO
+
+: --CREATE-INPUTENABLE ( pad-reg "<spaces>name" -- )
+    RTCIO_PAD_FUN_IE_S 1 1
+    WRITE_RTC_REG
+;
+
+RTCIO_TOUCH_PAD0_REG    --CREATE-INPUTENABLE RTC_GPIO0.INPUT_ENABLE
+RTCIO_TOUCH_PAD1_REG    --CREATE-INPUTENABLE RTC_GPIO1.INPUT_ENABLE
+RTCIO_TOUCH_PAD2_REG    --CREATE-INPUTENABLE RTC_GPIO2.INPUT_ENABLE
+RTCIO_TOUCH_PAD3_REG    --CREATE-INPUTENABLE RTC_GPIO3.INPUT_ENABLE
+RTCIO_TOUCH_PAD4_REG    --CREATE-INPUTENABLE RTC_GPIO4.INPUT_ENABLE
+RTCIO_TOUCH_PAD5_REG    --CREATE-INPUTENABLE RTC_GPIO5.INPUT_ENABLE
+RTCIO_TOUCH_PAD6_REG    --CREATE-INPUTENABLE RTC_GPIO6.INPUT_ENABLE
+RTCIO_TOUCH_PAD7_REG    --CREATE-INPUTENABLE RTC_GPIO7.INPUT_ENABLE
+RTCIO_TOUCH_PAD8_REG    --CREATE-INPUTENABLE RTC_GPIO8.INPUT_ENABLE
+RTCIO_TOUCH_PAD9_REG    --CREATE-INPUTENABLE RTC_GPIO9.INPUT_ENABLE
+RTCIO_TOUCH_PAD10_REG   --CREATE-INPUTENABLE RTC_GPIO10.INPUT_ENABLE
+RTCIO_TOUCH_PAD11_REG   --CREATE-INPUTENABLE RTC_GPIO11.INPUT_ENABLE
+RTCIO_TOUCH_PAD12_REG   --CREATE-INPUTENABLE RTC_GPIO12.INPUT_ENABLE
+RTCIO_TOUCH_PAD13_REG   --CREATE-INPUTENABLE RTC_GPIO13.INPUT_ENABLE
+RTCIO_TOUCH_PAD14_REG   --CREATE-INPUTENABLE RTC_GPIO14.INPUT_ENABLE
+RTCIO_XTAL_32P_PAD_REG  --CREATE-INPUTENABLE RTC_GPIO15.INPUT_ENABLE
+RTCIO_XTAL_32N_PAD_REG  --CREATE-INPUTENABLE RTC_GPIO16.INPUT_ENABLE
+RTCIO_PAD_DAC1_REG      --CREATE-INPUTENABLE RTC_GPIO17.INPUT_ENABLE
+RTCIO_PAD_DAC2_REG      --CREATE-INPUTENABLE RTC_GPIO18.INPUT_ENABLE
+RTCIO_RTC_PAD19_REG     --CREATE-INPUTENABLE RTC_GPIO19.INPUT_ENABLE
+RTCIO_RTC_PAD20_REG     --CREATE-INPUTENABLE RTC_GPIO20.INPUT_ENABLE
+RTCIO_RTC_PAD21_REG     --CREATE-INPUTENABLE RTC_GPIO21.INPUT_ENABLE
+
+\ words to set RTC_GPIO high
+
+: --CREATE-SETHIGH ( n "<spaces>name" -- )
+    RTCIO_RTC_GPIO_OUT_W1TS_REG
+    SWAP RTCIO_RTC_GPIO_OUT_DATA_W1TS_S +
+    1 1
+    WRITE_RTC_REG
+;
+
+0  --CREATE-SETHIGH RTC_GPIO0.SET_HIGH
+1  --CREATE-SETHIGH RTC_GPIO1.SET_HIGH
+2  --CREATE-SETHIGH RTC_GPIO2.SET_HIGH
+3  --CREATE-SETHIGH RTC_GPIO3.SET_HIGH
+4  --CREATE-SETHIGH RTC_GPIO4.SET_HIGH
+5  --CREATE-SETHIGH RTC_GPIO5.SET_HIGH
+6  --CREATE-SETHIGH RTC_GPIO6.SET_HIGH
+7  --CREATE-SETHIGH RTC_GPIO7.SET_HIGH
+8  --CREATE-SETHIGH RTC_GPIO8.SET_HIGH
+9  --CREATE-SETHIGH RTC_GPIO9.SET_HIGH
+10 --CREATE-SETHIGH RTC_GPIO10.SET_HIGH
+11 --CREATE-SETHIGH RTC_GPIO11.SET_HIGH
+12 --CREATE-SETHIGH RTC_GPIO12.SET_HIGH
+13 --CREATE-SETHIGH RTC_GPIO13.SET_HIGH
+14 --CREATE-SETHIGH RTC_GPIO14.SET_HIGH
+15 --CREATE-SETHIGH RTC_GPIO15.SET_HIGH
+16 --CREATE-SETHIGH RTC_GPIO16.SET_HIGH
+17 --CREATE-SETHIGH RTC_GPIO17.SET_HIGH
+18 --CREATE-SETHIGH RTC_GPIO18.SET_HIGH
+19 --CREATE-SETHIGH RTC_GPIO19.SET_HIGH
+20 --CREATE-SETHIGH RTC_GPIO20.SET_HIGH
+21 --CREATE-SETHIGH RTC_GPIO21.SET_HIGH
+
+\ words to set RTC_GPIO low
+
+: --CREATE-SETLOW ( n "<spaces>name" -- )
+    RTCIO_RTC_GPIO_OUT_W1TC_REG
+    SWAP RTCIO_RTC_GPIO_OUT_DATA_W1TC_S +
+    1 1
+    WRITE_RTC_REG
+;
+
+0  --CREATE-SETLOW RTC_GPIO0.SET_LOW
+1  --CREATE-SETLOW RTC_GPIO1.SET_LOW
+2  --CREATE-SETLOW RTC_GPIO2.SET_LOW
+3  --CREATE-SETLOW RTC_GPIO3.SET_LOW
+4  --CREATE-SETLOW RTC_GPIO4.SET_LOW
+5  --CREATE-SETLOW RTC_GPIO5.SET_LOW
+6  --CREATE-SETLOW RTC_GPIO6.SET_LOW
+7  --CREATE-SETLOW RTC_GPIO7.SET_LOW
+8  --CREATE-SETLOW RTC_GPIO8.SET_LOW
+9  --CREATE-SETLOW RTC_GPIO9.SET_LOW
+10 --CREATE-SETLOW RTC_GPIO10.SET_LOW
+11 --CREATE-SETLOW RTC_GPIO11.SET_LOW
+12 --CREATE-SETLOW RTC_GPIO12.SET_LOW
+13 --CREATE-SETLOW RTC_GPIO13.SET_LOW
+14 --CREATE-SETLOW RTC_GPIO14.SET_LOW
+15 --CREATE-SETLOW RTC_GPIO15.SET_LOW
+16 --CREATE-SETLOW RTC_GPIO16.SET_LOW
+17 --CREATE-SETLOW RTC_GPIO17.SET_LOW
+18 --CREATE-SETLOW RTC_GPIO18.SET_LOW
+19 --CREATE-SETLOW RTC_GPIO19.SET_LOW
+20 --CREATE-SETLOW RTC_GPIO20.SET_LOW
+21 --CREATE-SETLOW RTC_GPIO21.SET_LOW
+
+\ words to get RTC_GPIO levels
+\
+\ these are done separately for speed
+\ and consistent read times
+
+: --CREATE-GET ( n "<spaces>name" -- )
+    RTCIO_RTC_GPIO_IN_REG
+    SWAP RTCIO_RTC_GPIO_IN_NEXT_S +
+    1
+    READ_RTC_REG
+;
+
+0  --CREATE-GET RTC_GPIO0.GET
+1  --CREATE-GET RTC_GPIO1.GET
+2  --CREATE-GET RTC_GPIO2.GET
+3  --CREATE-GET RTC_GPIO3.GET
+4  --CREATE-GET RTC_GPIO4.GET
+5  --CREATE-GET RTC_GPIO5.GET
+6  --CREATE-GET RTC_GPIO6.GET
+7  --CREATE-GET RTC_GPIO7.GET
+8  --CREATE-GET RTC_GPIO8.GET
+9  --CREATE-GET RTC_GPIO9.GET
+10 --CREATE-GET RTC_GPIO10.GET
+11 --CREATE-GET RTC_GPIO11.GET
+12 --CREATE-GET RTC_GPIO12.GET
+13 --CREATE-GET RTC_GPIO13.GET
+14 --CREATE-GET RTC_GPIO14.GET
+15 --CREATE-GET RTC_GPIO15.GET
+16 --CREATE-GET RTC_GPIO16.GET
+17 --CREATE-GET RTC_GPIO17.GET
+18 --CREATE-GET RTC_GPIO18.GET
+19 --CREATE-GET RTC_GPIO19.GET
+20 --CREATE-GET RTC_GPIO20.GET
+21 --CREATE-GET RTC_GPIO21.GET
+
+\ words to enable and disable RTC_GPIO pullups
+
+: --CREATE-PULLUP ( pad-reg enable "<spaces>name" -- )
+    >R RTCIO_PAD_RUE_S 1 R>
+    WRITE_RTC_REG
+;
+
+RTCIO_TOUCH_PAD0_REG    1 --CREATE-PULLUP RTC_GPIO0.PULLUP_ENABLE
+RTCIO_TOUCH_PAD0_REG    0 --CREATE-PULLUP RTC_GPIO0.PULLUP_DISABLE
+RTCIO_TOUCH_PAD1_REG    1 --CREATE-PULLUP RTC_GPIO1.PULLUP_ENABLE
+RTCIO_TOUCH_PAD1_REG    0 --CREATE-PULLUP RTC_GPIO1.PULLUP_DISABLE
+RTCIO_TOUCH_PAD2_REG    1 --CREATE-PULLUP RTC_GPIO2.PULLUP_ENABLE
+RTCIO_TOUCH_PAD2_REG    0 --CREATE-PULLUP RTC_GPIO2.PULLUP_DISABLE
+RTCIO_TOUCH_PAD3_REG    1 --CREATE-PULLUP RTC_GPIO3.PULLUP_ENABLE
+RTCIO_TOUCH_PAD3_REG    0 --CREATE-PULLUP RTC_GPIO3.PULLUP_DISABLE
+RTCIO_TOUCH_PAD4_REG    1 --CREATE-PULLUP RTC_GPIO4.PULLUP_ENABLE
+RTCIO_TOUCH_PAD4_REG    0 --CREATE-PULLUP RTC_GPIO4.PULLUP_DISABLE
+RTCIO_TOUCH_PAD5_REG    1 --CREATE-PULLUP RTC_GPIO5.PULLUP_ENABLE
+RTCIO_TOUCH_PAD5_REG    0 --CREATE-PULLUP RTC_GPIO5.PULLUP_DISABLE
+RTCIO_TOUCH_PAD6_REG    1 --CREATE-PULLUP RTC_GPIO6.PULLUP_ENABLE
+RTCIO_TOUCH_PAD6_REG    0 --CREATE-PULLUP RTC_GPIO6.PULLUP_DISABLE
+RTCIO_TOUCH_PAD7_REG    1 --CREATE-PULLUP RTC_GPIO7.PULLUP_ENABLE
+RTCIO_TOUCH_PAD7_REG    0 --CREATE-PULLUP RTC_GPIO7.PULLUP_DISABLE
+RTCIO_TOUCH_PAD8_REG    1 --CREATE-PULLUP RTC_GPIO8.PULLUP_ENABLE
+RTCIO_TOUCH_PAD8_REG    0 --CREATE-PULLUP RTC_GPIO8.PULLUP_DISABLE
+RTCIO_TOUCH_PAD9_REG    1 --CREATE-PULLUP RTC_GPIO9.PULLUP_ENABLE
+RTCIO_TOUCH_PAD9_REG    0 --CREATE-PULLUP RTC_GPIO9.PULLUP_DISABLE
+RTCIO_TOUCH_PAD10_REG   1 --CREATE-PULLUP RTC_GPIO10.PULLUP_ENABLE
+RTCIO_TOUCH_PAD10_REG   0 --CREATE-PULLUP RTC_GPIO10.PULLUP_DISABLE
+RTCIO_TOUCH_PAD11_REG   1 --CREATE-PULLUP RTC_GPIO11.PULLUP_ENABLE
+RTCIO_TOUCH_PAD11_REG   0 --CREATE-PULLUP RTC_GPIO11.PULLUP_DISABLE
+RTCIO_TOUCH_PAD12_REG   1 --CREATE-PULLUP RTC_GPIO12.PULLUP_ENABLE
+RTCIO_TOUCH_PAD12_REG   0 --CREATE-PULLUP RTC_GPIO12.PULLUP_DISABLE
+RTCIO_TOUCH_PAD13_REG   1 --CREATE-PULLUP RTC_GPIO13.PULLUP_ENABLE
+RTCIO_TOUCH_PAD13_REG   0 --CREATE-PULLUP RTC_GPIO13.PULLUP_DISABLE
+RTCIO_TOUCH_PAD14_REG   1 --CREATE-PULLUP RTC_GPIO14.PULLUP_ENABLE
+RTCIO_TOUCH_PAD14_REG   0 --CREATE-PULLUP RTC_GPIO14.PULLUP_DISABLE
+RTCIO_XTAL_32P_PAD_REG  1 --CREATE-PULLUP RTC_GPIO15.PULLUP_ENABLE
+RTCIO_XTAL_32P_PAD_REG  0 --CREATE-PULLUP RTC_GPIO15.PULLUP_DISABLE
+RTCIO_XTAL_32N_PAD_REG  1 --CREATE-PULLUP RTC_GPIO16.PULLUP_ENABLE
+RTCIO_XTAL_32N_PAD_REG  0 --CREATE-PULLUP RTC_GPIO16.PULLUP_DISABLE
+RTCIO_PAD_DAC1_REG      1 --CREATE-PULLUP RTC_GPIO17.PULLUP_ENABLE
+RTCIO_PAD_DAC1_REG      0 --CREATE-PULLUP RTC_GPIO17.PULLUP_DISABLE
+RTCIO_PAD_DAC2_REG      1 --CREATE-PULLUP RTC_GPIO18.PULLUP_ENABLE
+RTCIO_PAD_DAC2_REG      0 --CREATE-PULLUP RTC_GPIO18.PULLUP_DISABLE
+RTCIO_RTC_PAD19_REG     1 --CREATE-PULLUP RTC_GPIO19.PULLUP_ENABLE
+RTCIO_RTC_PAD19_REG     0 --CREATE-PULLUP RTC_GPIO19.PULLUP_DISABLE
+RTCIO_RTC_PAD20_REG     1 --CREATE-PULLUP RTC_GPIO20.PULLUP_ENABLE
+RTCIO_RTC_PAD20_REG     0 --CREATE-PULLUP RTC_GPIO20.PULLUP_DISABLE
+RTCIO_RTC_PAD21_REG     1 --CREATE-PULLUP RTC_GPIO21.PULLUP_ENABLE
+RTCIO_RTC_PAD21_REG     0 --CREATE-PULLUP RTC_GPIO21.PULLUP_DISABLE
+
+\ words to enable and disable RTC_GPIO pulldowns
+
+: --CREATE-PULLDOWN ( pad-reg enable "<spaces>name" -- )
+    >R RTCIO_PAD_RDE_S 1 R>
+    WRITE_RTC_REG
+;
+
+RTCIO_TOUCH_PAD0_REG    1 --CREATE-PULLDOWN RTC_GPIO0.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD0_REG    0 --CREATE-PULLDOWN RTC_GPIO0.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD1_REG    1 --CREATE-PULLDOWN RTC_GPIO1.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD1_REG    0 --CREATE-PULLDOWN RTC_GPIO1.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD2_REG    1 --CREATE-PULLDOWN RTC_GPIO2.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD2_REG    0 --CREATE-PULLDOWN RTC_GPIO2.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD3_REG    1 --CREATE-PULLDOWN RTC_GPIO3.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD3_REG    0 --CREATE-PULLDOWN RTC_GPIO3.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD4_REG    1 --CREATE-PULLDOWN RTC_GPIO4.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD4_REG    0 --CREATE-PULLDOWN RTC_GPIO4.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD5_REG    1 --CREATE-PULLDOWN RTC_GPIO5.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD5_REG    0 --CREATE-PULLDOWN RTC_GPIO5.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD6_REG    1 --CREATE-PULLDOWN RTC_GPIO6.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD6_REG    0 --CREATE-PULLDOWN RTC_GPIO6.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD7_REG    1 --CREATE-PULLDOWN RTC_GPIO7.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD7_REG    0 --CREATE-PULLDOWN RTC_GPIO7.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD8_REG    1 --CREATE-PULLDOWN RTC_GPIO8.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD8_REG    0 --CREATE-PULLDOWN RTC_GPIO8.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD9_REG    1 --CREATE-PULLDOWN RTC_GPIO9.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD9_REG    0 --CREATE-PULLDOWN RTC_GPIO9.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD10_REG   1 --CREATE-PULLDOWN RTC_GPIO10.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD10_REG   0 --CREATE-PULLDOWN RTC_GPIO10.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD11_REG   1 --CREATE-PULLDOWN RTC_GPIO11.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD11_REG   0 --CREATE-PULLDOWN RTC_GPIO11.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD12_REG   1 --CREATE-PULLDOWN RTC_GPIO12.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD12_REG   0 --CREATE-PULLDOWN RTC_GPIO12.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD13_REG   1 --CREATE-PULLDOWN RTC_GPIO13.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD13_REG   0 --CREATE-PULLDOWN RTC_GPIO13.PULLDOWN_DISABLE
+RTCIO_TOUCH_PAD14_REG   1 --CREATE-PULLDOWN RTC_GPIO14.PULLDOWN_ENABLE
+RTCIO_TOUCH_PAD14_REG   0 --CREATE-PULLDOWN RTC_GPIO14.PULLDOWN_DISABLE
+RTCIO_XTAL_32P_PAD_REG  1 --CREATE-PULLDOWN RTC_GPIO15.PULLDOWN_ENABLE
+RTCIO_XTAL_32P_PAD_REG  0 --CREATE-PULLDOWN RTC_GPIO15.PULLDOWN_DISABLE
+RTCIO_XTAL_32N_PAD_REG  1 --CREATE-PULLDOWN RTC_GPIO16.PULLDOWN_ENABLE
+RTCIO_XTAL_32N_PAD_REG  0 --CREATE-PULLDOWN RTC_GPIO16.PULLDOWN_DISABLE
+RTCIO_PAD_DAC1_REG      1 --CREATE-PULLDOWN RTC_GPIO17.PULLDOWN_ENABLE
+RTCIO_PAD_DAC1_REG      0 --CREATE-PULLDOWN RTC_GPIO17.PULLDOWN_DISABLE
+RTCIO_PAD_DAC2_REG      1 --CREATE-PULLDOWN RTC_GPIO18.PULLDOWN_ENABLE
+RTCIO_PAD_DAC2_REG      0 --CREATE-PULLDOWN RTC_GPIO18.PULLDOWN_DISABLE
+RTCIO_RTC_PAD19_REG     1 --CREATE-PULLDOWN RTC_GPIO19.PULLDOWN_ENABLE
+RTCIO_RTC_PAD19_REG     0 --CREATE-PULLDOWN RTC_GPIO19.PULLDOWN_DISABLE
+RTCIO_RTC_PAD20_REG     1 --CREATE-PULLDOWN RTC_GPIO20.PULLDOWN_ENABLE
+RTCIO_RTC_PAD20_REG     0 --CREATE-PULLDOWN RTC_GPIO20.PULLDOWN_DISABLE
+RTCIO_RTC_PAD21_REG     1 --CREATE-PULLDOWN RTC_GPIO21.PULLDOWN_ENABLE
+RTCIO_RTC_PAD21_REG     0 --CREATE-PULLDOWN RTC_GPIO21.PULLDOWN_DISABLE
+
+\ helper words to set RTC_GPIO levels
+
+: RTC_GPIO0.SET  ( n -- ) IF RTC_GPIO0.SET_HIGH  EXIT THEN RTC_GPIO0.SET_LOW  ;
+: RTC_GPIO1.SET  ( n -- ) IF RTC_GPIO1.SET_HIGH  EXIT THEN RTC_GPIO1.SET_LOW  ;
+: RTC_GPIO2.SET  ( n -- ) IF RTC_GPIO2.SET_HIGH  EXIT THEN RTC_GPIO2.SET_LOW  ;
+: RTC_GPIO3.SET  ( n -- ) IF RTC_GPIO3.SET_HIGH  EXIT THEN RTC_GPIO3.SET_LOW  ;
+: RTC_GPIO4.SET  ( n -- ) IF RTC_GPIO4.SET_HIGH  EXIT THEN RTC_GPIO4.SET_LOW  ;
+: RTC_GPIO5.SET  ( n -- ) IF RTC_GPIO5.SET_HIGH  EXIT THEN RTC_GPIO5.SET_LOW  ;
+: RTC_GPIO6.SET  ( n -- ) IF RTC_GPIO6.SET_HIGH  EXIT THEN RTC_GPIO6.SET_LOW  ;
+: RTC_GPIO7.SET  ( n -- ) IF RTC_GPIO7.SET_HIGH  EXIT THEN RTC_GPIO7.SET_LOW  ;
+: RTC_GPIO8.SET  ( n -- ) IF RTC_GPIO8.SET_HIGH  EXIT THEN RTC_GPIO8.SET_LOW  ;
+: RTC_GPIO9.SET  ( n -- ) IF RTC_GPIO9.SET_HIGH  EXIT THEN RTC_GPIO9.SET_LOW  ;
+: RTC_GPIO10.SET ( n -- ) IF RTC_GPIO10.SET_HIGH EXIT THEN RTC_GPIO10.SET_LOW ;
+: RTC_GPIO11.SET ( n -- ) IF RTC_GPIO11.SET_HIGH EXIT THEN RTC_GPIO11.SET_LOW ;
+: RTC_GPIO12.SET ( n -- ) IF RTC_GPIO12.SET_HIGH EXIT THEN RTC_GPIO12.SET_LOW ;
+: RTC_GPIO13.SET ( n -- ) IF RTC_GPIO13.SET_HIGH EXIT THEN RTC_GPIO13.SET_LOW ;
+: RTC_GPIO14.SET ( n -- ) IF RTC_GPIO14.SET_HIGH EXIT THEN RTC_GPIO14.SET_LOW ;
+: RTC_GPIO15.SET ( n -- ) IF RTC_GPIO15.SET_HIGH EXIT THEN RTC_GPIO15.SET_LOW ;
+: RTC_GPIO16.SET ( n -- ) IF RTC_GPIO16.SET_HIGH EXIT THEN RTC_GPIO16.SET_LOW ;
+: RTC_GPIO17.SET ( n -- ) IF RTC_GPIO17.SET_HIGH EXIT THEN RTC_GPIO17.SET_LOW ;
+: RTC_GPIO18.SET ( n -- ) IF RTC_GPIO18.SET_HIGH EXIT THEN RTC_GPIO18.SET_LOW ;
+: RTC_GPIO19.SET ( n -- ) IF RTC_GPIO19.SET_HIGH EXIT THEN RTC_GPIO19.SET_LOW ;
+: RTC_GPIO20.SET ( n -- ) IF RTC_GPIO20.SET_HIGH EXIT THEN RTC_GPIO20.SET_LOW ;
+: RTC_GPIO21.SET ( n -- ) IF RTC_GPIO21.SET_HIGH EXIT THEN RTC_GPIO21.SET_LOW ;
+
+\ GPIO equivalents
+
+: GPIO0.ENABLE ( -- ) RTC_GPIO0.ENABLE ;
+: GPIO0.OUTPUT_ENABLE ( -- ) RTC_GPIO0.OUTPUT_ENABLE ;
+: GPIO0.OUTPUT_DISABLE ( -- ) RTC_GPIO0.OUTPUT_DISABLE ;
+: GPIO0.INPUT_ENABLE ( -- ) RTC_GPIO0.INPUT_ENABLE ;
+: GPIO0.SET_HIGH ( -- ) RTC_GPIO0.SET_HIGH ;
+: GPIO0.SET_LOW ( -- ) RTC_GPIO0.SET_LOW ;
+: GPIO0.SET ( n -- ) RTC_GPIO0.SET ;
+: GPIO0.GET ( -- n ) RTC_GPIO0.GET ;
+: GPIO0.PULLUP_ENABLE ( -- ) RTC_GPIO0.PULLUP_ENABLE ;
+: GPIO0.PULLUP_DISABLE ( -- ) RTC_GPIO0.PULLUP_DISABLE ;
+: GPIO0.PULLDOWN_ENABLE ( -- ) RTC_GPIO0.PULLDOWN_ENABLE ;
+: GPIO0.PULLDOWN_DISABLE ( -- ) RTC_GPIO0.PULLDOWN_DISABLE ;
+
+: GPIO1.ENABLE ( -- ) RTC_GPIO1.ENABLE ;
+: GPIO1.OUTPUT_ENABLE ( -- ) RTC_GPIO1.OUTPUT_ENABLE ;
+: GPIO1.OUTPUT_DISABLE ( -- ) RTC_GPIO1.OUTPUT_DISABLE ;
+: GPIO1.INPUT_ENABLE ( -- ) RTC_GPIO1.INPUT_ENABLE ;
+: GPIO1.SET_HIGH ( -- ) RTC_GPIO1.SET_HIGH ;
+: GPIO1.SET_LOW ( -- ) RTC_GPIO1.SET_LOW ;
+: GPIO1.SET ( n -- ) RTC_GPIO1.SET ;
+: GPIO1.GET ( -- n ) RTC_GPIO1.GET ;
+: GPIO1.PULLUP_ENABLE ( -- ) RTC_GPIO1.PULLUP_ENABLE ;
+: GPIO1.PULLUP_DISABLE ( -- ) RTC_GPIO1.PULLUP_DISABLE ;
+: GPIO1.PULLDOWN_ENABLE ( -- ) RTC_GPIO1.PULLDOWN_ENABLE ;
+: GPIO1.PULLDOWN_DISABLE ( -- ) RTC_GPIO1.PULLDOWN_DISABLE ;
+
+: GPIO2.ENABLE ( -- ) RTC_GPIO2.ENABLE ;
+: GPIO2.OUTPUT_ENABLE ( -- ) RTC_GPIO2.OUTPUT_ENABLE ;
+: GPIO2.OUTPUT_DISABLE ( -- ) RTC_GPIO2.OUTPUT_DISABLE ;
+: GPIO2.INPUT_ENABLE ( -- ) RTC_GPIO2.INPUT_ENABLE ;
+: GPIO2.SET_HIGH ( -- ) RTC_GPIO2.SET_HIGH ;
+: GPIO2.SET_LOW ( -- ) RTC_GPIO2.SET_LOW ;
+: GPIO2.SET ( n -- ) RTC_GPIO2.SET ;
+: GPIO2.GET ( -- n ) RTC_GPIO2.GET ;
+: GPIO2.PULLUP_ENABLE ( -- ) RTC_GPIO2.PULLUP_ENABLE ;
+: GPIO2.PULLUP_DISABLE ( -- ) RTC_GPIO2.PULLUP_DISABLE ;
+: GPIO2.PULLDOWN_ENABLE ( -- ) RTC_GPIO2.PULLDOWN_ENABLE ;
+: GPIO2.PULLDOWN_DISABLE ( -- ) RTC_GPIO2.PULLDOWN_DISABLE ;
+
+: GPIO3.ENABLE ( -- ) RTC_GPIO3.ENABLE ;
+: GPIO3.OUTPUT_ENABLE ( -- ) RTC_GPIO3.OUTPUT_ENABLE ;
+: GPIO3.OUTPUT_DISABLE ( -- ) RTC_GPIO3.OUTPUT_DISABLE ;
+: GPIO3.INPUT_ENABLE ( -- ) RTC_GPIO3.INPUT_ENABLE ;
+: GPIO3.SET_HIGH ( -- ) RTC_GPIO3.SET_HIGH ;
+: GPIO3.SET_LOW ( -- ) RTC_GPIO3.SET_LOW ;
+: GPIO3.SET ( n -- ) RTC_GPIO3.SET ;
+: GPIO3.GET ( -- n ) RTC_GPIO3.GET ;
+: GPIO3.PULLUP_ENABLE ( -- ) RTC_GPIO3.PULLUP_ENABLE ;
+: GPIO3.PULLUP_DISABLE ( -- ) RTC_GPIO3.PULLUP_DISABLE ;
+: GPIO3.PULLDOWN_ENABLE ( -- ) RTC_GPIO3.PULLDOWN_ENABLE ;
+: GPIO3.PULLDOWN_DISABLE ( -- ) RTC_GPIO3.PULLDOWN_DISABLE ;
+
+: GPIO4.ENABLE ( -- ) RTC_GPIO4.ENABLE ;
+: GPIO4.OUTPUT_ENABLE ( -- ) RTC_GPIO4.OUTPUT_ENABLE ;
+: GPIO4.OUTPUT_DISABLE ( -- ) RTC_GPIO4.OUTPUT_DISABLE ;
+: GPIO4.INPUT_ENABLE ( -- ) RTC_GPIO4.INPUT_ENABLE ;
+: GPIO4.SET_HIGH ( -- ) RTC_GPIO4.SET_HIGH ;
+: GPIO4.SET_LOW ( -- ) RTC_GPIO4.SET_LOW ;
+: GPIO4.SET ( n -- ) RTC_GPIO4.SET ;
+: GPIO4.GET ( -- n ) RTC_GPIO4.GET ;
+: GPIO4.PULLUP_ENABLE ( -- ) RTC_GPIO4.PULLUP_ENABLE ;
+: GPIO4.PULLUP_DISABLE ( -- ) RTC_GPIO4.PULLUP_DISABLE ;
+: GPIO4.PULLDOWN_ENABLE ( -- ) RTC_GPIO4.PULLDOWN_ENABLE ;
+: GPIO4.PULLDOWN_DISABLE ( -- ) RTC_GPIO4.PULLDOWN_DISABLE ;
+
+: GPIO5.ENABLE ( -- ) RTC_GPIO5.ENABLE ;
+: GPIO5.OUTPUT_ENABLE ( -- ) RTC_GPIO5.OUTPUT_ENABLE ;
+: GPIO5.OUTPUT_DISABLE ( -- ) RTC_GPIO5.OUTPUT_DISABLE ;
+: GPIO5.INPUT_ENABLE ( -- ) RTC_GPIO5.INPUT_ENABLE ;
+: GPIO5.SET_HIGH ( -- ) RTC_GPIO5.SET_HIGH ;
+: GPIO5.SET_LOW ( -- ) RTC_GPIO5.SET_LOW ;
+: GPIO5.SET ( n -- ) RTC_GPIO5.SET ;
+: GPIO5.GET ( -- n ) RTC_GPIO5.GET ;
+: GPIO5.PULLUP_ENABLE ( -- ) RTC_GPIO5.PULLUP_ENABLE ;
+: GPIO5.PULLUP_DISABLE ( -- ) RTC_GPIO5.PULLUP_DISABLE ;
+: GPIO5.PULLDOWN_ENABLE ( -- ) RTC_GPIO5.PULLDOWN_ENABLE ;
+: GPIO5.PULLDOWN_DISABLE ( -- ) RTC_GPIO5.PULLDOWN_DISABLE ;
+
+: GPIO6.ENABLE ( -- ) RTC_GPIO6.ENABLE ;
+: GPIO6.OUTPUT_ENABLE ( -- ) RTC_GPIO6.OUTPUT_ENABLE ;
+: GPIO6.OUTPUT_DISABLE ( -- ) RTC_GPIO6.OUTPUT_DISABLE ;
+: GPIO6.INPUT_ENABLE ( -- ) RTC_GPIO6.INPUT_ENABLE ;
+: GPIO6.SET_HIGH ( -- ) RTC_GPIO6.SET_HIGH ;
+: GPIO6.SET_LOW ( -- ) RTC_GPIO6.SET_LOW ;
+: GPIO6.SET ( n -- ) RTC_GPIO6.SET ;
+: GPIO6.GET ( -- n ) RTC_GPIO6.GET ;
+: GPIO6.PULLUP_ENABLE ( -- ) RTC_GPIO6.PULLUP_ENABLE ;
+: GPIO6.PULLUP_DISABLE ( -- ) RTC_GPIO6.PULLUP_DISABLE ;
+: GPIO6.PULLDOWN_ENABLE ( -- ) RTC_GPIO6.PULLDOWN_ENABLE ;
+: GPIO6.PULLDOWN_DISABLE ( -- ) RTC_GPIO6.PULLDOWN_DISABLE ;
+
+: GPIO7.ENABLE ( -- ) RTC_GPIO7.ENABLE ;
+: GPIO7.OUTPUT_ENABLE ( -- ) RTC_GPIO7.OUTPUT_ENABLE ;
+: GPIO7.OUTPUT_DISABLE ( -- ) RTC_GPIO7.OUTPUT_DISABLE ;
+: GPIO7.INPUT_ENABLE ( -- ) RTC_GPIO7.INPUT_ENABLE ;
+: GPIO7.SET_HIGH ( -- ) RTC_GPIO7.SET_HIGH ;
+: GPIO7.SET_LOW ( -- ) RTC_GPIO7.SET_LOW ;
+: GPIO7.SET ( n -- ) RTC_GPIO7.SET ;
+: GPIO7.GET ( -- n ) RTC_GPIO7.GET ;
+: GPIO7.PULLUP_ENABLE ( -- ) RTC_GPIO7.PULLUP_ENABLE ;
+: GPIO7.PULLUP_DISABLE ( -- ) RTC_GPIO7.PULLUP_DISABLE ;
+: GPIO7.PULLDOWN_ENABLE ( -- ) RTC_GPIO7.PULLDOWN_ENABLE ;
+: GPIO7.PULLDOWN_DISABLE ( -- ) RTC_GPIO7.PULLDOWN_DISABLE ;
+
+: GPIO8.ENABLE ( -- ) RTC_GPIO8.ENABLE ;
+: GPIO8.OUTPUT_ENABLE ( -- ) RTC_GPIO8.OUTPUT_ENABLE ;
+: GPIO8.OUTPUT_DISABLE ( -- ) RTC_GPIO8.OUTPUT_DISABLE ;
+: GPIO8.INPUT_ENABLE ( -- ) RTC_GPIO8.INPUT_ENABLE ;
+: GPIO8.SET_HIGH ( -- ) RTC_GPIO8.SET_HIGH ;
+: GPIO8.SET_LOW ( -- ) RTC_GPIO8.SET_LOW ;
+: GPIO8.SET ( n -- ) RTC_GPIO8.SET ;
+: GPIO8.GET ( -- n ) RTC_GPIO8.GET ;
+: GPIO8.PULLUP_ENABLE ( -- ) RTC_GPIO8.PULLUP_ENABLE ;
+: GPIO8.PULLUP_DISABLE ( -- ) RTC_GPIO8.PULLUP_DISABLE ;
+: GPIO8.PULLDOWN_ENABLE ( -- ) RTC_GPIO8.PULLDOWN_ENABLE ;
+: GPIO8.PULLDOWN_DISABLE ( -- ) RTC_GPIO8.PULLDOWN_DISABLE ;
+
+: GPIO9.ENABLE ( -- ) RTC_GPIO9.ENABLE ;
+: GPIO9.OUTPUT_ENABLE ( -- ) RTC_GPIO9.OUTPUT_ENABLE ;
+: GPIO9.OUTPUT_DISABLE ( -- ) RTC_GPIO9.OUTPUT_DISABLE ;
+: GPIO9.INPUT_ENABLE ( -- ) RTC_GPIO9.INPUT_ENABLE ;
+: GPIO9.SET_HIGH ( -- ) RTC_GPIO9.SET_HIGH ;
+: GPIO9.SET_LOW ( -- ) RTC_GPIO9.SET_LOW ;
+: GPIO9.SET ( n -- ) RTC_GPIO9.SET ;
+: GPIO9.GET ( -- n ) RTC_GPIO9.GET ;
+: GPIO9.PULLUP_ENABLE ( -- ) RTC_GPIO9.PULLUP_ENABLE ;
+: GPIO9.PULLUP_DISABLE ( -- ) RTC_GPIO9.PULLUP_DISABLE ;
+: GPIO9.PULLDOWN_ENABLE ( -- ) RTC_GPIO9.PULLDOWN_ENABLE ;
+: GPIO9.PULLDOWN_DISABLE ( -- ) RTC_GPIO9.PULLDOWN_DISABLE ;
+
+: GPIO10.ENABLE ( -- ) RTC_GPIO10.ENABLE ;
+: GPIO10.OUTPUT_ENABLE ( -- ) RTC_GPIO10.OUTPUT_ENABLE ;
+: GPIO10.OUTPUT_DISABLE ( -- ) RTC_GPIO10.OUTPUT_DISABLE ;
+: GPIO10.INPUT_ENABLE ( -- ) RTC_GPIO10.INPUT_ENABLE ;
+: GPIO10.SET_HIGH ( -- ) RTC_GPIO10.SET_HIGH ;
+: GPIO10.SET_LOW ( -- ) RTC_GPIO10.SET_LOW ;
+: GPIO10.SET ( n -- ) RTC_GPIO10.SET ;
+: GPIO10.GET ( -- n ) RTC_GPIO10.GET ;
+: GPIO10.PULLUP_ENABLE ( -- ) RTC_GPIO10.PULLUP_ENABLE ;
+: GPIO10.PULLUP_DISABLE ( -- ) RTC_GPIO10.PULLUP_DISABLE ;
+: GPIO10.PULLDOWN_ENABLE ( -- ) RTC_GPIO10.PULLDOWN_ENABLE ;
+: GPIO10.PULLDOWN_DISABLE ( -- ) RTC_GPIO10.PULLDOWN_DISABLE ;
+
+: GPIO11.ENABLE ( -- ) RTC_GPIO11.ENABLE ;
+: GPIO11.OUTPUT_ENABLE ( -- ) RTC_GPIO11.OUTPUT_ENABLE ;
+: GPIO11.OUTPUT_DISABLE ( -- ) RTC_GPIO11.OUTPUT_DISABLE ;
+: GPIO11.INPUT_ENABLE ( -- ) RTC_GPIO11.INPUT_ENABLE ;
+: GPIO11.SET_HIGH ( -- ) RTC_GPIO11.SET_HIGH ;
+: GPIO11.SET_LOW ( -- ) RTC_GPIO11.SET_LOW ;
+: GPIO11.SET ( n -- ) RTC_GPIO11.SET ;
+: GPIO11.GET ( -- n ) RTC_GPIO11.GET ;
+: GPIO11.PULLUP_ENABLE ( -- ) RTC_GPIO11.PULLUP_ENABLE ;
+: GPIO11.PULLUP_DISABLE ( -- ) RTC_GPIO11.PULLUP_DISABLE ;
+: GPIO11.PULLDOWN_ENABLE ( -- ) RTC_GPIO11.PULLDOWN_ENABLE ;
+: GPIO11.PULLDOWN_DISABLE ( -- ) RTC_GPIO11.PULLDOWN_DISABLE ;
+
+: GPIO12.ENABLE ( -- ) RTC_GPIO12.ENABLE ;
+: GPIO12.OUTPUT_ENABLE ( -- ) RTC_GPIO12.OUTPUT_ENABLE ;
+: GPIO12.OUTPUT_DISABLE ( -- ) RTC_GPIO12.OUTPUT_DISABLE ;
+: GPIO12.INPUT_ENABLE ( -- ) RTC_GPIO12.INPUT_ENABLE ;
+: GPIO12.SET_HIGH ( -- ) RTC_GPIO12.SET_HIGH ;
+: GPIO12.SET_LOW ( -- ) RTC_GPIO12.SET_LOW ;
+: GPIO12.SET ( n -- ) RTC_GPIO12.SET ;
+: GPIO12.GET ( -- n ) RTC_GPIO12.GET ;
+: GPIO12.PULLUP_ENABLE ( -- ) RTC_GPIO12.PULLUP_ENABLE ;
+: GPIO12.PULLUP_DISABLE ( -- ) RTC_GPIO12.PULLUP_DISABLE ;
+: GPIO12.PULLDOWN_ENABLE ( -- ) RTC_GPIO12.PULLDOWN_ENABLE ;
+: GPIO12.PULLDOWN_DISABLE ( -- ) RTC_GPIO12.PULLDOWN_DISABLE ;
+
+: GPIO13.ENABLE ( -- ) RTC_GPIO13.ENABLE ;
+: GPIO13.OUTPUT_ENABLE ( -- ) RTC_GPIO13.OUTPUT_ENABLE ;
+: GPIO13.OUTPUT_DISABLE ( -- ) RTC_GPIO13.OUTPUT_DISABLE ;
+: GPIO13.INPUT_ENABLE ( -- ) RTC_GPIO13.INPUT_ENABLE ;
+: GPIO13.SET_HIGH ( -- ) RTC_GPIO13.SET_HIGH ;
+: GPIO13.SET_LOW ( -- ) RTC_GPIO13.SET_LOW ;
+: GPIO13.SET ( n -- ) RTC_GPIO13.SET ;
+: GPIO13.GET ( -- n ) RTC_GPIO13.GET ;
+: GPIO13.PULLUP_ENABLE ( -- ) RTC_GPIO13.PULLUP_ENABLE ;
+: GPIO13.PULLUP_DISABLE ( -- ) RTC_GPIO13.PULLUP_DISABLE ;
+: GPIO13.PULLDOWN_ENABLE ( -- ) RTC_GPIO13.PULLDOWN_ENABLE ;
+: GPIO13.PULLDOWN_DISABLE ( -- ) RTC_GPIO13.PULLDOWN_DISABLE ;
+
+: GPIO14.ENABLE ( -- ) RTC_GPIO14.ENABLE ;
+: GPIO14.OUTPUT_ENABLE ( -- ) RTC_GPIO14.OUTPUT_ENABLE ;
+: GPIO14.OUTPUT_DISABLE ( -- ) RTC_GPIO14.OUTPUT_DISABLE ;
+: GPIO14.INPUT_ENABLE ( -- ) RTC_GPIO14.INPUT_ENABLE ;
+: GPIO14.SET_HIGH ( -- ) RTC_GPIO14.SET_HIGH ;
+: GPIO14.SET_LOW ( -- ) RTC_GPIO14.SET_LOW ;
+: GPIO14.SET ( n -- ) RTC_GPIO14.SET ;
+: GPIO14.GET ( -- n ) RTC_GPIO14.GET ;
+: GPIO14.PULLUP_ENABLE ( -- ) RTC_GPIO14.PULLUP_ENABLE ;
+: GPIO14.PULLUP_DISABLE ( -- ) RTC_GPIO14.PULLUP_DISABLE ;
+: GPIO14.PULLDOWN_ENABLE ( -- ) RTC_GPIO14.PULLDOWN_ENABLE ;
+: GPIO14.PULLDOWN_DISABLE ( -- ) RTC_GPIO14.PULLDOWN_DISABLE ;
+
+: GPIO15.ENABLE ( -- ) RTC_GPIO15.ENABLE ;
+: GPIO15.OUTPUT_ENABLE ( -- ) RTC_GPIO15.OUTPUT_ENABLE ;
+: GPIO15.OUTPUT_DISABLE ( -- ) RTC_GPIO15.OUTPUT_DISABLE ;
+: GPIO15.INPUT_ENABLE ( -- ) RTC_GPIO15.INPUT_ENABLE ;
+: GPIO15.SET_HIGH ( -- ) RTC_GPIO15.SET_HIGH ;
+: GPIO15.SET_LOW ( -- ) RTC_GPIO15.SET_LOW ;
+: GPIO15.SET ( n -- ) RTC_GPIO15.SET ;
+: GPIO15.GET ( -- n ) RTC_GPIO15.GET ;
+: GPIO15.PULLUP_ENABLE ( -- ) RTC_GPIO15.PULLUP_ENABLE ;
+: GPIO15.PULLUP_DISABLE ( -- ) RTC_GPIO15.PULLUP_DISABLE ;
+: GPIO15.PULLDOWN_ENABLE ( -- ) RTC_GPIO15.PULLDOWN_ENABLE ;
+: GPIO15.PULLDOWN_DISABLE ( -- ) RTC_GPIO15.PULLDOWN_DISABLE ;
+
+: GPIO16.ENABLE ( -- ) RTC_GPIO16.ENABLE ;
+: GPIO16.OUTPUT_ENABLE ( -- ) RTC_GPIO16.OUTPUT_ENABLE ;
+: GPIO16.OUTPUT_DISABLE ( -- ) RTC_GPIO16.OUTPUT_DISABLE ;
+: GPIO16.INPUT_ENABLE ( -- ) RTC_GPIO16.INPUT_ENABLE ;
+: GPIO16.SET_HIGH ( -- ) RTC_GPIO16.SET_HIGH ;
+: GPIO16.SET_LOW ( -- ) RTC_GPIO16.SET_LOW ;
+: GPIO16.SET ( n -- ) RTC_GPIO16.SET ;
+: GPIO16.GET ( -- n ) RTC_GPIO16.GET ;
+: GPIO16.PULLUP_ENABLE ( -- ) RTC_GPIO16.PULLUP_ENABLE ;
+: GPIO16.PULLUP_DISABLE ( -- ) RTC_GPIO16.PULLUP_DISABLE ;
+: GPIO16.PULLDOWN_ENABLE ( -- ) RTC_GPIO16.PULLDOWN_ENABLE ;
+: GPIO16.PULLDOWN_DISABLE ( -- ) RTC_GPIO16.PULLDOWN_DISABLE ;
+
+: GPIO17.ENABLE ( -- ) RTC_GPIO17.ENABLE ;
+: GPIO17.OUTPUT_ENABLE ( -- ) RTC_GPIO17.OUTPUT_ENABLE ;
+: GPIO17.OUTPUT_DISABLE ( -- ) RTC_GPIO17.OUTPUT_DISABLE ;
+: GPIO17.INPUT_ENABLE ( -- ) RTC_GPIO17.INPUT_ENABLE ;
+: GPIO17.SET_HIGH ( -- ) RTC_GPIO17.SET_HIGH ;
+: GPIO17.SET_LOW ( -- ) RTC_GPIO17.SET_LOW ;
+: GPIO17.SET ( n -- ) RTC_GPIO17.SET ;
+: GPIO17.GET ( -- n ) RTC_GPIO17.GET ;
+: GPIO17.PULLUP_ENABLE ( -- ) RTC_GPIO17.PULLUP_ENABLE ;
+: GPIO17.PULLUP_DISABLE ( -- ) RTC_GPIO17.PULLUP_DISABLE ;
+: GPIO17.PULLDOWN_ENABLE ( -- ) RTC_GPIO17.PULLDOWN_ENABLE ;
+: GPIO17.PULLDOWN_DISABLE ( -- ) RTC_GPIO17.PULLDOWN_DISABLE ;
+
+: GPIO18.ENABLE ( -- ) RTC_GPIO18.ENABLE ;
+: GPIO18.OUTPUT_ENABLE ( -- ) RTC_GPIO18.OUTPUT_ENABLE ;
+: GPIO18.OUTPUT_DISABLE ( -- ) RTC_GPIO18.OUTPUT_DISABLE ;
+: GPIO18.INPUT_ENABLE ( -- ) RTC_GPIO18.INPUT_ENABLE ;
+: GPIO18.SET_HIGH ( -- ) RTC_GPIO18.SET_HIGH ;
+: GPIO18.SET_LOW ( -- ) RTC_GPIO18.SET_LOW ;
+: GPIO18.SET ( n -- ) RTC_GPIO18.SET ;
+: GPIO18.GET ( -- n ) RTC_GPIO18.GET ;
+: GPIO18.PULLUP_ENABLE ( -- ) RTC_GPIO18.PULLUP_ENABLE ;
+: GPIO18.PULLUP_DISABLE ( -- ) RTC_GPIO18.PULLUP_DISABLE ;
+: GPIO18.PULLDOWN_ENABLE ( -- ) RTC_GPIO18.PULLDOWN_ENABLE ;
+: GPIO18.PULLDOWN_DISABLE ( -- ) RTC_GPIO18.PULLDOWN_DISABLE ;
+
+: GPIO19.ENABLE ( -- ) RTC_GPIO19.ENABLE ;
+: GPIO19.OUTPUT_ENABLE ( -- ) RTC_GPIO19.OUTPUT_ENABLE ;
+: GPIO19.OUTPUT_DISABLE ( -- ) RTC_GPIO19.OUTPUT_DISABLE ;
+: GPIO19.INPUT_ENABLE ( -- ) RTC_GPIO19.INPUT_ENABLE ;
+: GPIO19.SET_HIGH ( -- ) RTC_GPIO19.SET_HIGH ;
+: GPIO19.SET_LOW ( -- ) RTC_GPIO19.SET_LOW ;
+: GPIO19.SET ( n -- ) RTC_GPIO19.SET ;
+: GPIO19.GET ( -- n ) RTC_GPIO19.GET ;
+: GPIO19.PULLUP_ENABLE ( -- ) RTC_GPIO19.PULLUP_ENABLE ;
+: GPIO19.PULLUP_DISABLE ( -- ) RTC_GPIO19.PULLUP_DISABLE ;
+: GPIO19.PULLDOWN_ENABLE ( -- ) RTC_GPIO19.PULLDOWN_ENABLE ;
+: GPIO19.PULLDOWN_DISABLE ( -- ) RTC_GPIO19.PULLDOWN_DISABLE ;
+
+: GPIO20.ENABLE ( -- ) RTC_GPIO20.ENABLE ;
+: GPIO20.OUTPUT_ENABLE ( -- ) RTC_GPIO20.OUTPUT_ENABLE ;
+: GPIO20.OUTPUT_DISABLE ( -- ) RTC_GPIO20.OUTPUT_DISABLE ;
+: GPIO20.INPUT_ENABLE ( -- ) RTC_GPIO20.INPUT_ENABLE ;
+: GPIO20.SET_HIGH ( -- ) RTC_GPIO20.SET_HIGH ;
+: GPIO20.SET_LOW ( -- ) RTC_GPIO20.SET_LOW ;
+: GPIO20.SET ( n -- ) RTC_GPIO20.SET ;
+: GPIO20.GET ( -- n ) RTC_GPIO20.GET ;
+: GPIO20.PULLUP_ENABLE ( -- ) RTC_GPIO20.PULLUP_ENABLE ;
+: GPIO20.PULLUP_DISABLE ( -- ) RTC_GPIO20.PULLUP_DISABLE ;
+: GPIO20.PULLDOWN_ENABLE ( -- ) RTC_GPIO20.PULLDOWN_ENABLE ;
+: GPIO20.PULLDOWN_DISABLE ( -- ) RTC_GPIO20.PULLDOWN_DISABLE ;
+
+: GPIO21.ENABLE ( -- ) RTC_GPIO21.ENABLE ;
+: GPIO21.OUTPUT_ENABLE ( -- ) RTC_GPIO21.OUTPUT_ENABLE ;
+: GPIO21.OUTPUT_DISABLE ( -- ) RTC_GPIO21.OUTPUT_DISABLE ;
+: GPIO21.INPUT_ENABLE ( -- ) RTC_GPIO21.INPUT_ENABLE ;
+: GPIO21.SET_HIGH ( -- ) RTC_GPIO21.SET_HIGH ;
+: GPIO21.SET_LOW ( -- ) RTC_GPIO21.SET_LOW ;
+: GPIO21.SET ( n -- ) RTC_GPIO21.SET ;
+: GPIO21.GET ( -- n ) RTC_GPIO21.GET ;
+: GPIO21.PULLUP_ENABLE ( -- ) RTC_GPIO21.PULLUP_ENABLE ;
+: GPIO21.PULLUP_DISABLE ( -- ) RTC_GPIO21.PULLUP_DISABLE ;
+: GPIO21.PULLDOWN_ENABLE ( -- ) RTC_GPIO21.PULLDOWN_ENABLE ;
+: GPIO21.PULLDOWN_DISABLE ( -- ) RTC_GPIO21.PULLDOWN_DISABLE ;
+
+: GPIO_NUMBER_TO_RTC ( gpio_num -- rtc_gpio_num )
+    DUP 21 U> IF
+        ." ERROR: Invalid gpio number " DUP . CR
+    THEN
+;
@@ -25,6 +25,12 @@ type Flag struct {
 	// not dependent on current state.
 	isPure          bool
 	usesReturnStack bool // This primitive word uses the return stack.
+	uncheckedStack  bool // The stack effect of this word depends on the values on the stack.
+	exported        bool // This Forth word is called from outside of the cross compiled code.
+	hot             bool // This Forth word uses subroutine threading in a mixed build.
+	// This primitive word reads the code at an execution token,
+	// which is laid out differently by each threading model.
+	followsToken bool
 
 	calls int // The number of times that this is called, not including tail calls.
 }
@@ -0,0 +1,408 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The suffix added to the labels of subroutine threaded code
+// in a mixed build. The token threaded code keeps the normal
+// labels so that execution tokens don't change.
+const MixedSuffix = "_srt"
+
+// Mixed threading builds most words with token threading and
+// the hot words with subroutine threading. The hot words are
+// the ones marked with HOT or, if none are marked, the words
+// that contain a loop and the words called inside of one.
+// Every word called by a hot word is hot too.
+//
+// Each hot word gets a token threaded stub with its normal
+// labels that enters the subroutine threaded code, so token
+// threaded code calls it like a primitive. Hot words call
+// token threaded code through a stub that runs a short thread
+// with the token threaded interpreter.
+type mixedThreading struct {
+	hot   map[*WordForth]bool         // the words using subroutine threading
+	srt   map[*WordPrimitive]bool     // the primitives that need a subroutine threaded version
+	token map[*WordPrimitive]bool     // the primitives that need a token threaded version
+	stubs map[*WordForth]bool         // the hot words called by token threaded code
+	cold  []*DictionaryEntry          // the token threaded words called by hot words
+	names map[*DictionaryEntry]string // the label of the stub for each of the cold words
+}
+
+// Choose which words are hot. The roots are started by
+// the token threaded interpreter so they stay cold, as do
+// words with an execution token because the code at an
+// execution token is read by words such as >BODY.
+func (m *mixedThreading) choose(u *Ulp, roots []*DictionaryEntry) {
+	m.hot = make(map[*WordForth]bool)
+	cold := make(map[*WordForth]bool)
+	marked := false
+	for _, w := range u.forthWords {
+		marked = marked || w.Entry.Flag.hot
+		for _, c := range w.Cells {
+			literal, ok := c.(CellLiteral)
+			if !ok {
+				continue
+			}
+			address, ok := literal.cell.(CellAddress)
+			if !ok {
+				continue
+			}
+			callee, ok := address.Entry.Word.(*WordForth)
+			if ok {
+				cold[callee] = true
+			}
+		}
+	}
+	for _, w := range u.dataWords {
+		for _, c := range w.Cells {
+			address, ok := c.(CellAddress)
+			if !ok {
+				continue
+			}
+			callee, ok := address.Entry.Word.(*WordForth)
+			if ok {
+				cold[callee] = true
+			}
+		}
+	}
+	for _, root := range roots {
+		w, ok := root.Word.(*WordForth)
+		if ok {
+			cold[w] = true
+		}
+	}
+
+	work := make([]*WordForth, 0)
+	for _, w := range u.forthWords {
+		if marked {
+			if w.Entry.Flag.hot {
+				work = append(work, w)
+			}
+			continue
+		}
+		loop := loopCells(w)
+		if len(loop) != 0 && !cold[w] {
+			m.hot[w] = true // only the calls inside of the loop are hot
+		}
+		work = append(work, calledWords(loop)...)
+	}
+	// everything called by a hot word runs as often as it does
+	visited := make(map[*WordForth]bool)
+	for len(work) != 0 {
+		w := work[len(work)-1]
+		work = work[:len(work)-1]
+		if visited[w] || cold[w] || w.Entry.Flag.Data {
+			continue
+		}
+		visited[w] = true
+		m.hot[w] = true
+		work = append(work, calledWords(w.Cells)...)
+	}
+}
+
+// The cells between a branch and an earlier destination
+// that it jumps back to.
+func loopCells(w *WordForth) []Cell {
+	dests := make(map[*CellDestination]int)
+	loop := make([]Cell, 0)
+	for i, c := range w.Cells {
+		var dest *CellDestination
+		switch cell := c.(type) {
+		case *CellDestination:
+			dests[cell] = i
+			continue
+		case *CellBranch:
+			dest = cell.dest
+		case *CellBranch0:
+			dest = cell.dest
+		default:
+			continue
+		}
+		start, ok := dests[dest]
+		if ok {
+			loop = append(loop, w.Cells[start:i]...)
+		}
+	}
+	return loop
+}
+
+// The forth words that the cells call.
+func calledWords(cells []Cell) []*WordForth {
+	words := make([]*WordForth, 0)
+	for _, c := range cells {
+		switch cell := c.(type) {
+		case CellAddress:
+			w, ok := cell.Entry.Word.(*WordForth)
+			if ok {
+				words = append(words, w)
+			}
+		case *CellTailCall:
+			words = append(words, cell.dest)
+		}
+	}
+	return words
+}
+
+// Find the versions of each primitive and the stubs that are
+// needed between the two threading models. Called after the
+// optimized lists are built and the calls are counted.
+func (m *mixedThreading) prepare(u *Ulp) error {
+	m.srt = make(map[*WordPrimitive]bool)
+	m.token = make(map[*WordPrimitive]bool)
+	m.stubs = make(map[*WordForth]bool)
+	m.cold = nil
+	m.names = make(map[*DictionaryEntry]string)
+	// only the literals of token threaded code are tokens
+	u.literals = make(map[string]string)
+	for w := range m.hot {
+		w.Entry.Flag.calls = 0 // token threaded code calls the stub instead
+	}
+	for _, w := range u.forthWords {
+		hot := m.hot[w]
+		for _, c := range w.Cells {
+			switch cell := c.(type) {
+			case CellLiteral:
+				if !hot {
+					err := cell.AddToList(u)
+					if err != nil {
+						return err
+					}
+				}
+				address, ok := cell.cell.(CellAddress)
+				if ok {
+					m.tokenReference(address)
+				}
+			case CellAddress:
+				if cell.Offset != 0 || cell.UpperByte {
+					m.tokenReference(cell)
+					continue
+				}
+				switch callee := cell.Entry.Word.(type) {
+				case *WordPrimitive:
+					if hot && srtCallable(callee) {
+						m.srt[callee] = true
+						continue
+					}
+					m.token[callee] = true
+				case *WordForth:
+					if hot && m.hot[callee] {
+						callee.Entry.Flag.calls += 1
+						continue
+					}
+					if m.hot[callee] {
+						m.stubs[callee] = true
+						continue
+					}
+				}
+				if hot {
+					m.coldStub(u, cell.Entry)
+				}
+			}
+		}
+	}
+	for _, w := range u.dataWords {
+		for _, c := range w.Cells {
+			address, ok := c.(CellAddress)
+			if ok {
+				m.tokenReference(address)
+			}
+		}
+	}
+	return nil
+}
+
+// The address is used by token threaded code.
+func (m *mixedThreading) tokenReference(address CellAddress) {
+	w, ok := address.Entry.Word.(*WordPrimitive)
+	if ok {
+		m.token[w] = true
+	}
+}
+
+// Add a stub for hot words to call the token threaded entry.
+func (m *mixedThreading) coldStub(u *Ulp, entry *DictionaryEntry) {
+	_, ok := m.names[entry]
+	if ok {
+		return
+	}
+	m.names[entry] = u.name("cold", entry.Name, true)
+	m.cold = append(m.cold, entry)
+}
+
+// Check if subroutine threaded code can call the primitive directly.
+func srtCallable(w *WordPrimitive) bool {
+	return len(w.UlpSrt.Asm) != 0 && !w.Entry.Flag.followsToken
+}
+
+// The label that subroutine threaded code jumps to when calling the entry.
+func (m *mixedThreading) call(entry *DictionaryEntry) string {
+	name, ok := m.names[entry]
+	if ok {
+		return name
+	}
+	return entry.ulpName + MixedSuffix
+}
+
+// Check if a cell of the word calls code using the other threading model.
+func (m *mixedThreading) crosses(w *WordForth, c Cell) bool {
+	address, ok := c.(CellAddress)
+	if !ok {
+		return false
+	}
+	switch callee := address.Entry.Word.(type) {
+	case *WordForth:
+		return m.hot[w] != m.hot[callee]
+	case *WordPrimitive:
+		return m.hot[w] && !srtCallable(callee)
+	}
+	return false
+}
+
+// The threading model used to build a forth word.
+func (u *Ulp) wordTarget(w *WordForth) UlpCompileTarget {
+	if u.mixed != nil && u.mixed.hot[w] {
+		return UlpCompileTargetSubroutine
+	}
+	return u.compileTarget
+}
+
+// Build the subroutine threaded primitives, the stubs and the hot
+// words, which are placed in .text. Also returns the threads run
+// by the cold stubs, which are placed with the token threaded words.
+func (m *mixedThreading) build(u *Ulp) (string, string, error) {
+	text := make([]string, 0)
+	threads := make([]string, 0)
+	target := u.compileTarget
+	defer func() { u.compileTarget = target }()
+
+	u.compileTarget = UlpCompileTargetSubroutine
+	for _, w := range u.assemblyWords {
+		if !m.srt[w] {
+			continue
+		}
+		asm, err := w.BuildAssembly(u)
+		if err != nil {
+			return "", "", err
+		}
+		text = append(text, renameLabels(asm, MixedSuffix))
+	}
+	for _, entry := range m.cold {
+		name := m.names[entry]
+		text = append(text, strings.Join([]string{
+			name + ":",
+			"move r1, " + name + "_thread",
+			"jump __srt_to_token",
+		}, "\r\n"))
+		threads = append(threads, strings.Join([]string{
+			name + "_thread:",
+			".int " + entry.ulpName,
+			".int __srt_resume",
+		}, "\r\n"))
+	}
+	for _, w := range u.forthWords {
+		if !m.stubs[w] {
+			continue
+		}
+		text = append(text, strings.Join([]string{
+			w.Entry.ulpName + ":",
+			w.Entry.BodyLabel() + ":",
+			"move r0, " + w.Entry.BodyLabel() + MixedSuffix,
+			"jump __token_to_srt",
+		}, "\r\n"))
+	}
+	for _, w := range u.forthWords {
+		if !m.hot[w] {
+			continue
+		}
+		asm, err := w.BuildAssembly(u)
+		if err != nil {
+			return "", "", err
+		}
+		text = append(text, asm)
+	}
+	return strings.Join(text, "\r\n\r\n"), strings.Join(threads, "\r\n"), nil
+}
+
+// Add the suffix to every label defined in the assembly,
+// and to every use of those labels.
+func renameLabels(asm string, suffix string) string {
+	labels := make([]string, 0)
+	for _, line := range strings.Split(asm, "\r\n") {
+		labels = append(labels, parsePeepholeLine(line).labels...)
+	}
+	for _, label := range labels {
+		use := regexp.MustCompile(`(^|[^\w.])` + regexp.QuoteMeta(label) + `($|[^\w.])`)
+		for {
+			renamed := use.ReplaceAllString(asm, "${1}"+label+suffix+"${2}")
+			if renamed == asm {
+				break
+			}
+			asm = renamed
+		}
+	}
+	return asm
+}
+
+// The code that moves between the two threading models,
+// placed after the token threaded interpreter.
+func (u *Ulp) buildInterpreterMixed() []string {
+	i := []string{".text"}
+	i = append(i, buildSrtHelpers()...)
+	i = append(i,
+		// a hot word called by token threaded code,
+		// r0 holds the body of the word and r1 the
+		// instruction pointer
+		"__token_to_srt:",
+		"ld r2, r2, __rsp",        // load the return stack pointer, r2 is 0
+		"st r1, r2, 1",            // push the instruction pointer
+		"move r1, __token_resume", // then the return into token threaded code
+		"st r1, r2, 2",
+		"add r2, r2, 2",
+		"move r1, __rsp",
+		"st r2, r1, 0", // store the rsp
+		"move r2, r0",  // the body is the current cell
+		"jump r2",
+		"__token_resume:",
+		"halt", // never runs, EXIT continues after the return address
+		"move r2, 0",
+		"ld r0, r2, __rsp", // load the return stack pointer
+		"ld r1, r0, 0",     // pop the instruction pointer
+		"sub r0, r0, 1",
+		"st r0, r2, __rsp",      // store the rsp
+		"jump __next_skip_load", // and continue the token threaded code
+	)
+	if len(u.mixed.cold) == 0 {
+		return i // hot words don't call token threaded code
+	}
+	return append(i,
+		// token threaded code called by a hot word,
+		// r1 holds the thread and r2 the address of the call
+		"__srt_to_token:",
+		"move r0, __rsp",
+		"ld r0, r0, 0",          // load the return stack pointer
+		"add r0, r0, 1",         // increment the rsp
+		"st r2, r0, 0",          // push the address of the call
+		"move r2, 0",            // r2 is 0 in the token threaded interpreter
+		"st r0, r2, __rsp",      // store the rsp
+		"jump __next_skip_load", // run the thread
+
+		// the last token of each thread, return to the hot word
+		"__srt_resume:",
+		"ld r0, r2, __rsp", // load the return stack pointer
+		"ld r1, r0, 0",     // pop the address of the call
+		"sub r0, r0, 1",
+		"st r0, r2, __rsp", // store the rsp
+		"move r2, r1",
+		"add r2, r2, 1", // continue after the call
+		"jump r2",
+	)
+}
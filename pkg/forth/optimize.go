@@ -10,10 +10,27 @@ package forth
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 )
 
+// The largest primitive, in instructions, that is copied
+// into its callers when subroutine threading.
+const inlineAssemblyMax = 5
+
+var registerR2 = regexp.MustCompile(`\br2\b`)
+
+// The longest cell sequence checked during sequence compression.
+const compressSequenceMax = 16
+
+// The number of bytes used by a token.
+const tokenBytes = 4
+
 type Optimizer struct {
-	u *Ulp
+	u     *Ulp
+	roots []*DictionaryEntry // the words that the output lists are built from
+	exit  *DictionaryEntry   // the EXIT word, used to end synthesized words
 }
 
 func (o *Optimizer) Optimize() error {
@@ -26,11 +43,36 @@ func (o *Optimizer) Optimize() error {
 	if err != nil {
 		return errors.Join(fmt.Errorf("could not remove deferred word"), err)
 	}
+	// copy small forth words into their callers
+	err = o.inlineForth()
+	if err != nil {
+		return errors.Join(fmt.Errorf("could not inline forth words, please file a bug report"), err)
+	}
+	// evaluate pure words on literals during compilation
+	err = o.foldConstants()
+	if err != nil {
+		return errors.Join(fmt.Errorf("could not fold constants, please file a bug report"), err)
+	}
+	// remove code that can never run
+	err = o.analyzeFlow()
+	if err != nil {
+		return errors.Join(fmt.Errorf("could not analyze flow control, please file a bug report"), err)
+	}
+	// factor repeated sequences into new words
+	err = o.compressSequences()
+	if err != nil {
+		return errors.Join(fmt.Errorf("could not compress sequences, please file a bug report"), err)
+	}
 	// change calls at end of words to tail calls
 	err = o.putTailCalls()
 	if err != nil {
 		return errors.Join(fmt.Errorf("could not create tail calls, please file a bug report"), err)
 	}
+	// copy short primitives into their callers
+	err = o.inlineAssembly()
+	if err != nil {
+		return errors.Join(fmt.Errorf("could not inline assembly words, please file a bug report"), err)
+	}
 	return nil
 }
 
@@ -74,13 +116,170 @@ func (o *Optimizer) putTailCalls() error {
 			if !secondAddress.Entry.Flag.isExit {
 				continue
 			}
+			// a tail call can't change the threading model
+			if o.u.wordTarget(w) != o.u.wordTarget(word) {
+				continue
+			}
 			// replace both cells with the tail call!
-			tailCall := CellTailCall{word}     // create the tail call
-			w.Cells[i] = &tailCall             // replace the word
-			before := w.Cells[:i+1]            // get the cells before, including the tail call
-			after := w.Cells[i+2:]             // get the cells after, excluding the exit
-			w.Cells = append(before, after...) // recreate the list
-			length -= 1                        // shift the length
+			tailCall := CellTailCall{dest: word} // create the tail call
+			w.Cells[i] = &tailCall               // replace the word
+			before := w.Cells[:i+1]              // get the cells before, including the tail call
+			after := w.Cells[i+2:]               // get the cells after, excluding the exit
+			w.Cells = append(before, after...)   // recreate the list
+			length -= 1                          // shift the length
+		}
+	}
+	return nil
+}
+
+// Copy the bodies of forth words into the words that call them.
+// A word is inlined if it is only called once or if its body is
+// smaller than the call and the EXIT. Words that are no longer
+// called are removed when the lists are rebuilt.
+func (o *Optimizer) inlineForth() error {
+	for {
+		o.u.countCalls()
+		changed := false
+		for _, w := range o.u.forthWords {
+			cells := make([]Cell, 0, len(w.Cells))
+			for _, c := range w.Cells {
+				address, ok := c.(CellAddress)
+				if !ok || address.Offset != 0 || address.UpperByte {
+					cells = append(cells, c)
+					continue
+				}
+				word, ok := address.Entry.Word.(*WordForth)
+				if !ok || word == w || !o.canInline(word) {
+					cells = append(cells, c)
+					continue
+				}
+				body := word.Cells[:len(word.Cells)-1] // don't copy the EXIT
+				cells = append(cells, body...)
+				changed = true
+			}
+			w.Cells = cells
+		}
+		if !changed {
+			return nil
+		}
+		// remove the words that are no longer called
+		err := o.rebuildLists()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Check if a forth word can be copied into its callers.
+func (o *Optimizer) canInline(w *WordForth) bool {
+	f := w.Entry.Flag
+	if f.recursive || f.Data || f.exported || len(w.Cells) == 0 {
+		return false
+	}
+	// the word must end with EXIT
+	length := len(w.Cells) - 1
+	exit, ok := w.Cells[length].(CellAddress)
+	if !ok || !exit.Entry.Flag.isExit {
+		return false
+	}
+	// the body must be straight line code that doesn't
+	// depend on the return address
+	for _, c := range w.Cells[:length] {
+		switch cell := c.(type) {
+		case CellAddress:
+			if cell.Entry.Flag.isExit || cell.Entry.Flag.usesReturnStack {
+				return false
+			}
+		case CellLiteral:
+		default:
+			return false
+		}
+	}
+	if length < 2 { // smaller than a call and an EXIT
+		return true
+	}
+	return f.calls == 1 && !f.inToken
+}
+
+// Replace runs of number literals followed by pure primitives
+// with the literals that they would produce. For example,
+// `1 2 + 3 *` is compiled as `9`.
+func (o *Optimizer) foldConstants() error {
+	for _, w := range o.u.forthWords {
+		for start := 0; start < len(w.Cells); start++ {
+			end, results := o.foldFrom(w.Cells, start)
+			if end < 0 {
+				continue
+			}
+			replace := make([]Cell, 0, len(results)+len(w.Cells)-end)
+			for _, r := range results {
+				replace = append(replace, CellLiteral{r})
+			}
+			replace = append(replace, w.Cells[end:]...)
+			w.Cells = append(w.Cells[:start], replace...)
+			start += len(results) - 1 // skip the folded literals
+		}
+	}
+	return nil
+}
+
+// Evaluate the cells beginning at start. Returns the index after the
+// longest sequence that can be replaced by fewer literal cells along with
+// those literals, or -1 if nothing can be folded.
+func (o *Optimizer) foldFrom(cells []Cell, start int) (int, []Cell) {
+	vm := VirtualMachine{}
+	vm.Stack.Setup()
+	vm.ReturnStack.Setup()
+	bestEnd := -1
+	var best []Cell
+	for i := start; i < len(cells); i++ {
+		if !o.foldable(cells[i]) {
+			break
+		}
+		if o.foldExecute(&vm, cells[i]) != nil {
+			break
+		}
+		depth := vm.Stack.Depth()
+		if depth < i+1-start {
+			bestEnd = i + 1
+			best = slices.Clone(vm.Stack.stack)
+		}
+	}
+	return bestEnd, best
+}
+
+// Check if a cell can be evaluated at compile time.
+func (o *Optimizer) foldable(c Cell) bool {
+	switch cell := c.(type) {
+	case CellLiteral:
+		_, ok := cell.cell.(CellNumber)
+		return ok
+	case CellAddress:
+		_, ok := cell.Entry.Word.(*WordPrimitive)
+		f := cell.Entry.Flag
+		return ok && f.isPure && !f.usesReturnStack
+	default:
+		return false
+	}
+}
+
+// Execute a foldable cell on the host, turning any
+// panic (such as dividing by 0) into an error.
+func (o *Optimizer) foldExecute(vm *VirtualMachine, c Cell) (err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			err = fmt.Errorf("could not fold %s: %v", c, r)
+		}
+	}()
+	err = c.Execute(vm)
+	if err != nil {
+		return err
+	}
+	for _, result := range vm.Stack.stack {
+		_, ok := result.(CellNumber)
+		if !ok {
+			return fmt.Errorf("could not fold %s into a number", c)
 		}
 	}
 	return nil
@@ -116,6 +315,376 @@ func (o *Optimizer) removeDeferred() error {
 	return nil
 }
 
+// Copy the assembly of short primitives into the forth
+// words that call them. Only used with subroutine threading,
+// including the hot words of mixed threading, and on the
+// ULP-RISC-V. The primitive is removed if it is no longer called.
+func (o *Optimizer) inlineAssembly() error {
+	if o.u.compileTarget != UlpCompileTargetSubroutine && o.u.compileTarget != UlpCompileTargetRiscv && o.u.mixed == nil {
+		return nil
+	}
+	for _, w := range o.u.forthWords {
+		if o.u.wordTarget(w) == UlpCompileTargetToken {
+			continue
+		}
+		for i, c := range w.Cells {
+			addr, ok := c.(CellAddress)
+			if !ok {
+				continue
+			}
+			word, ok := addr.Entry.Word.(*WordPrimitive)
+			if !ok || !o.canInlineAssembly(word) {
+				continue
+			}
+			w.Cells[i] = &CellInline{word}
+		}
+	}
+	return nil
+}
+
+// Check if a primitive can be copied into a forth word.
+// It must be short, use the standard NEXT, and can't
+// have labels, jumps, or use the instruction pointer.
+func (o *Optimizer) canInlineAssembly(w *WordPrimitive) bool {
+	if o.u.compileTarget == UlpCompileTargetRiscv {
+		return o.canInlineRiscv(w)
+	}
+	asm := w.UlpSrt.Asm
+	if w.UlpSrt.NonStandardNext || len(asm) == 0 || len(asm) > inlineAssemblyMax {
+		return false
+	}
+	for _, line := range asm {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if strings.Contains(line, ":") || strings.Contains(line, "\n") {
+			return false // label or multiple lines
+		}
+		if strings.HasPrefix(line, "jump") || strings.HasPrefix(line, ".") {
+			return false // flow control or directive
+		}
+		if registerR2.MatchString(line) {
+			return false // uses the instruction pointer
+		}
+	}
+	return true
+}
+
+// Check if a ULP-RISC-V primitive can be copied into a
+// forth word. It must be short, return normally, and can't
+// have labels or change the flow of the program.
+func (o *Optimizer) canInlineRiscv(w *WordPrimitive) bool {
+	asm := w.UlpRiscv.Asm
+	if w.UlpRiscv.NonStandardNext || len(asm) == 0 || len(asm) > inlineAssemblyMax {
+		return false
+	}
+	for _, line := range asm {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if strings.Contains(line, ":") || strings.Contains(line, "\n") {
+			return false // label or multiple lines
+		}
+		if strings.HasPrefix(line, ".") {
+			return false // directive
+		}
+		op, _, _ := strings.Cut(line, " ")
+		if strings.HasPrefix(op, "j") || strings.HasPrefix(op, "b") || op == "ret" || op == "call" || op == "tail" {
+			return false // flow control
+		}
+	}
+	return true
+}
+
+// Use the control flow of each word to fold conditional
+// branches on constants and remove cells that can never run.
+// Words that are no longer called are removed when the
+// lists are rebuilt.
+func (o *Optimizer) analyzeFlow() error {
+	changed := false
+	for _, w := range o.u.forthWords {
+		if w.Entry.Flag.Data {
+			continue
+		}
+		for {
+			cells, ok := simplifyFlow(w.Cells)
+			if !ok {
+				break
+			}
+			w.Cells = cells
+			changed = true
+		}
+	}
+	if changed {
+		return o.rebuildLists()
+	}
+	return nil
+}
+
+// Run one round of flow control simplification on the cells.
+// Returns the new cells and true if anything changed.
+func simplifyFlow(cells []Cell) ([]Cell, bool) {
+	// fold conditional branches on a constant number
+	folded := make([]Cell, 0, len(cells))
+	changed := false
+	for i := 0; i < len(cells); i++ {
+		if i+1 < len(cells) {
+			lit, isLit := cells[i].(CellLiteral)
+			num, isNum := lit.cell.(CellNumber)
+			branch0, isBranch0 := cells[i+1].(*CellBranch0)
+			if isLit && isNum && isBranch0 {
+				if num.Number == 0 { // always branches
+					folded = append(folded, &CellBranch{dest: branch0.dest})
+				}
+				changed = true
+				i += 1
+				continue
+			}
+		}
+		folded = append(folded, cells[i])
+	}
+	cells = folded
+
+	// build the control flow graph
+	dests := make(map[*CellDestination]int) // the index of each destination
+	for i, c := range cells {
+		if dest, ok := c.(*CellDestination); ok {
+			dests[dest] = i
+		}
+	}
+	next := make([][]int, len(cells)) // the cells that can run after each cell
+	for i, c := range cells {
+		switch cell := c.(type) {
+		case *CellBranch:
+			d, ok := dests[cell.dest]
+			if !ok {
+				return cells, changed // branch leaves the word, don't touch it
+			}
+			next[i] = []int{d}
+		case *CellBranch0:
+			d, ok := dests[cell.dest]
+			if !ok {
+				return cells, changed
+			}
+			next[i] = []int{i + 1, d}
+		case *CellTailCall:
+		case CellAddress:
+			if !cell.Entry.Flag.isExit {
+				next[i] = []int{i + 1}
+			}
+		default:
+			next[i] = []int{i + 1}
+		}
+	}
+
+	// find every cell that can run
+	reachable := make([]bool, len(cells))
+	work := []int{0}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		if i >= len(cells) || reachable[i] {
+			continue
+		}
+		reachable[i] = true
+		work = append(work, next[i]...)
+	}
+
+	// keep reachable cells, branches that don't skip any
+	// cells, and destinations that are branched to
+	used := make(map[*CellDestination]bool)
+	for i, c := range cells {
+		if !reachable[i] {
+			continue
+		}
+		switch cell := c.(type) {
+		case *CellBranch:
+			if !branchesToNext(cells, reachable, i, dests[cell.dest]) {
+				used[cell.dest] = true
+			}
+		case *CellBranch0:
+			used[cell.dest] = true
+		}
+	}
+	out := make([]Cell, 0, len(cells))
+	for i, c := range cells {
+		keep := reachable[i]
+		switch cell := c.(type) {
+		case *CellBranch:
+			keep = keep && used[cell.dest]
+		case *CellDestination:
+			keep = keep && used[cell]
+		}
+		if keep {
+			out = append(out, c)
+		}
+	}
+	return out, changed || len(out) != len(cells)
+}
+
+// Check if every reachable cell between a branch
+// and its destination is only another destination.
+func branchesToNext(cells []Cell, reachable []bool, branch int, dest int) bool {
+	if dest <= branch {
+		return false
+	}
+	for i := branch + 1; i < dest; i++ {
+		if !reachable[i] {
+			continue
+		}
+		if _, ok := cells[i].(*CellDestination); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Factor cell sequences that are repeated across forth words
+// into new hidden words. Only used with token and direct
+// threading, where each cell is a token and a call costs one token.
+func (o *Optimizer) compressSequences() error {
+	if !o.u.threaded() || o.exit == nil {
+		return nil
+	}
+	changed := false
+	for {
+		cells, count, saved := o.bestSequence()
+		if saved <= 0 {
+			break
+		}
+		entry := &DictionaryEntry{
+			Name: fmt.Sprintf("SEQUENCE.%d", len(o.u.Sequences)),
+			Flag: Flag{Hidden: true},
+		}
+		body := append(slices.Clone(cells), CellAddress{Entry: o.exit})
+		entry.Word = &WordForth{Cells: body, Entry: entry}
+		call := CellAddress{Entry: entry}
+		for _, w := range o.u.forthWords {
+			if o.u.wordTarget(w) != o.u.compileTarget {
+				continue // the hot words of mixed threading
+			}
+			w.Cells = replaceSequence(w.Cells, cells, call)
+		}
+		o.u.forthWords = append(o.u.forthWords, entry.Word.(*WordForth))
+		o.u.Sequences = append(o.u.Sequences, CompressedSequence{
+			Name:  entry.Name,
+			Cells: fmt.Sprint(cells),
+			Count: count,
+			Saved: saved * tokenBytes,
+		})
+		changed = true
+	}
+	if changed {
+		return o.rebuildLists()
+	}
+	return nil
+}
+
+// Find the sequence of cells that saves the most tokens when
+// factored into its own word. Returns the sequence, the number
+// of times it is used, and the number of tokens saved.
+func (o *Optimizer) bestSequence() ([]Cell, int, int) {
+	ids := make(map[Cell]int) // a unique number for each cell
+	type found struct {
+		cells []Cell
+		count int
+		words map[*WordForth]int // the end of the last use in each word
+	}
+	sequences := make(map[string]*found)
+	order := make([]string, 0) // keep the order deterministic
+	for _, w := range o.u.forthWords {
+		if w.Entry.Flag.Data || o.u.wordTarget(w) != o.u.compileTarget {
+			continue
+		}
+		for start := range w.Cells {
+			key := ""
+			for end := start; end < len(w.Cells) && end-start < compressSequenceMax; end++ {
+				c := w.Cells[end]
+				if !o.compressible(c) {
+					break
+				}
+				id, ok := ids[c]
+				if !ok {
+					id = len(ids)
+					ids[c] = id
+				}
+				key += fmt.Sprintf("%d ", id)
+				if end == start {
+					continue // a single cell can't be smaller
+				}
+				f, ok := sequences[key]
+				if !ok {
+					f = &found{cells: w.Cells[start : end+1], words: make(map[*WordForth]int)}
+					sequences[key] = f
+					order = append(order, key)
+				}
+				last, ok := f.words[w]
+				if ok && start < last {
+					continue // overlaps with the previous use
+				}
+				f.words[w] = end + 1
+				f.count += 1
+			}
+		}
+	}
+	var best *found
+	bestSaved := 0
+	for _, key := range order {
+		f := sequences[key]
+		// each use shrinks to one call, the new word adds the sequence and EXIT
+		saved := f.count*len(f.cells) - f.count - len(f.cells) - 1
+		if o.u.compileTarget == UlpCompileTargetDirect {
+			saved -= 1 // and the jump to __docol
+		}
+		if saved > bestSaved {
+			best = f
+			bestSaved = saved
+		}
+	}
+	if best == nil {
+		return nil, 0, 0
+	}
+	return best.cells, best.count, bestSaved
+}
+
+// Check if a cell can be moved into another word.
+// Words that use the return stack would see the
+// return address of the new word.
+func (o *Optimizer) compressible(c Cell) bool {
+	switch cell := c.(type) {
+	case CellLiteral:
+		return true
+	case CellAddress:
+		if cell.Offset != 0 || cell.UpperByte {
+			return false
+		}
+		return !cell.Entry.Flag.isExit && !cell.Entry.Flag.usesReturnStack
+	}
+	return false
+}
+
+// Replace every use of the sequence with the call.
+func replaceSequence(cells []Cell, sequence []Cell, call Cell) []Cell {
+	out := make([]Cell, 0, len(cells))
+	for i := 0; i < len(cells); {
+		end := i + len(sequence)
+		if end <= len(cells) && slices.Equal(cells[i:end], sequence) {
+			out = append(out, call)
+			i = end
+			continue
+		}
+		out = append(out, cells[i])
+		i += 1
+	}
+	return out
+}
+
+// Rebuild the output lists so that words that are
+// no longer used are removed.
+func (o *Optimizer) rebuildLists() error {
+	err := o.u.clearLists()
+	if err != nil {
+		return err
+	}
+	return o.u.buildLists(o.roots...)
+}
+
 func (o *Optimizer) clearVisited() {
 	for _, w := range o.u.forthWords {
 		w.Entry.ClearVisited()
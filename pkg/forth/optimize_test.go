@@ -0,0 +1,249 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// TestOptimizations checks the words left in a definition
+// after it has been cross compiled. The output of the
+// optimized code is tested on the ulp in basic_test.go.
+func TestOptimizations(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string // the code that defines MAIN
+		expect     string // the expected cells in MAIN after optimization
+		subroutine bool   // cross compile using subroutine threading
+	}{
+		{
+			name:   "fold add",
+			code:   ": MAIN 1 2 + ;",
+			expect: "[Literal(3) Address{EXIT}]",
+		},
+		{
+			name:   "fold chain",
+			code:   ": MAIN 1 2 + 3 * 4 SWAP - ;",
+			expect: "[Literal(65531) Address{EXIT}]",
+		},
+		{
+			name:   "fold partial",
+			code:   ": MAIN DUP 1 2 + + ;",
+			expect: "[Address{DUP} Literal(3) Address{+} Address{EXIT}]",
+		},
+		{
+			name:   "fold multiple results",
+			code:   ": MAIN 7 2 U/MOD ;",
+			expect: "[Literal(1) Literal(3) Address{EXIT}]",
+		},
+		{
+			name:   "no fold divide by 0",
+			code:   ": MAIN 7 0 U/MOD ;",
+			expect: "[Literal(7) Literal(0) Address{U/MOD} Address{EXIT}]",
+		},
+		{
+			name:   "no fold same size",
+			code:   ": MAIN 5 DUP ;",
+			expect: "[Literal(5) Address{DUP} Address{EXIT}]",
+		},
+		{
+			name:   "no fold impure",
+			code:   "VARIABLE V : MAIN V @ 1 + ;",
+			expect: "[Literal(Address{__data_unnamed_2}) Address{@} Literal(1) Address{+} Address{EXIT}]",
+		},
+		{
+			name:   "no fold impure after inline",
+			code:   ": V 0x10 ; : MAIN V @ 1 + ;",
+			expect: "[Literal(16) Address{@} Literal(1) Address{+} Address{EXIT}]",
+		},
+		{
+			name:   "inline small",
+			code:   ": ONE 1 ; : MAIN ONE ONE + ;",
+			expect: "[Literal(2) Address{EXIT}]",
+		},
+		{
+			name:   "inline called once",
+			code:   ": SQUARE DUP * ; : MAIN SQUARE 1+ ;",
+			expect: "[Address{DUP} Address{*} Literal(1) Address{+} Address{EXIT}]",
+		},
+		{
+			name:   "no inline called twice",
+			code:   ": SQUARE DUP * ; : MAIN SQUARE SQUARE ;",
+			expect: "[Address{SQUARE} TailCall{SQUARE}]",
+		},
+		{
+			name:   "no inline return stack",
+			code:   ": R-TEST >R R> ; : MAIN R-TEST R-TEST ;",
+			expect: "[Address{R-TEST} TailCall{R-TEST}]",
+		},
+		{
+			name:   "no inline early exit",
+			code:   ": EARLY 1 EXIT 2 ; : MAIN EARLY EARLY ;",
+			expect: "[Address{EARLY} TailCall{EARLY}]",
+		},
+		{
+			name:   "no inline branches",
+			code:   ": BRANCHES IF 1 THEN ; : MAIN BRANCHES BRANCHES ;",
+			expect: "[Address{BRANCHES} TailCall{BRANCHES}]",
+		},
+		{
+			name:   "constant if true",
+			code:   ": MAIN 1 IF 5 ELSE 6 THEN ;",
+			expect: "[Literal(5) Address{EXIT}]",
+		},
+		{
+			name:   "constant if false",
+			code:   ": MAIN 0 IF 5 ELSE 6 THEN ;",
+			expect: "[Literal(6) Address{EXIT}]",
+		},
+		{
+			name:   "constant debug flag",
+			code:   "0 CONSTANT DEBUG : MAIN DEBUG IF 5 U. THEN 6 ;",
+			expect: "[Literal(6) Address{EXIT}]",
+		},
+		{
+			name:   "unreachable after exit",
+			code:   ": MAIN 5 EXIT 6 ;",
+			expect: "[Literal(5) Address{EXIT}]",
+		},
+		{
+			name:   "unreachable after loop",
+			code:   ": MAIN BEGIN 5 U. AGAIN 6 U. ;",
+			expect: "[Dest{*} Literal(5) Address{U.} Branch{*}]",
+		},
+		{
+			name:   "compress sequence",
+			code:   ": MAIN 9 8 7 6 u. 9 8 7 6 u. 9 8 7 6 u. ;",
+			expect: "[Address{SEQUENCE.0} Address{SEQUENCE.0} TailCall{SEQUENCE.0}]",
+		},
+		{
+			name:   "compress two sequences",
+			code:   ": MAIN 9 8 7 6 u. 9 8 7 6 u. 9 8 7 6 u. 5 4 3 2 EMIT 5 4 3 2 EMIT 5 4 3 2 EMIT ;",
+			expect: "[Address{SEQUENCE.0} Address{SEQUENCE.0} Address{SEQUENCE.0} Address{SEQUENCE.1} Address{SEQUENCE.1} TailCall{SEQUENCE.1}]",
+		},
+		{
+			name:   "no compress return stack",
+			code:   ": MAIN 9 >R R> u. 9 >R R> u. 9 >R R> u. ;",
+			expect: "[Literal(9) Address{>R} Address{R>} Address{U.} Literal(9) Address{>R} Address{R>} Address{U.} Literal(9) Address{>R} Address{R>} TailCall{U.}]",
+		},
+		{
+			name:       "no compress subroutine threaded",
+			code:       ": MAIN 9 8 7 6 u. 9 8 7 6 u. 9 8 7 6 u. ;",
+			expect:     "[Literal(9) Literal(8) Literal(7) Literal(6) Address{U.} Literal(9) Literal(8) Literal(7) Literal(6) Address{U.} Literal(9) Literal(8) Literal(7) Literal(6) TailCall{U.}]",
+			subroutine: true,
+		},
+		{
+			name:       "inline assembly",
+			code:       ": MAIN DUP + @ ;",
+			expect:     "[Inline{DUP} Inline{+} Inline{@} Address{EXIT}]",
+			subroutine: true,
+		},
+		{
+			name:       "no inline assembly with r2",
+			code:       ": MAIN U/MOD U/MOD ;",
+			expect:     "[Address{U/MOD} Address{U/MOD} Address{EXIT}]",
+			subroutine: true,
+		},
+		{
+			name:   "no inline assembly token threaded",
+			code:   ": MAIN DUP + @ ;",
+			expect: "[Address{DUP} Address{+} Address{@} Address{EXIT}]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := optimizedCells(tt.code, "MAIN", tt.subroutine)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expect {
+				t.Errorf("expected %s got %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+// Cross compile the named word as a library, so that it
+// isn't inlined, and return the string representation of its cells.
+func optimizedCells(code string, name string, subroutine bool) (string, error) {
+	var buff bytes.Buffer
+	vm := VirtualMachine{Out: &buff}
+	err := vm.Setup()
+	if err != nil {
+		return "", fmt.Errorf("failed to set up vm: %s", err)
+	}
+	err = vm.Execute([]byte(code))
+	if err != nil {
+		return "", fmt.Errorf("failed to execute test code: %s", err)
+	}
+	entry, err := vm.Dictionary.FindName(name)
+	if err != nil {
+		return "", err
+	}
+	ulp := Ulp{}
+	if subroutine {
+		_, err = ulp.BuildLibrarySrt(&vm, []string{name})
+	} else {
+		_, err = ulp.BuildLibrary(&vm, []string{name})
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate assembly: %s", err)
+	}
+	word, ok := entry.Word.(*WordForth)
+	if !ok {
+		return "", fmt.Errorf("%s is not a forth word", name)
+	}
+	// pointers change every run, hide them
+	return pointer.ReplaceAllString(fmt.Sprint(word.Cells), "*"), nil
+}
+
+var pointer = regexp.MustCompile(`0x[0-9a-f]+`)
+
+// TestFallthrough checks that a word tail called from
+// one place is placed directly after its caller.
+func TestFallthrough(t *testing.T) {
+	for _, subroutine := range []bool{false, true} {
+		t.Run(fmt.Sprintf("subroutine %t", subroutine), func(t *testing.T) {
+			var buff bytes.Buffer
+			vm := VirtualMachine{Out: &buff}
+			err := vm.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = vm.Execute([]byte(": C IF 3 THEN ; : B IF 1 THEN C ; : MAIN 1 B 0 B ;"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ulp := Ulp{}
+			if subroutine {
+				_, err = ulp.BuildAssemblySrt(&vm, "MAIN")
+			} else {
+				_, err = ulp.BuildAssembly(&vm, "MAIN")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, len(ulp.forthWords))
+			for i, w := range ulp.forthWords {
+				names[i] = w.Entry.Name
+			}
+			b := slices.Index(names, "B")
+			if b == -1 || b+1 >= len(names) || names[b+1] != "C" {
+				t.Fatalf("expected C directly after B, got %v", names)
+			}
+			tailCall, ok := ulp.forthWords[b].Cells[len(ulp.forthWords[b].Cells)-1].(*CellTailCall)
+			if !ok || !tailCall.fallsThrough {
+				t.Errorf("expected B to fall through to C, got %v", ulp.forthWords[b].Cells)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The maximum number of instructions followed when
+// checking if the ALU flags are used.
+const peepholeFlagSearch = 32
+
+var peepholeLabel = regexp.MustCompile(`^([^\s:,]+):\s*`)
+
+// The lines around code that the peephole pass must not change,
+// such as the body of a primitive that is balanced by cycle count.
+// They are removed from the output.
+const (
+	peepholeFixedStart = "#peephole fixed"
+	peepholeFixedEnd   = "#peephole end"
+)
+
+// A single line of the generated assembly.
+type peepholeLine struct {
+	text      string   // the original text
+	labels    []string // labels defined on this line
+	op        string   // the instruction or directive, empty if none
+	args      []string // the instruction arguments
+	directive bool     // this line is an assembler directive
+	fixed     bool     // this line can't be removed
+}
+
+// Check if this line is an instruction without a label,
+// so nothing can jump to it directly.
+func (l *peepholeLine) plain() bool {
+	return l.op != "" && !l.directive && len(l.labels) == 0 && !l.fixed
+}
+
+func (l *peepholeLine) is(op string, args ...string) bool {
+	if l.op != op || len(l.args) != len(args) {
+		return false
+	}
+	for i, arg := range args {
+		if arg != "" && arg != l.args[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Removes redundant instructions from the generated
+// assembly. The only instructions removed are ones
+// that cannot be jumped to and have no visible effect.
+type Peephole struct {
+	lines  []peepholeLine
+	labels map[string]int // the line that each label is on
+}
+
+func (p *Peephole) Optimize(asm string) string {
+	p.parse(asm)
+	for p.pass() {
+	}
+	out := make([]string, len(p.lines))
+	for i, l := range p.lines {
+		out[i] = l.text
+	}
+	return strings.Join(out, "\r\n")
+}
+
+func (p *Peephole) parse(asm string) {
+	text := strings.Split(asm, "\r\n")
+	p.lines = make([]peepholeLine, 0, len(text))
+	fixed := false
+	for _, t := range text {
+		switch strings.TrimSpace(t) {
+		case peepholeFixedStart:
+			fixed = true
+			continue
+		case peepholeFixedEnd:
+			fixed = false
+			continue
+		}
+		l := parsePeepholeLine(t)
+		l.fixed = fixed
+		p.lines = append(p.lines, l)
+	}
+	p.findLabels()
+}
+
+// Check if the line starts or ends a fixed region.
+func peepholeMarker(t string) bool {
+	t = strings.TrimSpace(t)
+	return t == peepholeFixedStart || t == peepholeFixedEnd
+}
+
+func parsePeepholeLine(t string) peepholeLine {
+	l := peepholeLine{text: t}
+	rest := strings.TrimSpace(t)
+	for {
+		match := peepholeLabel.FindStringSubmatch(rest)
+		if match == nil {
+			break
+		}
+		l.labels = append(l.labels, match[1])
+		rest = rest[len(match[0]):]
+	}
+	if rest != "" {
+		op, args, _ := strings.Cut(rest, " ")
+		l.op = strings.ToLower(op)
+		l.directive = strings.HasPrefix(op, ".")
+		if !l.directive && strings.TrimSpace(args) != "" {
+			l.args = strings.Split(args, ",")
+			for j := range l.args {
+				l.args[j] = strings.TrimSpace(l.args[j])
+			}
+		}
+	}
+	return l
+}
+
+func (p *Peephole) findLabels() {
+	p.labels = make(map[string]int)
+	for i, l := range p.lines {
+		for _, label := range l.labels {
+			p.labels[label] = i
+		}
+	}
+}
+
+// Run every rule once over the assembly.
+// Returns true if anything was removed.
+func (p *Peephole) pass() bool {
+	remove := make([]bool, len(p.lines))
+	changed := false
+	for i := 0; i < len(p.lines); i++ {
+		if !p.lines[i].plain() {
+			continue
+		}
+		next := p.removable(p.next(i))
+		after := p.removable(p.next(next))
+		last := i
+		switch {
+		case next != -1 && p.inversePair(i, next) && p.flagsUnused(next):
+			// the stack pointer moves and then moves back
+			remove[i], remove[next] = true, true
+			last = next
+		case after != -1 && p.deadPush(i, next, after) && p.flagsUnused(after):
+			// a value is stored below the stack and then dropped
+			remove[i], remove[next], remove[after] = true, true, true
+			last = after
+		case next != -1 && p.reload(i, next):
+			// the register already holds the stored value
+			remove[next] = true
+			last = next
+		case p.jumpToNext(i):
+			remove[i] = true
+		default:
+			continue
+		}
+		changed = true
+		i = last // don't overlap removals
+	}
+	if !changed {
+		return false
+	}
+	lines := make([]peepholeLine, 0, len(p.lines))
+	for i, l := range p.lines {
+		if !remove[i] {
+			lines = append(lines, l)
+		}
+	}
+	p.lines = lines
+	p.findLabels()
+	return true
+}
+
+// Get the index of the next line with any contents, or -1.
+func (p *Peephole) next(i int) int {
+	if i == -1 {
+		return -1
+	}
+	for i++; i < len(p.lines); i++ {
+		l := p.lines[i]
+		if l.op != "" || len(l.labels) != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the index if that line can be removed, otherwise -1.
+func (p *Peephole) removable(i int) int {
+	if i == -1 || !p.lines[i].plain() {
+		return -1
+	}
+	return i
+}
+
+// Check for "sub rA, rA, N" followed by "add rA, rA, N" or the reverse.
+func (p *Peephole) inversePair(i int, j int) bool {
+	first, second := p.lines[i], p.lines[j]
+	if len(first.args) != 3 || first.args[0] != first.args[1] {
+		return false
+	}
+	switch first.op {
+	case "add":
+		return second.is("sub", first.args...)
+	case "sub":
+		return second.is("add", first.args...)
+	}
+	return false
+}
+
+// Check for "sub r3, r3, 1", "st rX, r3, 0", "add r3, r3, 1".
+// The data stack grows down so the stored value is discarded.
+func (p *Peephole) deadPush(i int, j int, k int) bool {
+	return p.lines[i].is("sub", "r3", "r3", "1") &&
+		p.lines[j].is("st", "", "r3", "0") &&
+		p.lines[k].is("add", "r3", "r3", "1")
+}
+
+// Check for "st rX, rB, off" followed by "ld rX, rB, off".
+func (p *Peephole) reload(i int, j int) bool {
+	store := p.lines[i]
+	return store.op == "st" && p.lines[j].is("ld", store.args...)
+}
+
+// Check for a jump to the line directly after it.
+func (p *Peephole) jumpToNext(i int) bool {
+	l := p.lines[i]
+	if l.op != "jump" || len(l.args) == 0 || isRegister(l.args[0]) {
+		return false
+	}
+	found := false
+	for j := p.next(i); j != -1; j = p.next(j) {
+		found = found || slices.Contains(p.lines[j].labels, l.args[0])
+		if p.lines[j].op != "" {
+			// __docol reads the jump that called it
+			return found && !p.lines[j].is("jump", "__docol")
+		}
+	}
+	return found
+}
+
+// Check that the ALU flags are set again before being read,
+// starting after line i and following unconditional jumps.
+func (p *Peephole) flagsUnused(i int) bool {
+	for steps := 0; steps < peepholeFlagSearch; steps++ {
+		i = p.next(i)
+		if i == -1 {
+			return false
+		}
+		l := p.lines[i]
+		if l.op == "" { // only a label
+			continue
+		}
+		if l.directive {
+			return false
+		}
+		switch l.op {
+		case "add", "sub": // sets both the zero and overflow flags
+			return true
+		case "halt":
+			return true
+		case "jump":
+			if len(l.args) != 1 || isRegister(l.args[0]) {
+				return false // conditional or unknown destination
+			}
+			target, ok := p.labels[l.args[0]]
+			if !ok {
+				return false
+			}
+			i = target - 1 // continue from the destination
+		case "jumpr", "jumps":
+			return false
+		}
+	}
+	return false
+}
+
+func isRegister(s string) bool {
+	switch strings.ToLower(s) {
+	case "r0", "r1", "r2", "r3":
+		return true
+	}
+	return false
+}
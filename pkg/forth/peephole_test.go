@@ -0,0 +1,202 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPeephole(t *testing.T) {
+	tests := []struct {
+		name   string
+		asm    []string
+		expect []string
+	}{
+		{
+			name: "stack pointer pair",
+			asm: []string{
+				"sub r3, r3, 1",
+				"add r3, r3, 1",
+				"move r0, 1",
+				"add r1, r1, 1",
+			},
+			expect: []string{
+				"move r0, 1",
+				"add r1, r1, 1",
+			},
+		},
+		{
+			name: "pair with flags used",
+			asm: []string{
+				"sub r3, r3, 1",
+				"add r3, r3, 1",
+				"jump __x, eq",
+				"__x:",
+			},
+			expect: []string{
+				"sub r3, r3, 1",
+				"add r3, r3, 1",
+				"__x:",
+			},
+		},
+		{
+			name: "pair with flags used after jump",
+			asm: []string{
+				"sub r3, r3, 1",
+				"add r3, r3, 1",
+				"jump __x",
+				"halt",
+				"__x:",
+				"jump __y, ov",
+				"halt",
+				"__y:",
+				"halt",
+			},
+			expect: []string{
+				"sub r3, r3, 1",
+				"add r3, r3, 1",
+				"jump __x",
+				"halt",
+				"__x:",
+				"jump __y, ov",
+				"halt",
+				"__y:",
+				"halt",
+			},
+		},
+		{
+			name: "pair split by label",
+			asm: []string{
+				"sub r3, r3, 1",
+				"__x:",
+				"add r3, r3, 1",
+				"halt",
+			},
+			expect: []string{
+				"sub r3, r3, 1",
+				"__x:",
+				"add r3, r3, 1",
+				"halt",
+			},
+		},
+		{
+			name: "dead push",
+			asm: []string{
+				"ld r0, r3, 0",
+				"sub r3, r3, 1",
+				"st r0, r3, 0",
+				"add r3, r3, 1",
+				"halt",
+			},
+			expect: []string{
+				"ld r0, r3, 0",
+				"halt",
+			},
+		},
+		{
+			name: "store reload",
+			asm: []string{
+				"st r0, r3, 0",
+				"ld r0, r3, 0",
+				"ld r1, r3, 0",
+			},
+			expect: []string{
+				"st r0, r3, 0",
+				"ld r1, r3, 0",
+			},
+		},
+		{
+			name: "jump to next",
+			asm: []string{
+				"jump __x",
+				"",
+				"__x:",
+				"halt",
+			},
+			expect: []string{
+				"",
+				"__x:",
+				"halt",
+			},
+		},
+		{
+			name: "jump over instruction",
+			asm: []string{
+				"jump __x",
+				"halt",
+				"__x:",
+				"halt",
+			},
+			expect: []string{
+				"jump __x",
+				"halt",
+				"__x:",
+				"halt",
+			},
+		},
+		{
+			name: "keep call to forth word",
+			asm: []string{
+				"jump __forth_x",
+				"__forth_x:",
+				"jump __docol",
+			},
+			expect: []string{
+				"jump __forth_x",
+				"__forth_x:",
+				"jump __docol",
+			},
+		},
+		{
+			name: "keep safe call",
+			asm: []string{
+				"__safe_call: jump __x",
+				"__x:",
+				"halt",
+			},
+			expect: []string{
+				"__safe_call: jump __x",
+				"__x:",
+				"halt",
+			},
+		},
+		{
+			name: "keep fixed primitive body",
+			asm: []string{
+				peepholeFixedStart,
+				"sub r3, r3, 1",
+				"add r3, r3, 1",
+				"jump __x",
+				"__x:",
+				peepholeFixedEnd,
+				"jump __y",
+				"__y:",
+				"halt",
+			},
+			expect: []string{
+				"sub r3, r3, 1",
+				"add r3, r3, 1",
+				"jump __x",
+				"__x:",
+				"__y:",
+				"halt",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Peephole{}
+			got := p.Optimize(strings.Join(tt.asm, "\r\n"))
+			expect := strings.Join(tt.expect, "\r\n")
+			if got != expect {
+				t.Errorf("expected:\n%s\ngot:\n%s", expect, got)
+			}
+		})
+	}
+}
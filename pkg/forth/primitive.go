@@ -15,22 +15,28 @@ import (
 )
 
 type primitive struct {
-	name      string
-	goFunc    PrimitiveGo
-	ulpAsm    PrimitiveUlp
-	ulpAsmSrt PrimitiveUlpSrt
-	flag      Flag
+	name        string
+	goFunc      PrimitiveGo
+	ulpAsm      PrimitiveUlp
+	ulpAsmSrt   PrimitiveUlpSrt
+	ulpAsmRiscv PrimitiveUlpRiscv
+	flag        Flag
+	effect      StackEffect
+	rEffect     StackEffect
 }
 
-func primitiveAdd(vm *VirtualMachine, name string, goFunc PrimitiveGo, ulpAsm PrimitiveUlp, ulpAsmSrt PrimitiveUlpSrt, flag Flag) error {
+func primitiveAdd(vm *VirtualMachine, name string, goFunc PrimitiveGo, ulpAsm PrimitiveUlp, ulpAsmSrt PrimitiveUlpSrt, ulpAsmRiscv PrimitiveUlpRiscv, flag Flag, effect StackEffect, rEffect StackEffect) error {
 	var entry DictionaryEntry
 	entry = DictionaryEntry{
 		Name: name,
 		Word: &WordPrimitive{
-			Go:     goFunc,
-			Ulp:    ulpAsm,
-			UlpSrt: ulpAsmSrt,
-			Entry:  &entry,
+			Go:           goFunc,
+			Ulp:          ulpAsm,
+			UlpSrt:       ulpAsmSrt,
+			UlpRiscv:     ulpAsmRiscv,
+			Effect:       effect,
+			ReturnEffect: rEffect,
+			Entry:        &entry,
 		},
 		Flag: flag,
 	}
@@ -50,6 +56,20 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 				return nil
 			},
 		},
+		{
+			name: "TRACE-ON",
+			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
+				vm.Trace = true
+				return nil
+			},
+		},
+		{
+			name: "TRACE-OFF",
+			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
+				vm.Trace = false
+				return nil
+			},
+		},
 		{
 			name: "WORDS",
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
@@ -363,6 +383,36 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 				return nil
 			},
 		},
+		{
+			name: "--CREATE-ASSEMBLY-RISCV",
+			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
+				name, err := parseWord(vm, entry)
+				if err != nil {
+					return JoinEntryError(err, entry, "could not parse name")
+				}
+				asm, err := parseAssembly(vm, entry)
+				if err != nil {
+					return JoinEntryError(err, entry, "could not parse assembly")
+				}
+				var newEntry DictionaryEntry
+				newEntry = DictionaryEntry{
+					Name: name,
+					Word: &WordPrimitive{
+						Go: notImplemented,
+						UlpRiscv: PrimitiveUlpRiscv{
+							Asm:             asm,
+							NonStandardNext: false,
+						},
+						Entry: &newEntry,
+					},
+				}
+				err = vm.Dictionary.AddEntry(&newEntry)
+				if err != nil {
+					return JoinEntryError(err, entry, "could not add entry to dictionary")
+				}
+				return nil
+			},
+		},
 		{
 			name: "[",
 			flag: Flag{Immediate: true},
@@ -463,7 +513,9 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 			},
 		},
 		{
-			name: ">R",
+			name:    ">R",
+			effect:  stackEffect(1, 0),
+			rEffect: stackEffect(0, 1),
 			flag: Flag{
 				isPure:          true,
 				usesReturnStack: true,
@@ -501,9 +553,19 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"add r3, r3, 1",  // decrement stack
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",   // load the value from the stack
+					"addi s0, s0, 4", // decrement the stack
+					"addi s1, s1, 4", // increment the rsp
+					"sw a0, 0(s1)",   // store the value on the return stack
+				},
+			},
 		},
 		{
-			name: "R>",
+			name:    "R>",
+			effect:  stackEffect(0, 1),
+			rEffect: stackEffect(1, 0),
 			flag: Flag{
 				isPure:          true,
 				usesReturnStack: true,
@@ -541,6 +603,14 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",   // store value on stack
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s1)",    // load the value from the return stack
+					"addi s1, s1, -4", // decrement the rsp
+					"addi s0, s0, -4", // increment the stack
+					"sw a0, 0(s0)",    // store the value on the stack
+				},
+			},
 		},
 		{
 			name: "BRANCH",
@@ -730,6 +800,9 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 		},
 		{
 			name: "EXECUTE",
+			flag: Flag{
+				followsToken: true,
+			},
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				c, err := vm.Stack.Pop()
 				if err != nil {
@@ -777,6 +850,21 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 				},
 				NonStandardNext: true,
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",   // load the token
+					"addi s0, s0, 4", // decrement the stack
+					"slli a0, a0, 2", // change the cells to bytes
+					"la t0, __forth_words",
+					"bgeu a0, t0, __execute.0", // jump if the address is past assembly words
+					"jr a0",                    // it's an assembly word, it returns to our caller
+					"__execute.0:",
+					"addi s1, s1, 4", // it's a forth word, push the return address like the docol
+					"sw ra, 0(s1)",
+					"jr a0", // jump to the forth word, past the docol
+				},
+				NonStandardNext: true,
+			},
 		},
 		{
 			name: "--ALLOCATE", // ( size global name -- address success )
@@ -928,7 +1016,8 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 			},
 		},
 		{
-			name: "@",
+			name:   "@",
+			effect: stackEffect(1, 1),
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				cell, err := vm.Stack.Pop()
 				if err != nil {
@@ -967,9 +1056,19 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0", // store the value on stack
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",    // get the address from stack
+					"slli a0, a0, 17", // remove the upper byte bit
+					"srli a0, a0, 15", // and change the cells to bytes
+					"lhu a0, 0(a0)",   // load the value
+					"sw a0, 0(s0)",    // store the value on stack
+				},
+			},
 		},
 		{
-			name: "!",
+			name:   "!",
+			effect: stackEffect(2, 0),
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				cell, err := vm.Stack.Pop()
 				if err != nil {
@@ -1011,9 +1110,23 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"add r3, r3, 2", // decrement the stack
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",    // load the address
+					"lw a1, 4(s0)",    // load the value
+					"slli a0, a0, 17", // remove the upper byte bit
+					"srli a0, a0, 15", // and change the cells to bytes
+					"sw a1, 0(a0)",    // store the value in address
+					"addi s0, s0, 8",  // decrement the stack
+				},
+			},
 		},
 		{
-			name: ">BODY",
+			name:   ">BODY",
+			effect: stackEffect(1, 1),
+			flag: Flag{
+				followsToken: true,
+			},
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				cell, err := vm.Stack.Pop()
 				if err != nil {
@@ -1052,6 +1165,13 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0", // store the body address on stack
 				},
 				Next: TokenNextSkipLoad,
+				Direct: []string{
+					"ld r0, r3, 0",  // get the address from stack
+					"ld r0, r0, 0",  // the body address literal is in the front, load it
+					"ld r0, r0, 0",  // get the move instruction of the literal
+					"rsh r0, r0, 4", // shift the address into the correct space
+					"st r0, r3, 0",  // store the body address on stack
+				},
 			},
 			ulpAsmSrt: PrimitiveUlpSrt{
 				Asm: []string{
@@ -1061,9 +1181,19 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",  // store the address on stack
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",   // get the address from stack
+					"slli a0, a0, 2", // change the cells to bytes
+					"lw a0, 4(a0)",   // the body address follows the call to __push_address
+					"srli a0, a0, 2", // change the bytes to cells
+					"sw a0, 0(s0)",   // store the address on stack
+				},
+			},
 		},
 		{
-			name: "C@",
+			name:   "C@",
+			effect: stackEffect(1, 1),
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				cell, err := vm.Stack.Pop()
 				if err != nil {
@@ -1120,9 +1250,21 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r1, r3, 0",     // store the masked value
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",    // load the address
+					"srli a1, a0, 15", // get the "upper" bit
+					"slli a0, a0, 17", // remove the upper bit
+					"srli a0, a0, 15", // and change the cells to bytes
+					"add a0, a0, a1",  // the upper byte is the next byte
+					"lbu a0, 0(a0)",   // load the character
+					"sw a0, 0(s0)",    // store the character
+				},
+			},
 		},
 		{
-			name: "C!",
+			name:   "C!",
+			effect: stackEffect(2, 0),
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				cell, err := vm.Stack.Pop()
 				if err != nil {
@@ -1204,9 +1346,22 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"add r3, r3, 2", // decrement stack
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",    // load the address
+					"lw a1, 4(s0)",    // load the value
+					"srli a2, a0, 15", // get the "upper" bit
+					"slli a0, a0, 17", // remove the upper bit
+					"srli a0, a0, 15", // and change the cells to bytes
+					"add a0, a0, a2",  // the upper byte is the next byte
+					"sb a1, 0(a0)",    // store the character
+					"addi s0, s0, 8",  // decrement stack
+				},
+			},
 		},
 		{
-			name: "CHAR+",
+			name:   "CHAR+",
+			effect: stackEffect(1, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1270,9 +1425,24 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0", // store the result
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",
+					"li a1, 0x8000",
+					"bltu a0, a1, __char_plus.0", // jump if the "upper" bit is not set
+					"sub a0, a0, a1",             // bit is set! remove it
+					"addi a0, a0, 1",             // increment to next position
+					"j __char_plus.1",
+					"__char_plus.0:",
+					"or a0, a0, a1", // bit is not set, set it
+					"__char_plus.1:",
+					"sw a0, 0(s0)", // store the result
+				},
+			},
 		},
 		{
-			name: "ALIGNED",
+			name:   "ALIGNED",
+			effect: stackEffect(1, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1322,6 +1492,18 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"__aligned.0:",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)", // load the address
+					"li a1, 0x8000",
+					"bltu a0, a1, __aligned.0",
+					"addi a0, a0, 1",  // go to the next major position
+					"slli a0, a0, 17", // mask off the upper bit
+					"srli a0, a0, 17",
+					"sw a0, 0(s0)", // store the result
+					"__aligned.0:",
+				},
+			},
 		},
 		{
 			name: "--POSTPONE",
@@ -1372,6 +1554,46 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 				return nil
 			},
 		},
+		{
+			name: "SET-UNCHECKED-STACK",
+			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
+				cell0, err := vm.Stack.Pop()
+				if err != nil {
+					return PopError(err, entry)
+				}
+				cellAddr, ok := cell0.(CellAddress)
+				if !ok {
+					return EntryError(entry, "requires an address cell, found %s type %T", cell0, cell0)
+				}
+				cellNum, err := vm.Stack.PopNumber()
+				if err != nil {
+					return JoinEntryError(err, entry, "could not get boolean")
+				}
+				flag := cellNum != 0
+				cellAddr.Entry.Flag.uncheckedStack = flag
+				return nil
+			},
+		},
+		{
+			name: "SET-HOT",
+			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
+				cell0, err := vm.Stack.Pop()
+				if err != nil {
+					return PopError(err, entry)
+				}
+				cellAddr, ok := cell0.(CellAddress)
+				if !ok {
+					return EntryError(entry, "requires an address cell, found %s type %T", cell0, cell0)
+				}
+				cellNum, err := vm.Stack.PopNumber()
+				if err != nil {
+					return JoinEntryError(err, entry, "could not get boolean")
+				}
+				flag := cellNum != 0
+				cellAddr.Entry.Flag.hot = flag
+				return nil
+			},
+		},
 		{
 			name: "SET-IMMEDIATE",
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
@@ -1432,7 +1654,35 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 			},
 		},
 		{
-			name: "EXIT",
+			name: "SET-STACK-EFFECT", // ( in out xt -- )
+			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
+				cell0, err := vm.Stack.Pop()
+				if err != nil {
+					return PopError(err, entry)
+				}
+				cellAddr, ok := cell0.(CellAddress)
+				if !ok {
+					return EntryError(entry, "requires an address cell, found %s type %T", cell0, cell0)
+				}
+				out, err := vm.Stack.PopNumber()
+				if err != nil {
+					return JoinEntryError(err, entry, "could not get number of outputs")
+				}
+				in, err := vm.Stack.PopNumber()
+				if err != nil {
+					return JoinEntryError(err, entry, "could not get number of inputs")
+				}
+				word, ok := cellAddr.Entry.Word.(*WordPrimitive)
+				if !ok {
+					return EntryError(entry, "requires a primitive word")
+				}
+				word.Effect = stackEffect(int(in), int(out))
+				return nil
+			},
+		},
+		{
+			name:   "EXIT",
+			effect: stackEffect(0, 0),
 			flag: Flag{
 				isExit: true,
 			},
@@ -1471,9 +1721,14 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 				},
 				NonStandardNext: true,
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm:             riscvExit,
+				NonStandardNext: true,
+			},
 		},
 		{
-			name: "+",
+			name:   "+",
+			effect: stackEffect(2, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1538,9 +1793,21 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",
+					"lw a1, 4(s0)",
+					"add a0, a0, a1",
+					"slli a0, a0, 16", // keep the lower 16 bits
+					"srli a0, a0, 16",
+					"addi s0, s0, 4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "-",
+			name:   "-",
+			effect: stackEffect(2, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1620,9 +1887,21 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 4(s0)",
+					"lw a1, 0(s0)",
+					"sub a0, a0, a1",
+					"slli a0, a0, 16", // keep the lower 16 bits
+					"srli a0, a0, 16",
+					"addi s0, s0, 4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "AND",
+			name:   "AND",
+			effect: stackEffect(2, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1660,9 +1939,19 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 4(s0)",
+					"lw a1, 0(s0)",
+					"and a0, a0, a1",
+					"addi s0, s0, 4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "OR",
+			name:   "OR",
+			effect: stackEffect(2, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1700,9 +1989,19 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 4(s0)",
+					"lw a1, 0(s0)",
+					"or a0, a0, a1",
+					"addi s0, s0, 4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "*",
+			name:   "*",
+			effect: stackEffect(2, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1773,9 +2072,21 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"add r3, r3, 1", // decrement stack, z already in place
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 4(s0)",
+					"lw a1, 0(s0)",
+					"mul a0, a0, a1",
+					"slli a0, a0, 16", // keep the lower 16 bits
+					"srli a0, a0, 16",
+					"addi s0, s0, 4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "U/MOD",
+			name:   "U/MOD",
+			effect: stackEffect(2, 2),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1876,9 +2187,22 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"ld r2, r3, -1", // reload r2
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 4(s0)",    // load n
+					"lw a1, 0(s0)",    // load d
+					"remu a2, a0, a1", // r
+					"divu a0, a0, a1", // q
+					"slli a0, a0, 16", // dividing by 0 gives all ones, keep 16 bits
+					"srli a0, a0, 16",
+					"sw a2, 4(s0)", // store r
+					"sw a0, 0(s0)", // store q
+				},
+			},
 		},
 		{
-			name: "LSHIFT",
+			name:   "LSHIFT",
+			effect: stackEffect(2, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1916,9 +2240,21 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 4(s0)",
+					"lw a1, 0(s0)",
+					"sll a0, a0, a1",
+					"slli a0, a0, 16", // keep the lower 16 bits
+					"srli a0, a0, 16",
+					"addi s0, s0, 4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "RSHIFT",
+			name:   "RSHIFT",
+			effect: stackEffect(2, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1956,9 +2292,19 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 4(s0)",
+					"lw a1, 0(s0)",
+					"srl a0, a0, a1",
+					"addi s0, s0, 4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "SWAP",
+			name:   "SWAP",
+			effect: stackEffect(2, 2),
 			flag: Flag{
 				isPure: true,
 			},
@@ -1998,9 +2344,18 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",
+					"lw a1, 4(s0)",
+					"sw a0, 4(s0)",
+					"sw a1, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "DUP",
+			name:   "DUP",
+			effect: stackEffect(1, 2),
 			flag: Flag{
 				isPure: true,
 			},
@@ -2034,9 +2389,17 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",
+					"addi s0, s0, -4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "PICK",
+			name:   "PICK",
+			effect: stackEffect(1, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -2073,9 +2436,20 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",
+					"slli a0, a0, 2",
+					"add a0, a0, s0",
+					"lw a0, 4(a0)",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "RPICK",
+			name:    "RPICK",
+			effect:  stackEffect(1, 1),
+			rEffect: stackEffect(0, 0),
 			flag: Flag{
 				isPure:          true,
 				usesReturnStack: true,
@@ -2116,9 +2490,19 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",
+					"slli a0, a0, 2",
+					"sub a0, s1, a0",
+					"lw a0, 0(a0)",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "ROT",
+			name:   "ROT",
+			effect: stackEffect(3, 3),
 			flag: Flag{
 				isPure: true,
 			},
@@ -2170,9 +2554,20 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)",
+					"lw a1, 4(s0)",
+					"lw a2, 8(s0)",
+					"sw a1, 8(s0)",
+					"sw a0, 4(s0)",
+					"sw a2, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "ROLL",
+			name:   "ROLL",
+			effect: stackEffect(1, 0),
 			flag: Flag{
 				isPure: true,
 			},
@@ -2240,9 +2635,27 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"add r3, r3, 1",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 0(s0)", // load u
+					"slli a0, a0, 2",
+					"add a1, s0, a0", // point to the value under xu
+					"lw a2, 4(a1)",   // load xu
+					"__roll.0:",
+					"beq a1, s0, __roll.1",
+					"lw t0, 0(a1)", // move each value up
+					"sw t0, 4(a1)",
+					"addi a1, a1, -4",
+					"j __roll.0",
+					"__roll.1:",
+					"addi s0, s0, 4", // decrement the stack
+					"sw a2, 0(s0)",   // store xu on top
+				},
+			},
 		},
 		{
-			name: "DROP",
+			name:   "DROP",
+			effect: stackEffect(1, 0),
 			flag: Flag{
 				isPure: true,
 			},
@@ -2264,9 +2677,16 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"add r3, r3, 1",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"addi s0, s0, 4",
+				},
+			},
 		},
 		{
-			name: "LOOPCHECK",
+			name:    "LOOPCHECK",
+			effect:  stackEffect(1, 1),
+			rEffect: stackEffect(2, 2),
 			flag: Flag{
 				isPure:          true,
 				usesReturnStack: true,
@@ -2370,9 +2790,39 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",  // save value
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw t0, 0(s1)",   // load loop index
+					"lw t1, -4(s1)",  // load limit
+					"lw a1, 0(s0)",   // load n
+					"add a0, t0, a1", // n+index
+					"slli a0, a0, 16",
+					"srli a0, a0, 16",
+					"sw a0, 0(s1)",   // store n+index for next time
+					"sub t0, t0, t1", // index-limit
+					"slli t0, t0, 16",
+					"srli t0, t0, 16",
+					"slli a2, a1, 16",
+					"bltz a2, __loopcheck.0", // check sign of n
+					"add t0, t0, a1",         // positive, add
+					"j __loopcheck.1",
+					"__loopcheck.0:",
+					"sub a1, zero, a1", // negative, negate and subtract
+					"slli a1, a1, 16",
+					"srli a1, a1, 16",
+					"sub t0, t0, a1",
+					"__loopcheck.1:",
+					"srli t0, t0, 16", // any bits past 16 means it crossed the limit
+					"snez t0, t0",
+					"neg t0, t0", // true is 0xFFFF
+					"srli t0, t0, 16",
+					"sw t0, 0(s0)", // save value
+				},
+			},
 		},
 		{
-			name: "U<",
+			name:   "U<",
+			effect: stackEffect(2, 1),
 			flag: Flag{
 				isPure: true,
 			},
@@ -2422,9 +2872,21 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",            // store the result
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 4(s0)", // left
+					"lw a1, 0(s0)", // right
+					"sltu a0, a0, a1",
+					"neg a0, a0", // true is 0xFFFF
+					"srli a0, a0, 16",
+					"addi s0, s0, 4", // decrement stack
+					"sw a0, 0(s0)",   // store the result
+				},
+			},
 		},
 		{
-			name: "DEPTH",
+			name:   "DEPTH",
+			effect: stackEffect(0, 1),
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				depth := len(vm.Stack.stack)
 				cell := CellNumber{uint16(depth)}
@@ -2451,9 +2913,19 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"la a0, __stack_end",
+					"sub a0, a0, s0",
+					"srli a0, a0, 2",
+					"addi s0, s0, -4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
-			name: "VM.STACK.INIT", // initialize the ulp stack
+			name:   "VM.STACK.INIT", // initialize the ulp stack
+			effect: stackEffect(0, 0),
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				vm.Stack.stack = make([]Cell, 0)
 				return nil
@@ -2469,9 +2941,15 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"move r3, __stack_end", // set the stack pointer to the end of the stack
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"la s0, __stack_end", // set the stack pointer to the end of the stack
+				},
+			},
 		},
 		{
 			name:   "HALT",
+			effect: stackEffect(0, 0),
 			goFunc: notImplemented,
 			ulpAsm: PrimitiveUlp{
 				Asm: []string{
@@ -2488,7 +2966,8 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 			},
 		},
 		{
-			name: "ESP.FUNC.UNSAFE", // use one of the custom esp32/host functions
+			name:   "ESP.FUNC.UNSAFE", // use one of the custom esp32/host functions
+			effect: stackEffect(2, 0),
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				funcType, err := vm.Stack.PopNumber()
 				if err != nil {
@@ -2535,9 +3014,21 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"add r3, r3, 2",          // decrease the stack by 2
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"la t0, HOST_PARAM0",
+					"lw a0, 4(s0)", // load the value we want to print
+					"sw a0, 0(t0)", // set the param
+					"la t0, HOST_FUNC",
+					"lw a0, 0(s0)",   // load the method number
+					"sw a0, 0(t0)",   // set the function indicator
+					"addi s0, s0, 8", // decrease the stack by 2
+				},
+			},
 		},
 		{
-			name: "ESP.FUNC.READ.UNSAFE",
+			name:   "ESP.FUNC.READ.UNSAFE",
+			effect: stackEffect(0, 1),
 			goFunc: func(vm *VirtualMachine, entry *DictionaryEntry) error {
 				err := vm.Stack.Push(CellNumber{0}) // always return 0
 				if err != nil {
@@ -2561,9 +3052,18 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"la a0, HOST_FUNC",
+					"lhu a0, 0(a0)",
+					"addi s0, s0, -4",
+					"sw a0, 0(s0)",
+				},
+			},
 		},
 		{
 			name:   "MUTEX.TAKE",
+			effect: stackEffect(0, 0),
 			goFunc: nop,
 			ulpAsm: PrimitiveUlp{
 				Asm: []string{
@@ -2593,9 +3093,26 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"__mutex.take.1:",
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"li a0, 1",
+					"la t0, MUTEX_FLAG0",
+					"sw a0, 0(t0)", // flag0 = 1
+					"la t0, MUTEX_TURN",
+					"sw a0, 0(t0)", // turn = 1
+					"la t1, MUTEX_FLAG1",
+					"__mutex.take.0:",         // while flag1>0 && turn>0
+					"lhu a0, 0(t1)",           // read flag1
+					"beqz a0, __mutex.take.1", // exit if flag1<1
+					"lhu a0, 0(t0)",           // read turn
+					"bnez a0, __mutex.take.0", // loop if turn>0
+					"__mutex.take.1:",
+				},
+			},
 		},
 		{
 			name:   "MUTEX.GIVE",
+			effect: stackEffect(0, 0),
 			goFunc: nop,
 			ulpAsm: PrimitiveUlp{
 				Asm: []string{
@@ -2609,10 +3126,17 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r0, MUTEX_FLAG0", // flag0 = 0
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"la t0, MUTEX_FLAG0",
+					"sw zero, 0(t0)", // flag0 = 0
+				},
+			},
 		},
 
 		{
-			name: "D-", // ( xlow xhigh ylow yhigh -- zlow zhigh )
+			name:   "D-", // ( xlow xhigh ylow yhigh -- zlow zhigh )
+			effect: stackEffect(4, 2),
 			flag: Flag{
 				isPure: true,
 			},
@@ -2686,9 +3210,29 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",   // store zhigh
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 12(s0)", // xlow
+					"lw a1, 8(s0)",  // xhigh
+					"slli a1, a1, 16",
+					"or a0, a0, a1", // x
+					"lw a1, 4(s0)",  // ylow
+					"lw a2, 0(s0)",  // yhigh
+					"slli a2, a2, 16",
+					"or a1, a1, a2",  // y
+					"sub a0, a0, a1", // subtract
+					"addi s0, s0, 8", // decrement stack
+					"srli a1, a0, 16",
+					"sw a1, 0(s0)", // store zhigh
+					"slli a0, a0, 16",
+					"srli a0, a0, 16",
+					"sw a0, 4(s0)", // store zlow
+				},
+			},
 		},
 		{
-			name: "D+", // ( xlow xhigh ylow yhigh -- zlow zhigh )
+			name:   "D+", // ( xlow xhigh ylow yhigh -- zlow zhigh )
+			effect: stackEffect(4, 2),
 			flag: Flag{
 				isPure: true,
 			},
@@ -2762,10 +3306,29 @@ func PrimitiveSetup(vm *VirtualMachine) error {
 					"st r0, r3, 0",   // store zhigh
 				},
 			},
+			ulpAsmRiscv: PrimitiveUlpRiscv{
+				Asm: []string{
+					"lw a0, 12(s0)", // xlow
+					"lw a1, 8(s0)",  // xhigh
+					"slli a1, a1, 16",
+					"or a0, a0, a1", // x
+					"lw a1, 4(s0)",  // ylow
+					"lw a2, 0(s0)",  // yhigh
+					"slli a2, a2, 16",
+					"or a1, a1, a2",  // y
+					"add a0, a0, a1", // add
+					"addi s0, s0, 8", // decrement stack
+					"srli a1, a0, 16",
+					"sw a1, 0(s0)", // store zhigh
+					"slli a0, a0, 16",
+					"srli a0, a0, 16",
+					"sw a0, 4(s0)", // store zlow
+				},
+			},
 		},
 	}
 	for _, p := range prims {
-		err := primitiveAdd(vm, p.name, p.goFunc, p.ulpAsm, p.ulpAsmSrt, p.flag)
+		err := primitiveAdd(vm, p.name, p.goFunc, p.ulpAsm, p.ulpAsmSrt, p.ulpAsmRiscv, p.flag, p.effect, p.rEffect)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The ULP-RISC-V is subroutine threaded. Every cell is
+// 32 bits in memory but only holds 16 bits, the same as
+// on the ULP-FSM, and the addresses used by forth are in
+// cells. Registers:
+//
+//	s0: data stack pointer, grows down and points to the top value
+//	s1: return stack pointer, grows up and points to the top value
+//	ra: the return address of primitives and forth words
+//
+// a0-a2 and t0-t2 are free to use by any primitive.
+
+// The number of stack cells used when the depth of the stacks is not known.
+const riscvStackCells = 256
+
+// The start of a forth word that is called,
+// push the return address to the return stack.
+var riscvDocol = []string{
+	"addi s1, s1, 4",
+	"sw ra, 0(s1)",
+}
+
+// Return from a forth word.
+var riscvExit = []string{
+	"lw ra, 0(s1)",
+	"addi s1, s1, -4",
+	"ret",
+}
+
+func (u *Ulp) buildInterpreterRiscv() string {
+	i := []string{
+		// required data, will be placed at the start of .data
+		".data",
+		".global MUTEX_FLAG0",
+		".global MUTEX_FLAG1",
+		".global MUTEX_TURN",
+		".global HOST_FUNC",
+		".global HOST_PARAM0",
+		"MUTEX_FLAG0: .int 0", // keep the same order as the ULP-FSM
+		"MUTEX_FLAG1: .int 0",
+		"MUTEX_TURN:  .int 0",
+		"HOST_FUNC:   .int 0",
+		"HOST_PARAM0: .int 0",
+		// the state saved by HALT, the processor is
+		// reset when it halts
+		"__resume_ra: .int 0", // the address to continue at, 0 if starting
+		"__resume_s0: .int 0",
+		"__resume_s1: .int 0",
+
+		// the esp-idf startup code calls main
+		".text",
+		".global main",
+		"main:",
+		"la t0, __resume_ra",
+		"lw ra, 0(t0)",
+		"beqz ra, __main.0", // start from the beginning if not halted
+		"sw zero, 0(t0)",    // only continue once
+		"lw s0, 4(t0)",
+		"lw s1, 8(t0)",
+		"ret", // continue after HALT
+
+		"__main.0:",
+		// change each data address from bytes to cells,
+		// each is cleared so it's only done once
+		"la t0, __fixups",
+		"la t1, __fixups_end",
+		"__main.1:",
+		"beq t0, t1, __main.3",
+		"lw t2, 0(t0)",
+		"beqz t2, __main.2",
+		"lw a0, 0(t2)",
+		"srli a0, a0, 2",
+		"sw a0, 0(t2)",
+		"sw zero, 0(t0)",
+		"__main.2:",
+		"addi t0, t0, 4",
+		"j __main.1",
+		"__main.3:",
+		"la s0, __stack_end",      // set up the data stack pointer
+		"la s1, __stack_start",    // set up the return stack pointer
+		"j __body__forth_VM.INIT", // begin execution
+
+		// push the address after the call, divided into cells,
+		// then continue after that address
+		"__push_address:",
+		"lw a0, 0(t0)",
+		"srli a0, a0, 2",
+		"addi s0, s0, -4",
+		"sw a0, 0(s0)",
+		"jalr zero, 4(t0)",
+
+		// save the state for HALT then return to t0
+		".global __save_state",
+		"__save_state:",
+		"la t1, __resume_ra",
+		"sw ra, 0(t1)",
+		"sw s0, 4(t1)",
+		"sw s1, 8(t1)",
+		"jr t0",
+	}
+	return strings.Join(i, "\r\n") + "\r\n"
+}
+
+// Build the table of data addresses and the stacks.
+func (u *Ulp) buildStackRiscv() string {
+	cells := riscvStackCells
+	if u.Depth.Bounded() {
+		cells = u.Depth.Cells()
+	}
+	i := []string{
+		".data",
+		"__fixups:",
+	}
+	for _, fixup := range u.fixups {
+		i = append(i, ".int "+fixup)
+	}
+	i = append(i,
+		"__fixups_end:",
+		".bss",
+		".balign 4",
+		"__stack_start:",
+		fmt.Sprintf(".skip %d", cells*4),
+		"__stack_end:",
+	)
+	return strings.Join(i, "\r\n")
+}
+
+// The number of bytes in the stack section of the last
+// ULP-RISC-V build.
+func (u *Ulp) RiscvStackBytes() int {
+	if u.Depth.Bounded() {
+		return u.Depth.Cells() * 4
+	}
+	return riscvStackCells * 4
+}
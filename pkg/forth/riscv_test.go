@@ -0,0 +1,873 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The ULP-RISC-V assembly is assembled by the esp-idf, so the
+// tests use a small RV32IM assembler and emulator. It only knows
+// enough to run the assembly created by ulp-forth.
+
+const (
+	riscvMemory   = 16384      // more than the RTC slow memory, the esp-idf compresses the instructions and checks the real size
+	riscvMaxSteps = 16_000_000 // the most instructions run by a test
+	riscvReturn   = 0xFFFFFFFC // the return address given to main
+
+	riscvPeripherals = 0x3F400000 // the lowest peripheral address
+)
+
+var (
+	riscvLabel  = regexp.MustCompile(`^\s*([A-Za-z_.$][\w.$]*)\s*:`)
+	riscvOffset = regexp.MustCompile(`^(.*)\((\w+)\)$`)
+)
+
+var riscvRegisters = map[string]int{
+	"zero": 0, "ra": 1, "sp": 2, "gp": 3, "tp": 4, "t0": 5, "t1": 6, "t2": 7,
+	"s0": 8, "fp": 8, "s1": 9, "a0": 10, "a1": 11, "a2": 12, "a3": 13, "a4": 14,
+	"a5": 15, "a6": 16, "a7": 17, "s2": 18, "s3": 19, "s4": 20, "s5": 21, "s6": 22,
+	"s7": 23, "s8": 24, "s9": 25, "s10": 26, "s11": 27, "t3": 28, "t4": 29, "t5": 30,
+	"t6": 31,
+}
+
+type riscvSection int
+
+const (
+	riscvText riscvSection = iota
+	riscvData
+	riscvBss
+)
+
+// A line of assembly placed in a section.
+type riscvItem struct {
+	section riscvSection
+	offset  int
+	op      string
+	args    []string
+	line    int
+}
+
+type riscvInstruction struct {
+	op           string
+	rd, rs1, rs2 int
+	imm          int64
+	size         uint32
+	text         string
+}
+
+type riscvProgram struct {
+	mem    []byte
+	code   map[uint32]riscvInstruction
+	labels map[string]uint32
+}
+
+// Split the arguments on commas.
+func riscvArgs(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	args := strings.Split(s, ",")
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+	return args
+}
+
+func riscvAssemble(asm string) (*riscvProgram, error) {
+	items := make([]riscvItem, 0)
+	type place struct {
+		section riscvSection
+		offset  int
+	}
+	places := make(map[string]place)
+	sizes := [3]int{}
+	section := riscvText
+	for n, line := range strings.Split(asm, "\n") {
+		line = strings.TrimSpace(strings.TrimRight(line, "\r"))
+		for {
+			m := riscvLabel.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			if _, ok := places[m[1]]; ok {
+				return nil, fmt.Errorf("line %d: label %s defined twice", n+1, m[1])
+			}
+			places[m[1]] = place{section, sizes[section]}
+			line = strings.TrimSpace(line[len(m[0]):])
+		}
+		if line == "" {
+			continue
+		}
+		op, rest, _ := strings.Cut(line, " ")
+		op = strings.ToLower(op)
+		args := riscvArgs(rest)
+		size := 0
+		switch op {
+		case ".text":
+			section = riscvText
+			continue
+		case ".data":
+			section = riscvData
+			continue
+		case ".bss":
+			section = riscvBss
+			continue
+		case ".global", ".globl", ".option":
+			continue
+		case ".int", ".word":
+			size = 4 * len(args)
+		case ".balign":
+			align, err := strconv.Atoi(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			size = (align - sizes[section]%align) % align
+		case ".skip":
+			skip, err := strconv.Atoi(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			size = skip
+		case "li", "la":
+			size = 8 // lui and addi
+		default:
+			if strings.HasPrefix(op, ".") {
+				return nil, fmt.Errorf("line %d: unknown directive %s", n+1, op)
+			}
+			size = 4
+		}
+		if section == riscvBss && op != ".skip" && op != ".balign" {
+			return nil, fmt.Errorf("line %d: only space can be reserved in .bss", n+1)
+		}
+		items = append(items, riscvItem{section, sizes[section], op, args, n + 1})
+		sizes[section] += size
+	}
+	align := func(n int) int { return (n + 3) &^ 3 }
+	bases := [3]int{0, align(sizes[riscvText]), 0}
+	bases[riscvBss] = align(bases[riscvData] + sizes[riscvData])
+	if bases[riscvBss]+sizes[riscvBss] > riscvMemory {
+		return nil, fmt.Errorf("the program needs %d bytes, only %d are available", bases[riscvBss]+sizes[riscvBss], riscvMemory)
+	}
+	p := riscvProgram{
+		mem:    make([]byte, riscvMemory),
+		code:   make(map[uint32]riscvInstruction),
+		labels: make(map[string]uint32),
+	}
+	for name, pl := range places {
+		p.labels[name] = uint32(bases[pl.section] + pl.offset)
+	}
+	for _, item := range items {
+		addr := uint32(bases[item.section] + item.offset)
+		switch item.op {
+		case ".int", ".word":
+			for i, arg := range item.args {
+				v, err := p.eval(arg)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", item.line, err)
+				}
+				binary.LittleEndian.PutUint32(p.mem[addr+uint32(4*i):], uint32(v))
+			}
+		case ".balign":
+			if item.section != riscvText {
+				continue
+			}
+			align, _ := strconv.Atoi(item.args[0])
+			for ; addr%uint32(align) != 0; addr += 4 {
+				p.code[addr] = riscvInstruction{op: "addi", size: 4, text: "nop"} // fill code with nops
+			}
+		case ".skip":
+		default:
+			ins, err := p.decode(item.op, item.args)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", item.line, err)
+			}
+			p.code[addr] = ins
+		}
+	}
+	return &p, nil
+}
+
+func (p *riscvProgram) register(s string) (int, error) {
+	r, ok := riscvRegisters[s]
+	if ok {
+		return r, nil
+	}
+	if strings.HasPrefix(s, "x") {
+		r, err := strconv.Atoi(s[1:])
+		if err == nil && r >= 0 && r < 32 {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown register %s", s)
+}
+
+// Decode the arguments, each letter of the format is
+// d (rd), s (rs1), t (rs2), i (immediate), or o (offset and rs1).
+func (p *riscvProgram) operands(ins *riscvInstruction, args []string, format string) error {
+	if len(args) != len(format) {
+		return fmt.Errorf("%s expects %d arguments", ins.op, len(format))
+	}
+	var err error
+	for i, f := range format {
+		switch f {
+		case 'd':
+			ins.rd, err = p.register(args[i])
+		case 's':
+			ins.rs1, err = p.register(args[i])
+		case 't':
+			ins.rs2, err = p.register(args[i])
+		case 'i':
+			ins.imm, err = p.eval(args[i])
+		case 'o':
+			m := riscvOffset.FindStringSubmatch(args[i])
+			if m == nil {
+				return fmt.Errorf("expected an offset and register, got %s", args[i])
+			}
+			ins.imm = 0
+			if strings.TrimSpace(m[1]) != "" {
+				ins.imm, err = p.eval(m[1])
+			}
+			if err == nil {
+				ins.rs1, err = p.register(m[2])
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *riscvProgram) decode(op string, args []string) (riscvInstruction, error) {
+	ins := riscvInstruction{op: op, size: 4, text: op + " " + strings.Join(args, ", ")}
+	var err error
+	switch op {
+	case "li", "la":
+		ins.size = 8
+		err = p.operands(&ins, args, "di")
+		if err == nil && (ins.imm < -(1<<31) || ins.imm >= 1<<32) {
+			err = fmt.Errorf("%d does not fit in 32 bits", ins.imm)
+		}
+	case "lui", "auipc":
+		err = p.operands(&ins, args, "di")
+		if err == nil && (ins.imm < 0 || ins.imm >= 1<<20) {
+			err = fmt.Errorf("%d does not fit in 20 bits", ins.imm)
+		}
+	case "mv":
+		ins.op = "addi"
+		err = p.operands(&ins, args, "ds")
+	case "neg":
+		ins.op = "sub"
+		err = p.operands(&ins, args, "dt")
+	case "not":
+		ins.op = "xori"
+		ins.imm = -1
+		err = p.operands(&ins, args, "ds")
+	case "snez":
+		ins.op = "sltu"
+		err = p.operands(&ins, args, "dt")
+	case "nop":
+		ins.op = "addi"
+	case "ret":
+		ins.op = "jalr"
+		ins.rs1 = riscvRegisters["ra"]
+	case "jr":
+		ins.op = "jalr"
+		err = p.operands(&ins, args, "s")
+	case "j":
+		ins.op = "jal"
+		err = p.operands(&ins, args, "i")
+	case "jal":
+		if len(args) == 1 {
+			ins.rd = riscvRegisters["ra"]
+			err = p.operands(&ins, args, "i")
+		} else {
+			err = p.operands(&ins, args, "di")
+		}
+	case "jalr":
+		if len(args) == 1 {
+			ins.rd = riscvRegisters["ra"]
+			err = p.operands(&ins, args, "s")
+		} else {
+			err = p.operands(&ins, args, "do")
+		}
+	case "beqz", "bnez", "bltz", "bgez":
+		ins.op = map[string]string{"beqz": "beq", "bnez": "bne", "bltz": "blt", "bgez": "bge"}[op]
+		err = p.operands(&ins, args, "si")
+	case "blez", "bgtz":
+		ins.op = map[string]string{"blez": "bge", "bgtz": "blt"}[op]
+		err = p.operands(&ins, args, "ti")
+	case "beq", "bne", "blt", "bge", "bltu", "bgeu":
+		err = p.operands(&ins, args, "sti")
+	case "lw", "lh", "lhu", "lb", "lbu":
+		err = p.operands(&ins, args, "do")
+	case "sw", "sh", "sb":
+		err = p.operands(&ins, args, "to")
+	case "add", "sub", "sll", "srl", "sra", "slt", "sltu", "xor", "or", "and",
+		"mul", "mulh", "mulhu", "div", "divu", "rem", "remu":
+		err = p.operands(&ins, args, "dst")
+	case "slli", "srli", "srai":
+		err = p.operands(&ins, args, "dsi")
+		if err == nil && (ins.imm < 0 || ins.imm > 31) {
+			err = fmt.Errorf("shift of %d is out of range", ins.imm)
+		}
+	case "addi", "slti", "sltiu", "xori", "ori", "andi":
+		err = p.operands(&ins, args, "dsi")
+	default:
+		err = fmt.Errorf("unknown instruction %s", op)
+	}
+	if err != nil {
+		return ins, err
+	}
+	switch ins.op {
+	case "addi", "slti", "sltiu", "xori", "ori", "andi", "lw", "lh", "lhu", "lb", "lbu", "sw", "sh", "sb", "jalr":
+		if ins.imm < -2048 || ins.imm > 2047 {
+			return ins, fmt.Errorf("immediate %d does not fit in 12 bits", ins.imm)
+		}
+	}
+	return ins, nil
+}
+
+// Evaluate an expression with the same precedence as the GNU
+// assembler, labels are replaced with their address.
+func (p *riscvProgram) eval(s string) (int64, error) {
+	tokens := make([]string, 0)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.HasPrefix(s[i:], "<<") || strings.HasPrefix(s[i:], ">>"):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		case strings.ContainsRune("+-*/~&|^()", rune(c)):
+			tokens = append(tokens, s[i:i+1])
+			i++
+		default:
+			j := i
+			for j < len(s) && (s[j] == '_' || s[j] == '.' || s[j] == '$' ||
+				(s[j] >= '0' && s[j] <= '9') || (s[j] >= 'a' && s[j] <= 'z') || (s[j] >= 'A' && s[j] <= 'Z')) {
+				j++
+			}
+			if j == i {
+				return 0, fmt.Errorf("unexpected %q in %s", c, s)
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	e := riscvExpression{p: p, tokens: tokens}
+	v, err := e.parse(0)
+	if err == nil && e.pos != len(tokens) {
+		err = fmt.Errorf("unexpected %s in %s", tokens[e.pos], s)
+	}
+	return v, err
+}
+
+type riscvExpression struct {
+	p      *riscvProgram
+	tokens []string
+	pos    int
+}
+
+// The binary operators from lowest to highest precedence.
+var riscvOperators = [][]string{
+	{"+", "-"},
+	{"&", "|", "^"},
+	{"*", "/", "<<", ">>"},
+}
+
+func (e *riscvExpression) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos]
+	}
+	return ""
+}
+
+func (e *riscvExpression) parse(level int) (int64, error) {
+	if level == len(riscvOperators) {
+		return e.unary()
+	}
+	left, err := e.parse(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := e.peek()
+		found := false
+		for _, o := range riscvOperators[level] {
+			found = found || o == op
+		}
+		if !found {
+			return left, nil
+		}
+		e.pos++
+		right, err := e.parse(level + 1)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "+":
+			left += right
+		case "-":
+			left -= right
+		case "&":
+			left &= right
+		case "|":
+			left |= right
+		case "^":
+			left ^= right
+		case "*":
+			left *= right
+		case "/":
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		case "<<":
+			left <<= right
+		case ">>":
+			left >>= right
+		}
+	}
+}
+
+func (e *riscvExpression) unary() (int64, error) {
+	t := e.peek()
+	e.pos++
+	switch {
+	case t == "":
+		return 0, fmt.Errorf("unexpected end of expression")
+	case t == "-":
+		v, err := e.unary()
+		return -v, err
+	case t == "~":
+		v, err := e.unary()
+		return ^v, err
+	case t == "(":
+		v, err := e.parse(0)
+		if err != nil {
+			return 0, err
+		}
+		if e.peek() != ")" {
+			return 0, fmt.Errorf("expected )")
+		}
+		e.pos++
+		return v, nil
+	case t[0] >= '0' && t[0] <= '9':
+		return strconv.ParseInt(t, 0, 64)
+	}
+	addr, ok := e.p.labels[t]
+	if !ok {
+		return 0, fmt.Errorf("unknown label %s", t)
+	}
+	return int64(addr), nil
+}
+
+// Runs an assembled program, peripherals are memory outside of
+// the RTC slow memory.
+type riscvMachine struct {
+	p           *riscvProgram
+	regs        [32]uint32
+	pc          uint32
+	steps       int
+	peripherals map[uint32]uint32
+	out         strings.Builder
+	mutexPrev   uint32
+	done        bool // the ESP.DONE function was called
+	halted      bool // waiting in a loop that never ends
+}
+
+func newRiscvMachine(p *riscvProgram) *riscvMachine {
+	m := riscvMachine{p: p, peripherals: make(map[uint32]uint32)}
+	m.reset()
+	return &m
+}
+
+// Start at main, the same as a reset of the processor.
+// The memory is not changed.
+func (m *riscvMachine) reset() {
+	m.regs = [32]uint32{}
+	m.regs[riscvRegisters["ra"]] = riscvReturn
+	m.pc = m.p.labels["main"]
+	m.halted = false
+	m.done = false
+}
+
+func (m *riscvMachine) load(addr uint32, size uint32) (uint32, error) {
+	if addr%size != 0 {
+		return 0, fmt.Errorf("unaligned load of %d bytes at 0x%X", size, addr)
+	}
+	if addr+size <= uint32(len(m.p.mem)) {
+		switch size {
+		case 1:
+			return uint32(m.p.mem[addr]), nil
+		case 2:
+			return uint32(binary.LittleEndian.Uint16(m.p.mem[addr:])), nil
+		default:
+			return binary.LittleEndian.Uint32(m.p.mem[addr:]), nil
+		}
+	}
+	if addr < riscvPeripherals || size != 4 {
+		return 0, fmt.Errorf("load of %d bytes outside of memory at 0x%X", size, addr)
+	}
+	return m.peripherals[addr], nil
+}
+
+func (m *riscvMachine) store(addr uint32, size uint32, value uint32) error {
+	if addr%size != 0 {
+		return fmt.Errorf("unaligned store of %d bytes at 0x%X", size, addr)
+	}
+	if _, ok := m.p.code[addr&^3]; ok {
+		return fmt.Errorf("store of %d bytes to code at 0x%X", size, addr)
+	}
+	if addr+size <= uint32(len(m.p.mem)) {
+		switch size {
+		case 1:
+			m.p.mem[addr] = byte(value)
+		case 2:
+			binary.LittleEndian.PutUint16(m.p.mem[addr:], uint16(value))
+		default:
+			binary.LittleEndian.PutUint32(m.p.mem[addr:], value)
+		}
+		return nil
+	}
+	if addr < riscvPeripherals || size != 4 {
+		return fmt.Errorf("store of %d bytes outside of memory at 0x%X", size, addr)
+	}
+	m.peripherals[addr] = value
+	return nil
+}
+
+// Run until ESP.DONE is called, the processor is stuck in a
+// loop, or the step limit is reached.
+func (m *riscvMachine) run(maxSteps int) error {
+	for !m.done && !m.halted {
+		if m.steps >= maxSteps {
+			return fmt.Errorf("exceeded %d instructions", maxSteps)
+		}
+		err := m.step()
+		if err != nil {
+			return err
+		}
+		m.system()
+	}
+	return nil
+}
+
+// Act as the esp32 when the ULP gives the mutex.
+func (m *riscvMachine) system() {
+	flag := m.p.labels["MUTEX_FLAG0"]
+	mutex := binary.LittleEndian.Uint32(m.p.mem[flag:]) & 0xFFFF
+	if m.mutexPrev == 1 && mutex == 0 {
+		fn := m.p.labels["HOST_FUNC"]
+		param := binary.LittleEndian.Uint32(m.p.mem[m.p.labels["HOST_PARAM0"]:]) & 0xFFFF
+		switch binary.LittleEndian.Uint32(m.p.mem[fn:]) & 0xFFFF {
+		case 1:
+			m.done = true
+		case 2:
+			fmt.Fprintf(&m.out, "%d ", param)
+		case 3:
+			fmt.Fprintf(&m.out, "%c", param&0xFF)
+		}
+		binary.LittleEndian.PutUint32(m.p.mem[fn:], 0) // acknowledge it
+	}
+	m.mutexPrev = mutex
+}
+
+func (m *riscvMachine) step() error {
+	if m.pc == riscvReturn {
+		return fmt.Errorf("returned from main")
+	}
+	ins, ok := m.p.code[m.pc]
+	if !ok {
+		return fmt.Errorf("no instruction at 0x%X", m.pc)
+	}
+	m.steps++
+	r := &m.regs
+	rs1 := r[ins.rs1]
+	rs2 := r[ins.rs2]
+	imm := uint32(ins.imm)
+	next := m.pc + ins.size
+	var rd uint32
+	write := true
+	var err error
+	branch := func(taken bool) {
+		write = false
+		if taken {
+			next = imm
+		}
+	}
+	switch ins.op {
+	case "li", "la":
+		rd = imm
+	case "lui":
+		rd = imm << 12
+	case "auipc":
+		rd = m.pc + imm<<12
+	case "jal":
+		rd = next
+		if imm == m.pc {
+			m.halted = true
+		}
+		next = imm
+	case "jalr":
+		rd = next
+		next = (rs1 + imm) &^ 1
+	case "beq":
+		branch(rs1 == rs2)
+	case "bne":
+		branch(rs1 != rs2)
+	case "blt":
+		branch(int32(rs1) < int32(rs2))
+	case "bge":
+		branch(int32(rs1) >= int32(rs2))
+	case "bltu":
+		branch(rs1 < rs2)
+	case "bgeu":
+		branch(rs1 >= rs2)
+	case "lw":
+		rd, err = m.load(rs1+imm, 4)
+	case "lh":
+		rd, err = m.load(rs1+imm, 2)
+		rd = uint32(int16(rd))
+	case "lhu":
+		rd, err = m.load(rs1+imm, 2)
+	case "lb":
+		rd, err = m.load(rs1+imm, 1)
+		rd = uint32(int8(rd))
+	case "lbu":
+		rd, err = m.load(rs1+imm, 1)
+	case "sw":
+		write = false
+		err = m.store(rs1+imm, 4, rs2)
+	case "sh":
+		write = false
+		err = m.store(rs1+imm, 2, rs2)
+	case "sb":
+		write = false
+		err = m.store(rs1+imm, 1, rs2)
+	case "addi":
+		rd = rs1 + imm
+	case "slti":
+		rd = riscvBool(int32(rs1) < int32(imm))
+	case "sltiu":
+		rd = riscvBool(rs1 < imm)
+	case "xori":
+		rd = rs1 ^ imm
+	case "ori":
+		rd = rs1 | imm
+	case "andi":
+		rd = rs1 & imm
+	case "slli":
+		rd = rs1 << imm
+	case "srli":
+		rd = rs1 >> imm
+	case "srai":
+		rd = uint32(int32(rs1) >> imm)
+	case "add":
+		rd = rs1 + rs2
+	case "sub":
+		rd = rs1 - rs2
+	case "sll":
+		rd = rs1 << (rs2 & 31)
+	case "srl":
+		rd = rs1 >> (rs2 & 31)
+	case "sra":
+		rd = uint32(int32(rs1) >> (rs2 & 31))
+	case "slt":
+		rd = riscvBool(int32(rs1) < int32(rs2))
+	case "sltu":
+		rd = riscvBool(rs1 < rs2)
+	case "xor":
+		rd = rs1 ^ rs2
+	case "or":
+		rd = rs1 | rs2
+	case "and":
+		rd = rs1 & rs2
+	case "mul":
+		rd = rs1 * rs2
+	case "mulh":
+		rd = uint32(uint64(int64(int32(rs1))*int64(int32(rs2))) >> 32)
+	case "mulhu":
+		rd = uint32(uint64(rs1) * uint64(rs2) >> 32)
+	case "div":
+		switch {
+		case rs2 == 0:
+			rd = 0xFFFFFFFF
+		case int32(rs1) == -1<<31 && int32(rs2) == -1:
+			rd = rs1
+		default:
+			rd = uint32(int32(rs1) / int32(rs2))
+		}
+	case "divu":
+		rd = 0xFFFFFFFF
+		if rs2 != 0 {
+			rd = rs1 / rs2
+		}
+	case "rem":
+		switch {
+		case rs2 == 0:
+			rd = rs1
+		case int32(rs1) == -1<<31 && int32(rs2) == -1:
+			rd = 0
+		default:
+			rd = uint32(int32(rs1) % int32(rs2))
+		}
+	case "remu":
+		rd = rs1
+		if rs2 != 0 {
+			rd = rs1 % rs2
+		}
+	default:
+		return fmt.Errorf("cannot execute %s", ins.text)
+	}
+	if err != nil {
+		return fmt.Errorf("0x%X %s: %w", m.pc, ins.text, err)
+	}
+	if write && ins.rd != 0 {
+		r[ins.rd] = rd
+	}
+	m.pc = next
+	return nil
+}
+
+func riscvBool(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Assemble and run the ULP-RISC-V assembly, checking the output.
+func runRiscvTest(t *testing.T, assembly string, expected string) {
+	p, err := riscvAssemble(assembly)
+	if err != nil {
+		t.Fatalf("failed to assemble: %s", err)
+	}
+	m := newRiscvMachine(p)
+	err = m.run(riscvMaxSteps)
+	if err != nil {
+		t.Fatalf("execution failed: %s", err)
+	}
+	got := m.out.String()
+	if got != expected {
+		t.Errorf("expected \"%s\" got \"%s\"", expected, got)
+	}
+}
+
+func buildRiscv(t *testing.T, target Target, code string) *riscvProgram {
+	var buff bytes.Buffer
+	vm := VirtualMachine{Out: &buff}
+	err := vm.Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vm.BuiltinTarget(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vm.Execute([]byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ulp := Ulp{}
+	assembly, err := ulp.BuildAssemblyRiscv(&vm, "MAIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := riscvAssemble(assembly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRiscvHardware(t *testing.T) {
+	tests := []struct {
+		target Target
+		rtc    uint32 // the start of the RTC registers
+		cocpu  uint32 // the offset of RTC_CNTL_COCPU_CTRL_REG
+	}{
+		{TargetEsp32s2Riscv, 0x3F408000, 0x100},
+		{TargetEsp32s3Riscv, 0x60008000, 0x104},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target.String(), func(t *testing.T) {
+			p := buildRiscv(t, tt.target, ": MAIN GPIO12.ENABLE GPIO12.OUTPUT_ENABLE GPIO12.SET_HIGH WAKE HALT ;")
+			m := newRiscvMachine(p)
+			err := m.run(riscvMaxSteps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !m.halted {
+				t.Fatalf("expected the processor to halt")
+			}
+			expected := []struct {
+				name string
+				addr uint32
+				bits uint32
+			}{
+				{"mux", tt.rtc + 0x400 + 0x84 + 12*4, 1 << 19},
+				{"output enable", tt.rtc + 0x410, 1 << (10 + 12)},
+				{"set high", tt.rtc + 0x404, 1 << (10 + 12)},
+				{"wake", tt.rtc + 0x18, 1},
+				{"halt", tt.rtc + tt.cocpu, 1<<25 | 1<<22 | 0x3F<<14},
+			}
+			for _, e := range expected {
+				got := m.peripherals[e.addr]
+				if got&e.bits != e.bits {
+					t.Errorf("%s: expected bits 0x%X set at 0x%X, got 0x%X", e.name, e.bits, e.addr, got)
+				}
+			}
+		})
+	}
+}
+
+// The processor is reset after HALT, main continues after it.
+func TestRiscvHalt(t *testing.T) {
+	p := buildRiscv(t, TargetEsp32s2Riscv, ": MAIN 1 U. BEGIN 2 U. HALT AGAIN ;")
+	m := newRiscvMachine(p)
+	expected := []string{"1 2 ", "1 2 2 ", "1 2 2 2 "}
+	for _, e := range expected {
+		err := m.run(riscvMaxSteps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := m.out.String()
+		if got != e {
+			t.Errorf("expected \"%s\" got \"%s\"", e, got)
+		}
+		m.reset()
+	}
+}
+
+func TestRiscvAssembler(t *testing.T) {
+	p, err := riscvAssemble(".text\r\nmain: li a0, ~(((1<<4)-1)<<2)\r\nj main\r\n.data\r\nx: .int main+4, 2*3+1, 16192*65536+33796")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.code[0].imm; got != ^int64(0x3C) {
+		t.Errorf("expected li of %d got %d", ^int64(0x3C), got)
+	}
+	x := p.labels["x"]
+	for i, e := range []uint32{4, 7, 0x3F408404} {
+		got := binary.LittleEndian.Uint32(p.mem[x+uint32(4*i):])
+		if got != e {
+			t.Errorf("expected 0x%X got 0x%X", e, got)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The chip that the ULP code runs on.
+type Target int
+
+const (
+	TargetEsp32 Target = iota
+	TargetEsp32s2
+	TargetEsp32s3
+	TargetEsp32s2Riscv
+	TargetEsp32s3Riscv
+)
+
+type targetInfo struct {
+	name      string
+	libraries []string // the embedded directories that are run, in order of the file names
+	riscv     bool     // the code runs on the ULP-RISC-V instead of the ULP-FSM
+}
+
+var targets = []targetInfo{
+	TargetEsp32:        {"esp32", []string{"ulp", "ulp_fsm", "esp32"}, false},
+	TargetEsp32s2:      {"esp32s2", []string{"ulp", "ulp_fsm", "ulp_fsm_sx", "esp32s2", "esp32sx"}, false},
+	TargetEsp32s3:      {"esp32s3", []string{"ulp", "ulp_fsm", "ulp_fsm_sx", "esp32s3", "esp32sx"}, false},
+	TargetEsp32s2Riscv: {"esp32s2-riscv", []string{"ulp", "ulp_riscv", "esp32s2", "esp32sx"}, true},
+	TargetEsp32s3Riscv: {"esp32s3-riscv", []string{"ulp", "ulp_riscv", "esp32s3", "esp32sx"}, true},
+}
+
+func (t Target) String() string {
+	if int(t) < 0 || int(t) >= len(targets) {
+		return fmt.Sprintf("Target(%d)", int(t))
+	}
+	return targets[t].name
+}
+
+// Check if the target uses the ULP-RISC-V coprocessor.
+func (t Target) Riscv() bool {
+	return int(t) >= 0 && int(t) < len(targets) && targets[t].riscv
+}
+
+// The names of all targets, for help messages.
+func TargetNames() []string {
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.name
+	}
+	return names
+}
+
+// Find the target with the given name.
+func ParseTarget(name string) (Target, error) {
+	for i, t := range targets {
+		if strings.EqualFold(name, t.name) {
+			return Target(i), nil
+		}
+	}
+	return TargetEsp32, fmt.Errorf("unknown target %s, expected one of %s", name, strings.Join(TargetNames(), ", "))
+}
+
+// The parts of the esp32 ULP instructions that are
+// encoded differently on the esp32-s2 and esp32-s3.
+const (
+	fsmOpStore  = 6
+	fsmOpBranch = 8
+	fsmSubStore = 4 // st
+
+	fsmSubJumpR     = 1 // jumpr, 3 bits on the esp32 and 2 bits on the others
+	fsmSubJumpStage = 2 // jumps, 3 bits on the esp32 and 2 bits on the others
+
+	fsmCondLt   = 0
+	fsmCondGe   = 1 // esp32 only
+	fsmCondLe   = 2 // esp32 jumps only
+	fsmCondGtS2 = 1 // esp32-s2 and esp32-s3 only
+
+	fsmStoreLowS2 = 3 // st writes only the lower 16 bits
+)
+
+// Change the code in a ULP binary built for the esp32
+// to the instructions used by the target.
+func (t Target) TranslateBinary(bin []byte) ([]byte, error) {
+	if t == TargetEsp32 {
+		return bin, nil
+	}
+	if t.Riscv() {
+		return nil, fmt.Errorf("the %s target does not use ULP-FSM binaries", t)
+	}
+	if len(bin) < 12 {
+		return nil, fmt.Errorf("the ULP binary is too short")
+	}
+	textAddr := int(bin[4]) | int(bin[5])<<8
+	textSize := int(bin[6]) | int(bin[7])<<8
+	if textAddr+textSize > len(bin) {
+		return nil, fmt.Errorf("the ULP binary is too short for its code")
+	}
+	out := slices.Clone(bin)
+	err := t.translate(out[textAddr : textAddr+textSize])
+	return out, err
+}
+
+// Change the code in the assembly created by the assembler
+// for the esp32 to the instructions used by the target.
+// Every instruction is written as bytes in the .text section.
+func (t Target) TranslateAssembly(asm []byte) ([]byte, error) {
+	if t == TargetEsp32 {
+		return asm, nil
+	}
+	if t.Riscv() {
+		return nil, fmt.Errorf("the %s target does not use ULP-FSM assembly", t)
+	}
+	lines := strings.Split(string(asm), "\n")
+	code := make([]byte, 0)
+	codeLines := make([]int, 0) // the line of each byte of code
+	text := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == ".text":
+			text = true
+		case trimmed == ".data" || trimmed == ".bss":
+			text = false
+		case text && strings.HasPrefix(trimmed, ".byte "):
+			b, err := strconv.ParseUint(strings.TrimPrefix(trimmed, ".byte "), 0, 8)
+			if err != nil {
+				return nil, err
+			}
+			code = append(code, byte(b))
+			codeLines = append(codeLines, i)
+		}
+	}
+	err := t.translate(code)
+	if err != nil {
+		return nil, err
+	}
+	for i, b := range code {
+		lines[codeLines[i]] = fmt.Sprintf("    .byte 0x%02X", b)
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+func (t Target) translate(code []byte) error {
+	if len(code)%4 != 0 {
+		return fmt.Errorf("the ULP code is not a whole number of instructions")
+	}
+	for i := 0; i < len(code); i += 4 {
+		ins := binary.LittleEndian.Uint32(code[i:])
+		binary.LittleEndian.PutUint32(code[i:], translateS2(ins, i/4))
+	}
+	return nil
+}
+
+// Change an esp32 instruction at the word address pc to the
+// esp32-s2 and esp32-s3 encoding. The relative jumps have a
+// smaller sub opcode and a larger condition. The conditions
+// "greater than or equal" and "less than or equal" are changed
+// to "greater than" and "less than", a condition that is always
+// true is changed to a jump to the same address.
+func translateS2(ins uint32, pc int) uint32 {
+	op := ins >> 28
+	sub := (ins >> 25) & 0b111
+	// the relative jumps on the esp32 have the step in bits 17 to 24
+	step := (ins >> 17) & 0xFF
+	jumpTo := func() uint32 {
+		offset := int(step & 0x7F)
+		if step&0x80 != 0 {
+			offset = -offset
+		}
+		target := uint32(pc+offset) & 0x7FF
+		return fsmOpBranch<<28 | target<<2
+	}
+	relative := func(sub uint32, cond uint32, threshold uint32) uint32 {
+		return fsmOpBranch<<28 | sub<<26 | step<<18 | cond<<16 | threshold
+	}
+	switch {
+	case op == fsmOpStore && sub == fsmSubStore:
+		return ins | fsmStoreLowS2<<7
+	case op == fsmOpBranch && sub == fsmSubJumpR:
+		threshold := ins & 0xFFFF
+		switch {
+		case (ins>>16)&1 == fsmCondLt:
+			return relative(fsmSubJumpR, fsmCondLt, threshold)
+		case threshold == 0:
+			return jumpTo() // r0 >= 0
+		default:
+			return relative(fsmSubJumpR, fsmCondGtS2, threshold-1)
+		}
+	case op == fsmOpBranch && sub == fsmSubJumpStage:
+		threshold := ins & 0xFF
+		switch (ins >> 15) & 0b11 {
+		case fsmCondLt:
+			return relative(fsmSubJumpStage, fsmCondLt, threshold)
+		case fsmCondGe:
+			if threshold == 0 {
+				return jumpTo() // stage >= 0
+			}
+			return relative(fsmSubJumpStage, fsmCondGtS2, threshold-1)
+		case fsmCondLe:
+			if threshold == 0xFF {
+				return jumpTo() // stage <= 255
+			}
+			return relative(fsmSubJumpStage, fsmCondLt, threshold+1)
+		}
+	}
+	return ins
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Molorius/ulp-c/pkg/asm"
+)
+
+func TestParseTarget(t *testing.T) {
+	for _, name := range TargetNames() {
+		target, err := ParseTarget(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target.String() != name {
+			t.Errorf("expected %s got %s", name, target)
+		}
+	}
+	_, err := ParseTarget("esp8266")
+	if err == nil {
+		t.Errorf("expected an error for an unknown target")
+	}
+}
+
+func TestTargetLibraries(t *testing.T) {
+	code := `
+		: MAIN
+			GPIO2.ENABLE GPIO2.OUTPUT_ENABLE
+			GPIO2.GET GPIO2.SET
+			RTC_CLOCK 2DROP
+			10 DELAY_MS
+			WAKE
+		;
+	`
+	for _, name := range TargetNames() {
+		t.Run(name, func(t *testing.T) {
+			target, err := ParseTarget(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buff bytes.Buffer
+			vm := VirtualMachine{Out: &buff}
+			err = vm.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = vm.BuiltinTarget(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buff.Len() != 0 {
+				t.Errorf("expected the libraries to load quietly, got %q", buff.String())
+			}
+			err = vm.Execute([]byte(code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ulp := Ulp{}
+			if target.Riscv() {
+				assembly, err := ulp.BuildAssemblyRiscv(&vm, "MAIN")
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = riscvAssemble(assembly)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			assembly, err := ulp.BuildAssembly(&vm, "MAIN")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assembler := asm.Assembler{}
+			bin, err := assembler.BuildFile(assembly, "forth.S", 8176, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = target.TranslateBinary(bin)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestTargetRedefine(t *testing.T) {
+	for _, name := range TargetNames() {
+		t.Run(name, func(t *testing.T) {
+			target, err := ParseTarget(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buff bytes.Buffer
+			vm := VirtualMachine{Out: &buff}
+			err = vm.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = vm.BuiltinTarget(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the user's own definitions still warn
+			err = vm.Execute([]byte(": HALT ;"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buff.String(), "Redefining HALT") {
+				t.Errorf("expected a redefinition warning, got %q", buff.String())
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	code := `
+		.boot
+		entry:
+		st r0, r1, 2
+		jump entry
+		jumpr entry, 5, lt
+		jumpr entry, 5, ge
+		jumpr entry, 0, ge
+		jumps entry, 3, lt
+		jumps entry, 3, le
+		jumps entry, 3, ge
+		jumps entry, 0, ge
+		jumps entry, 255, le
+		jumpr end, 7, lt
+		halt
+		end:
+		halt
+	`
+	// a relative jump on the esp32-s2 and esp32-s3
+	relative := func(sub uint32, step int, cond uint32, threshold uint32) uint32 {
+		s := uint32(step)
+		if step < 0 {
+			s = uint32(-step) | 0x80
+		}
+		return 8<<28 | sub<<26 | s<<18 | cond<<16 | threshold
+	}
+	jump := 8 << 28 // jump to 0
+	expected := []uint32{
+		6<<28 | 4<<25 | 2<<10 | 1<<2 | 3<<7, // st writes the lower 16 bits
+		uint32(jump),
+		relative(1, -2, 0, 5),
+		relative(1, -3, 1, 4),
+		uint32(jump),
+		relative(2, -5, 0, 3),
+		relative(2, -6, 0, 4),
+		relative(2, -7, 1, 2),
+		uint32(jump),
+		uint32(jump),
+		relative(1, 2, 0, 7),
+		11 << 28,
+		11 << 28,
+	}
+	assembler := asm.Assembler{}
+	bin, err := assembler.BuildFile(code, "test.S", 8176, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []Target{TargetEsp32s2, TargetEsp32s3} {
+		out, err := target.TranslateBinary(bin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, e := range expected {
+			got := binary.LittleEndian.Uint32(out[12+i*4:])
+			if got != e {
+				t.Errorf("%s instruction %d: expected 0x%08x got 0x%08x", target, i, e, got)
+			}
+		}
+		// the assembly output has the same code
+		assembly, err := assembler.BuildAssembly(code, "test.S", 8176, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		translated, err := target.TranslateAssembly(assembly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := make([]byte, 0)
+		text := false
+		for _, line := range strings.Split(string(translated), "\n") {
+			line = strings.TrimSpace(line)
+			switch {
+			case line == ".text":
+				text = true
+			case line == ".data":
+				text = false
+			case text && strings.HasPrefix(line, ".byte "):
+				b, err := strconv.ParseUint(strings.TrimPrefix(line, ".byte "), 0, 8)
+				if err != nil {
+					t.Fatal(err)
+				}
+				code = append(code, byte(b))
+			}
+		}
+		if !bytes.Equal(code, out[12:12+len(code)]) {
+			t.Errorf("%s: the translated assembly does not match the translated binary", target)
+		}
+	}
+	// the esp32 is not changed
+	out, err := TargetEsp32.TranslateBinary(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bin) {
+		t.Errorf("expected the esp32 binary to not change")
+	}
+}
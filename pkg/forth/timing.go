@@ -0,0 +1,653 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The number of cycles each instruction takes, including
+// the fetch. These match the ulp-c emulator, which is not
+// cycle accurate, so the timings are only estimates.
+// Also used by the simulator to count cycles.
+var InstructionCycles = map[string]int{
+	"add":       4,
+	"sub":       4,
+	"and":       4,
+	"or":        4,
+	"lsh":       4,
+	"rsh":       4,
+	"move":      4,
+	"stage_rst": 4,
+	"stage_inc": 4,
+	"stage_dec": 4,
+	"ld":        8,
+	"st":        8,
+	"jump":      4,
+	"jumpr":     4,
+	"jumps":     4,
+	"reg_rd":    8,
+	"reg_wr":    12,
+	"wait":      6, // plus the number of cycles to wait
+	"wake":      85,
+	"halt":      6,
+	"sleep":     6,
+}
+
+// The cycles of an instruction that depends on a peripheral,
+// such as the adc or i2c. It takes at least as long as the
+// fastest instruction.
+var unknownCycles = Cycles{Best: 4}
+
+// The number of ULP cycles taken to run some code.
+type Cycles struct {
+	Best    int  // the fewest cycles
+	Worst   int  // the most cycles, if bounded
+	Bounded bool // the most cycles is known
+}
+
+func exactCycles(n int) Cycles {
+	return Cycles{Best: n, Worst: n, Bounded: true}
+}
+
+func (c Cycles) add(o Cycles) Cycles {
+	return Cycles{
+		Best:    c.Best + o.Best,
+		Worst:   c.Worst + o.Worst,
+		Bounded: c.Bounded && o.Bounded,
+	}
+}
+
+func (c Cycles) String() string {
+	switch {
+	case !c.Bounded:
+		return fmt.Sprintf("at least %d", c.Best)
+	case c.Best == c.Worst:
+		return fmt.Sprintf("%d", c.Best)
+	default:
+		return fmt.Sprintf("%d to %d", c.Best, c.Worst)
+	}
+}
+
+// The estimated time to run a cross compiled word.
+type WordTiming struct {
+	Entry  *DictionaryEntry
+	Cycles Cycles       // the cycles to run the word once, including the words it calls
+	Exits  bool         // the word can finish, otherwise the cycles are unknown
+	Loops  []LoopTiming // the loops within the word
+}
+
+// The estimated time to run a loop once.
+type LoopTiming struct {
+	Start  string // where the loop starts
+	Cycles Cycles // the cycles for one time through the loop
+}
+
+// A piece of code in the timing graph.
+type timingNode struct {
+	cycles Cycles // the cycles to run this node
+	next   []int  // the nodes that can run after this one, -1 exits
+}
+
+type timingGraph []timingNode
+
+// Find the cycles to run from node start until a path ends.
+// Paths end when they go to node end or, if exits is set,
+// when they exit. Returns false if no path ends.
+func (g timingGraph) solve(start int, end int, exits bool) (Cycles, bool) {
+	// the fewest cycles, nodes are updated until nothing changes
+	const unreachable = math.MaxInt / 2
+	best := make([]int, len(g))
+	for i := range best {
+		best[i] = unreachable
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, n := range g {
+			b := unreachable
+			for _, s := range n.next {
+				switch {
+				case s == end:
+					b = 0
+				case s == -1:
+					if exits {
+						b = 0
+					}
+				default:
+					b = min(b, best[s])
+				}
+			}
+			if b != unreachable && n.cycles.Best+b < best[i] {
+				best[i] = n.cycles.Best + b
+				changed = true
+			}
+		}
+	}
+	if best[start] == unreachable {
+		return Cycles{}, false
+	}
+
+	// the most cycles, a loop that doesn't go through the end is unbounded
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make([]int, len(g))
+	worst := make([]Cycles, len(g))
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = active
+		n := g[i]
+		w := Cycles{Bounded: n.cycles.Bounded}
+		for _, s := range n.next {
+			switch {
+			case s == end:
+			case s == -1:
+			case state[s] == active:
+				w.Bounded = false
+			default:
+				if state[s] == unvisited {
+					visit(s)
+				}
+				if best[s] != unreachable { // only paths that end
+					w.Worst = max(w.Worst, worst[s].Worst)
+					w.Bounded = w.Bounded && worst[s].Bounded
+				}
+			}
+		}
+		w.Worst += n.cycles.Worst
+		worst[i] = w
+		state[i] = done
+	}
+	visit(start)
+	if !worst[start].Bounded {
+		return Cycles{Best: best[start]}, true
+	}
+	return Cycles{Best: best[start], Worst: worst[start].Worst, Bounded: true}, true
+}
+
+// Find the start of every loop that can be reached from node start.
+func (g timingGraph) loops(start int) []int {
+	state := make([]int, len(g)) // 0 unvisited, 1 active, 2 done
+	isLoop := make([]bool, len(g))
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = 1
+		for _, s := range g[i].next {
+			if s == -1 {
+				continue
+			}
+			switch state[s] {
+			case 0:
+				visit(s)
+			case 1:
+				isLoop[s] = true
+			}
+		}
+		state[i] = 2
+	}
+	visit(start)
+	loops := make([]int, 0)
+	for i, l := range isLoop {
+		if l {
+			loops = append(loops, i)
+		}
+	}
+	return loops
+}
+
+// Estimates the number of cycles taken by the cross compiled
+// words. Assembly is timed by following every path through
+// it, forth words by following every path through their cells
+// and adding the time of the interpreter or subroutine glue.
+type Timer struct {
+	u          *Ulp
+	header     []peepholeLine            // the interpreter assembly
+	exits      map[string]Cycles         // the cycles after jumping out of assembly words
+	primitives map[*WordPrimitive]Cycles // the cycles of each assembly word
+	forth      map[*WordForth]Cycles     // the cycles of each forth word
+	active     map[*WordForth]bool       // the forth words currently being timed
+	glue       map[string]Cycles         // the cycles of parts of the interpreter
+}
+
+// Time every word compiled by the last build.
+func (u *Ulp) Timing() ([]WordTiming, error) {
+	if u.mixed != nil {
+		return nil, fmt.Errorf("cycle counts are not known for mixed threading")
+	}
+	t := Timer{u: u}
+	err := t.setup()
+	if err != nil {
+		return nil, err
+	}
+	timings := make([]WordTiming, 0, len(u.assemblyWords)+len(u.forthWords))
+	for _, w := range u.assemblyWords {
+		timing, err := t.primitiveTiming(w)
+		if err != nil {
+			return nil, err
+		}
+		timings = append(timings, timing)
+	}
+	for _, w := range u.forthWords {
+		timing, err := t.forthTiming(w)
+		if err != nil {
+			return nil, err
+		}
+		timings = append(timings, timing)
+	}
+	return timings, nil
+}
+
+func (t *Timer) setup() error {
+	t.primitives = make(map[*WordPrimitive]Cycles)
+	t.forth = make(map[*WordForth]Cycles)
+	t.active = make(map[*WordForth]bool)
+	t.exits = make(map[string]Cycles)
+	t.glue = make(map[string]Cycles)
+	var err error
+	switch t.u.compileTarget {
+	case UlpCompileTargetToken:
+		t.header = parseTimingLines(t.u.buildInterpreter())
+		// every cell is timed from __next_skip_load
+		// so add the time to get there
+		t.exits["next"], err = t.headerCycles("next", "__next_skip_load")
+		if err != nil {
+			return err
+		}
+		t.exits["__next_skip_r2"], err = t.headerCycles("__next_skip_r2", "__next_skip_load")
+		if err != nil {
+			return err
+		}
+		t.exits["__next_skip_load"] = exactCycles(0)
+		// the time to decode each type of cell
+		glue := []struct {
+			name   string
+			follow []string
+		}{
+			{"asm", nil},
+			{"forth", []string{"__ins_forth"}},
+			{"num", []string{"__ins_forth", "__ins_num"}},
+			{"branch0", []string{"__ins_forth", "__ins_num", "__ins_branch0"}},
+			{"nobranch0", []string{"__ins_forth", "__ins_num", "__ins_branch0", "__next_skip_load"}},
+			{"branch", []string{"__ins_forth", "__ins_num", "__ins_branch0", "__ins_branch"}},
+		}
+		for _, g := range glue {
+			t.glue[g.name], err = t.headerCycles("__next_skip_load", "__next_skip_load", g.follow...)
+			if err != nil {
+				return err
+			}
+		}
+	case UlpCompileTargetDirect:
+		t.header = parseTimingLines(t.u.buildInterpreter())
+		t.exits["next"], err = t.headerCycles("next", "__next_skip_load")
+		if err != nil {
+			return err
+		}
+		t.exits["__next_skip_r2"], err = t.headerCycles("__next_skip_r2", "__next_skip_load")
+		if err != nil {
+			return err
+		}
+		t.exits["__next_skip_load"] = exactCycles(0)
+		// every cell is loaded then jumped to
+		t.glue["asm"], err = t.headerCycles("__next_skip_load", "")
+		if err != nil {
+			return err
+		}
+		glue := []struct {
+			name   string
+			start  string
+			follow []string
+		}{
+			{"forth", "__docol", nil},
+			{"num", "__push", nil},
+			{"branch0", "__branch0", []string{"__branch"}},
+			{"nobranch0", "__branch0", nil},
+			{"branch", "__branch", nil},
+		}
+		for _, g := range glue {
+			c, err := t.headerCycles(g.start, "__next_skip_load", g.follow...)
+			if err != nil {
+				return err
+			}
+			t.glue[g.name] = t.glue["asm"].add(c)
+		}
+		// the "jump __docol" at the start of the word
+		t.glue["forth"] = t.glue["forth"].add(exactCycles(InstructionCycles["jump"]))
+		// the "move r0" and "jump __push" of the literal
+		t.glue["num"] = t.glue["num"].add(exactCycles(InstructionCycles["move"] + InstructionCycles["jump"]))
+	case UlpCompileTargetSubroutine:
+		t.header = parseTimingLines(t.u.buildInterpreterSrt())
+		glue := []struct {
+			name   string
+			start  string
+			follow []string
+		}{
+			{"forth", "__docol", nil},
+			{"num", "__add_to_stack", nil},
+			{"branch0", "__branch_if", []string{"__branch_if.0"}},
+			{"nobranch0", "__branch_if", nil},
+		}
+		for _, g := range glue {
+			t.glue[g.name], err = t.headerCycles(g.start, "", g.follow...)
+			if err != nil {
+				return err
+			}
+		}
+		// the "jump __docol" at the start of the word
+		t.glue["forth"] = t.glue["forth"].add(exactCycles(InstructionCycles["jump"]))
+	case UlpCompileTargetRiscv:
+		return fmt.Errorf("cycle counts are only known for the ULP-FSM")
+	default:
+		return fmt.Errorf("unknown compile target %d, please file a bug report", t.u.compileTarget)
+	}
+	return nil
+}
+
+// Check if the cells are run by an interpreter,
+// instead of being compiled into subroutine calls.
+func (u *Ulp) threaded() bool {
+	return u.compileTarget == UlpCompileTargetToken || u.compileTarget == UlpCompileTargetDirect
+}
+
+func parseTimingLines(asm string) []peepholeLine {
+	text := strings.Split(strings.ReplaceAll(asm, "\r\n", "\n"), "\n")
+	lines := make([]peepholeLine, 0, len(text))
+	for _, s := range text {
+		if peepholeMarker(s) {
+			continue // not an instruction, the peephole may not have run
+		}
+		lines = append(lines, parsePeepholeLine(s))
+	}
+	return lines
+}
+
+func findTimingLabel(lines []peepholeLine, label string) int {
+	for i, l := range lines {
+		for _, name := range l.labels {
+			if name == label {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Find the cycles to run the interpreter from one label until
+// another, or until leaving the interpreter if until is empty.
+// Conditional jumps are only taken if the destination is in follow.
+func (t *Timer) headerCycles(from string, until string, follow ...string) (Cycles, error) {
+	followed := make(map[string]bool)
+	for _, f := range follow {
+		followed[f] = true
+	}
+	g := asmGraph(t.header, followed, nil)
+	start := findTimingLabel(t.header, from)
+	end := -1
+	if until != "" {
+		end = findTimingLabel(t.header, until)
+	}
+	if start == -1 || (until != "" && end == -1) {
+		return Cycles{}, fmt.Errorf("could not find the interpreter labels %s and %s, please file a bug report", from, until)
+	}
+	c, ok := g.solve(start, end, true)
+	if !ok {
+		return Cycles{}, fmt.Errorf("could not time the interpreter from %s to %s, please file a bug report", from, until)
+	}
+	return c, nil
+}
+
+// Create the timing graph of assembly, with a node for every line.
+// A jump to a label outside of the assembly exits, adding the
+// cycles in exits for that label. If follow is not nil then
+// conditional jumps are only taken to the labels in follow.
+func asmGraph(lines []peepholeLine, follow map[string]bool, exits map[string]Cycles) timingGraph {
+	labels := make(map[string]int)
+	for i, l := range lines {
+		for _, label := range l.labels {
+			labels[label] = i
+		}
+	}
+	g := make(timingGraph, len(lines))
+	// jump to the destination, or exit through an extra node
+	dest := func(label string) int {
+		i, ok := labels[label]
+		if ok {
+			return i
+		}
+		extra, ok := exits[label]
+		if !ok {
+			return -1
+		}
+		g = append(g, timingNode{cycles: extra, next: []int{-1}})
+		return len(g) - 1
+	}
+	for i, l := range lines {
+		next := i + 1
+		if next == len(lines) {
+			next = -1
+		}
+		n := timingNode{cycles: exactCycles(0), next: []int{next}}
+		if l.op == "" || l.directive {
+			g[i] = n
+			continue
+		}
+		cycles, ok := InstructionCycles[l.op]
+		if ok {
+			n.cycles = exactCycles(cycles)
+		} else {
+			n.cycles = unknownCycles
+		}
+		switch l.op {
+		case "wait":
+			if len(l.args) == 1 {
+				wait, err := strconv.ParseInt(l.args[0], 0, 32)
+				if err != nil {
+					n.cycles.Bounded = false
+				} else {
+					n.cycles = n.cycles.add(exactCycles(int(wait)))
+				}
+			}
+		case "halt":
+			n.next = []int{-1}
+		case "jump", "jumpr", "jumps":
+			if len(l.args) == 0 {
+				break
+			}
+			target := -1 // a register jump leaves the assembly
+			if !isRegister(l.args[0]) {
+				target = dest(l.args[0])
+			}
+			conditional := (l.op == "jump" && len(l.args) > 1) || l.op != "jump"
+			switch {
+			case !conditional:
+				n.next = []int{target}
+			case follow == nil:
+				n.next = []int{next, target}
+			case follow[l.args[0]]:
+				n.next = []int{target}
+			}
+		}
+		g[i] = n
+	}
+	return g
+}
+
+// Time the assembly that a word compiles to.
+func (t *Timer) asmTiming(asm string) (Cycles, []LoopTiming, bool) {
+	lines := parseTimingLines(asm)
+	g := asmGraph(lines, nil, t.exits)
+	if len(g) == 0 {
+		return exactCycles(0), nil, true
+	}
+	c, ok := g.solve(0, -1, true)
+	loops := make([]LoopTiming, 0)
+	for _, l := range g.loops(0) {
+		iteration, _ := g.solve(l, l, false)
+		name := fmt.Sprintf("line %d", l)
+		if len(lines[l].labels) != 0 {
+			name = lines[l].labels[0]
+		}
+		loops = append(loops, LoopTiming{Start: name, Cycles: iteration})
+	}
+	return c, loops, ok
+}
+
+func (t *Timer) primitiveTiming(w *WordPrimitive) (WordTiming, error) {
+	asm, err := w.BuildAssembly(t.u)
+	if err != nil {
+		return WordTiming{}, err
+	}
+	c, loops, ok := t.asmTiming(asm)
+	if !ok {
+		c = Cycles{} // never leaves the word
+	}
+	t.primitives[w] = c
+	return WordTiming{Entry: w.Entry, Cycles: c, Exits: ok, Loops: loops}, nil
+}
+
+func (t *Timer) primitiveCycles(w *WordPrimitive) (Cycles, error) {
+	c, ok := t.primitives[w]
+	if ok {
+		return c, nil
+	}
+	timing, err := t.primitiveTiming(w)
+	return timing.Cycles, err
+}
+
+func (t *Timer) forthTiming(w *WordForth) (WordTiming, error) {
+	t.active[w] = true
+	defer delete(t.active, w)
+	g, err := t.forthGraph(w)
+	if err != nil {
+		return WordTiming{}, err
+	}
+	timing := WordTiming{Entry: w.Entry, Exits: true}
+	if len(g) == 0 {
+		return timing, nil
+	}
+	timing.Cycles, timing.Exits = g.solve(0, -1, true)
+	for _, l := range g.loops(0) {
+		iteration, _ := g.solve(l, l, false)
+		timing.Loops = append(timing.Loops, LoopTiming{
+			Start:  fmt.Sprintf("position %d", l),
+			Cycles: iteration,
+		})
+	}
+	t.forth[w] = timing.Cycles
+	return timing, nil
+}
+
+func (t *Timer) forthCycles(w *WordForth) (Cycles, error) {
+	c, ok := t.forth[w]
+	if ok {
+		return c, nil
+	}
+	if t.active[w] {
+		return Cycles{}, nil // recursive, the worst case is unknown
+	}
+	timing, err := t.forthTiming(w)
+	return timing.Cycles, err
+}
+
+// Create the timing graph of a forth word, with a node for every cell.
+func (t *Timer) forthGraph(w *WordForth) (timingGraph, error) {
+	if w.Entry.Flag.Data {
+		return nil, nil
+	}
+	dests := make(map[*CellDestination]int)
+	for i, c := range w.Cells {
+		if dest, ok := c.(*CellDestination); ok {
+			dests[dest] = i
+		}
+	}
+	destination := func(dest *CellDestination) int {
+		i, ok := dests[dest]
+		if !ok {
+			return -1 // outside of this word
+		}
+		return i
+	}
+	g := make(timingGraph, len(w.Cells))
+	stale := false // r2 needs to be restored before the next call
+	for i, c := range w.Cells {
+		next := i + 1
+		if next == len(w.Cells) {
+			next = -1
+		}
+		asm, err := c.BuildExecution(t.u)
+		if err != nil {
+			return nil, err
+		}
+		own, _, _ := t.asmTiming(asm)
+		n := timingNode{cycles: own, next: []int{next}}
+		if t.u.compileTarget == UlpCompileTargetSubroutine {
+			if stale && srtNeedsAddress(c) {
+				n.cycles = n.cycles.add(exactCycles(InstructionCycles["move"]))
+			}
+			switch c.(type) {
+			case *CellInline:
+				stale = true
+			case *CellDestination:
+			default:
+				stale = false
+			}
+		}
+		switch cell := c.(type) {
+		case CellLiteral:
+			n.cycles = n.cycles.add(t.glue["num"])
+		case CellAddress:
+			var callee Cycles
+			switch word := cell.Entry.Word.(type) {
+			case *WordPrimitive:
+				callee, err = t.primitiveCycles(word)
+				if t.u.threaded() {
+					callee = callee.add(t.glue["asm"])
+				}
+				if cell.Entry.Flag.isExit {
+					n.next = []int{-1}
+				}
+			case *WordForth:
+				callee, err = t.forthCycles(word)
+				callee = callee.add(t.glue["forth"])
+			}
+			if err != nil {
+				return nil, err
+			}
+			if cell.Offset != 0 {
+				callee = Cycles{} // only part of the word runs
+			}
+			n.cycles = n.cycles.add(callee)
+		case *CellTailCall:
+			callee, err := t.forthCycles(cell.dest)
+			if err != nil {
+				return nil, err
+			}
+			if t.u.threaded() {
+				callee = callee.add(t.glue["branch"])
+			}
+			n.cycles = n.cycles.add(callee)
+			n.next = []int{-1}
+		case *CellBranch:
+			if t.u.threaded() {
+				n.cycles = n.cycles.add(t.glue["branch"])
+			}
+			n.next = []int{destination(cell.dest)}
+		case *CellBranch0:
+			// each direction takes a different amount of time
+			g = append(g, timingNode{cycles: t.glue["nobranch0"], next: []int{next}})
+			g = append(g, timingNode{cycles: t.glue["branch0"], next: []int{destination(cell.dest)}})
+			n.next = []int{len(g) - 2, len(g) - 1}
+		}
+		g[i] = n
+	}
+	return g, nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTimingAssembly(t *testing.T) {
+	tests := []struct {
+		name   string
+		asm    []string
+		expect Cycles
+		loop   Cycles // the cycles of the first loop, if any
+	}{
+		{
+			name:   "straight",
+			asm:    []string{"move r0, 1", "add r0, r0, 1", "st r0, r3, 0"},
+			expect: exactCycles(16),
+		},
+		{
+			name:   "branch",
+			asm:    []string{"jumpr __a, 1, lt", "wait 10", "__a:", "add r0, r0, 1"},
+			expect: Cycles{Best: 8, Worst: 24, Bounded: true},
+		},
+		{
+			name:   "register jump",
+			asm:    []string{"add r2, r2, 1", "jump r2", "halt"},
+			expect: exactCycles(8),
+		},
+		{
+			name:   "loop",
+			asm:    []string{"__l:", "sub r0, r0, 1", "jumpr __l, 0, gt", "reg_wr 257, 28, 28, 1"},
+			expect: Cycles{Best: 20},
+			loop:   exactCycles(8),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timer := Timer{}
+			got, loops, ok := timer.asmTiming(strings.Join(tt.asm, "\n"))
+			if !ok {
+				t.Fatal("expected the assembly to exit")
+			}
+			if got != tt.expect {
+				t.Errorf("expected %v got %v", tt.expect, got)
+			}
+			if tt.loop.Best == 0 {
+				if len(loops) != 0 {
+					t.Errorf("expected no loops, got %v", loops)
+				}
+				return
+			}
+			if len(loops) != 1 || loops[0].Cycles != tt.loop {
+				t.Errorf("expected a loop of %v got %v", tt.loop, loops)
+			}
+		})
+	}
+}
+
+func TestTimingSerial(t *testing.T) {
+	builds := []func(*Ulp, *VirtualMachine, string) (string, error){
+		(*Ulp).BuildAssembly,
+		(*Ulp).BuildAssemblySrt,
+		(*Ulp).BuildAssemblyDirect,
+	}
+	for _, build := range builds {
+		var buff bytes.Buffer
+		vm := VirtualMachine{Out: &buff}
+		err := vm.Setup()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vm.BuiltinEsp32()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vm.Execute([]byte("4 100 SERIAL.WRITE_CREATE SW : MAIN 65 SW ;"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ulp := Ulp{}
+		_, err = build(&ulp, &vm, "MAIN")
+		if err != nil {
+			t.Fatal(err)
+		}
+		timings, err := ulp.Timing()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, timing := range timings {
+			switch timing.Entry.Name {
+			case "SW":
+				found = true
+				// the time between writing each bit is 42 cycles plus the wait
+				if len(timing.Loops) != 1 || timing.Loops[0].Cycles != exactCycles(142) {
+					t.Errorf("expected one loop of 142 cycles, got %v", timing.Loops)
+				}
+			case "VM.INIT":
+				if timing.Exits {
+					t.Errorf("VM.INIT should never exit")
+				}
+			}
+		}
+		if !found {
+			t.Errorf("could not find the timing for SW")
+		}
+	}
+}
+
+func TestTimingPrimitive(t *testing.T) {
+	builds := []func(*Ulp, *VirtualMachine, string) (string, error){
+		(*Ulp).BuildAssembly,
+		(*Ulp).BuildAssemblySrt,
+	}
+	for _, build := range builds {
+		var buff bytes.Buffer
+		vm := VirtualMachine{Out: &buff}
+		err := vm.Setup()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vm.Execute([]byte(": MAIN 5 >R R> u. ;"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ulp := Ulp{}
+		_, err = build(&ulp, &vm, "MAIN")
+		if err != nil {
+			t.Fatal(err)
+		}
+		timings, err := ulp.Timing()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, timing := range timings {
+			if timing.Entry.Name == ">R" {
+				found = true
+				// the fixed region markers are not instructions
+				if !timing.Exits || timing.Cycles != exactCycles(52) {
+					t.Errorf("expected >R to take exactly 52 cycles, got %v", timing.Cycles)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("could not find the timing for >R")
+		}
+	}
+}
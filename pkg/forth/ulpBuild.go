@@ -10,6 +10,8 @@ package forth
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 )
 
@@ -27,6 +29,8 @@ type UlpCompileTarget int
 const (
 	UlpCompileTargetToken = iota
 	UlpCompileTargetSubroutine
+	UlpCompileTargetRiscv  // subroutine threaded RISC-V for the ULP-RISC-V
+	UlpCompileTargetDirect // each cell is the address of the code to run
 )
 
 type Ulp struct {
@@ -37,46 +41,172 @@ type Ulp struct {
 	assemblyWords []*WordPrimitive
 	dataWords     []*WordForth
 	literals      map[string]string
+	fixups        []string // the labels of the data cells holding addresses, ULP-RISC-V only
 
 	// current state of compilation
 	compileTarget UlpCompileTarget
+	exports       []*DictionaryEntry // the words called from outside, if building a library
+	mixed         *mixedThreading    // the hot words and stubs, if building with mixed threading
+
+	CheckStack bool                 // fail if a word uses the stack inconsistently
+	Sequences  []CompressedSequence // the sequences factored into new words
+	Depth      StackDepth           // the worst case depth of the stacks
+}
+
+// A repeated sequence of cells that was
+// factored into a new word.
+type CompressedSequence struct {
+	Name  string // the name of the new word
+	Cells string // the cells in the sequence
+	Count int    // the number of times the sequence was used
+	Saved int    // the number of bytes saved
 }
 
 // Build the assembly using the word passed in as the main function.
 // Note that the virtual machine will be unusable after this.
 func (u *Ulp) BuildAssembly(vm *VirtualMachine, word string) (string, error) {
-	// put back into interpret state and compile the main ulp program
+	return u.buildMain(vm, word, UlpCompileTargetToken)
+}
+
+func (u *Ulp) BuildAssemblySrt(vm *VirtualMachine, word string) (string, error) {
+	return u.buildMain(vm, word, UlpCompileTargetSubroutine)
+}
+
+// Build the assembly using direct threading, where every cell is
+// the address of the code to run. Forth words start with a jump
+// to the code that enters them. Faster than token threading but
+// larger.
+// Note that the virtual machine will be unusable after this.
+func (u *Ulp) BuildAssemblyDirect(vm *VirtualMachine, word string) (string, error) {
+	return u.buildMain(vm, word, UlpCompileTargetDirect)
+}
+
+// Build the assembly using token threading for most words and
+// subroutine threading for the hot words, the ones marked with
+// HOT or, if none are marked, the ones that run in a loop.
+// Note that the virtual machine will be unusable after this.
+func (u *Ulp) BuildAssemblyMixed(vm *VirtualMachine, word string) (string, error) {
+	u.mixed = &mixedThreading{}
+	return u.buildMain(vm, word, UlpCompileTargetToken)
+}
+
+// Build RISC-V assembly for the ULP-RISC-V of the esp32-s2
+// and esp32-s3, using the word passed in as the main function.
+// The output is assembled by the esp-idf, not ulp-c.
+// Note that the virtual machine will be unusable after this.
+func (u *Ulp) BuildAssemblyRiscv(vm *VirtualMachine, word string) (string, error) {
+	return u.buildMain(vm, word, UlpCompileTargetRiscv)
+}
 
+// Create the VM.INIT word that runs the main word then
+// halts forever, and build the assembly starting from it.
+func (u *Ulp) buildMain(vm *VirtualMachine, word string, target UlpCompileTarget) (string, error) {
+	u.compileTarget = target
+	u.exports = nil
+	// put back into interpret state and compile the main ulp program
 	vm.State.Set(uint16(StateInterpret))
+	stackInit := ""
+	if u.threaded() { // the subroutine threaded entry sets up the stack itself
+		stackInit = " VM.STACK.INIT"
+	}
 	// create the VM.INIT word without an EXIT
-	err := vm.Execute([]byte(" BL WORD VM.INIT --CREATE-FORTH ] VM.STACK.INIT " + word + " BEGIN HALT AGAIN [ LAST HIDE "))
+	err := vm.Execute([]byte(" BL WORD VM.INIT --CREATE-FORTH ]" + stackInit + " " + word + " BEGIN HALT AGAIN [ LAST HIDE "))
 	if err != nil {
 		return "", errors.Join(fmt.Errorf("could not compile the supporting words for ulp cross-compiling"), err)
 	}
+	return u.buildAssemblyHelper(vm, []*DictionaryEntry{vm.Dictionary.Entries[len(vm.Dictionary.Entries)-1]})
+}
+
+// Build the assembly for a library of words that are called
+// from other ULP assembly, instead of a main loop. Each word
+// gets a global label of "forth_" followed by its name.
+// Note that the virtual machine will be unusable after this.
+func (u *Ulp) BuildLibrary(vm *VirtualMachine, words []string) (string, error) {
+	err := u.findExports(vm, words)
+	if err != nil {
+		return "", err
+	}
 	u.compileTarget = UlpCompileTargetToken
-	return u.buildAssemblyHelper(vm, u.buildInterpreter())
+	return u.buildAssemblyHelper(vm, u.exports)
 }
 
-func (u *Ulp) BuildAssemblySrt(vm *VirtualMachine, word string) (string, error) {
-	vm.State.Set(uint16(StateInterpret))
-	// create the VM.INIT word without an EXIT
-	err := vm.Execute([]byte(" BL WORD VM.INIT --CREATE-FORTH ] " + word + " BEGIN HALT AGAIN [ LAST HIDE "))
+func (u *Ulp) BuildLibrarySrt(vm *VirtualMachine, words []string) (string, error) {
+	err := u.findExports(vm, words)
 	if err != nil {
-		return "", errors.Join(fmt.Errorf("could not compile the supporting words for ulp cross-compiling"), err)
+		return "", err
 	}
 	u.compileTarget = UlpCompileTargetSubroutine
-	return u.buildAssemblyHelper(vm, u.buildInterpreterSrt())
+	return u.buildAssemblyHelper(vm, u.exports)
+}
+
+func (u *Ulp) BuildLibraryDirect(vm *VirtualMachine, words []string) (string, error) {
+	err := u.findExports(vm, words)
+	if err != nil {
+		return "", err
+	}
+	u.compileTarget = UlpCompileTargetDirect
+	return u.buildAssemblyHelper(vm, u.exports)
+}
+
+func (u *Ulp) findExports(vm *VirtualMachine, words []string) error {
+	vm.State.Set(uint16(StateInterpret))
+	if len(words) == 0 {
+		return fmt.Errorf("a library needs at least one word to export")
+	}
+	u.exports = make([]*DictionaryEntry, 0, len(words))
+	for _, word := range words {
+		entry, err := vm.Dictionary.FindName(word)
+		if err != nil {
+			return err
+		}
+		if _, ok := entry.Word.(*WordForth); !ok || entry.Flag.Data {
+			return EntryError(entry, "can not be exported, only forth words can be exported")
+		}
+		if slices.Contains(u.exports, entry) {
+			continue
+		}
+		entry.Flag.exported = true
+		u.exports = append(u.exports, entry)
+	}
+	return nil
 }
 
-func (u *Ulp) buildAssemblyHelper(vm *VirtualMachine, header string) (string, error) {
-	vmInitEntry := vm.Dictionary.Entries[len(vm.Dictionary.Entries)-1]
+// The global label that other assembly uses to call an exported word.
+func (u *Ulp) exportLabel(entry *DictionaryEntry) string {
+	return "forth_" + u.replaceOtherChars(entry.Name)
+}
+
+func (u *Ulp) buildAssemblyHelper(vm *VirtualMachine, roots []*DictionaryEntry) (string, error) {
+	u.outCount = 0 // number the labels the same way every build
+	u.fixups = nil
 	// generate the various lists
-	err := u.buildLists(vmInitEntry)
+	err := u.buildLists(roots...)
 	if err != nil {
 		return "", err
 	}
+	if u.mixed != nil {
+		u.mixed.choose(u, roots)
+	}
+	// check that the stack is used consistently
+	if u.CheckStack {
+		checker := EffectChecker{}
+		for _, root := range roots {
+			if u.exports != nil {
+				err = checker.CheckExport(root)
+			} else {
+				err = checker.Check(root)
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+	}
 	// optimize!
-	optimizer := Optimizer{u}
+	exit, err := vm.Dictionary.FindName("EXIT")
+	if err != nil {
+		return "", err
+	}
+	optimizer := Optimizer{u: u, roots: roots, exit: exit}
 	err = optimizer.Optimize()
 	if err != nil {
 		return "", err
@@ -86,12 +216,25 @@ func (u *Ulp) buildAssemblyHelper(vm *VirtualMachine, header string) (string, er
 	if err != nil {
 		return "", err
 	}
-	err = u.buildLists(vmInitEntry)
+	err = u.buildLists(roots...)
 	if err != nil {
 		return "", err
 	}
 	// count the number of calls
 	u.countCalls()
+	if u.mixed != nil {
+		err = u.mixed.prepare(u)
+		if err != nil {
+			return "", err
+		}
+	}
+	// find how deep the stacks can get
+	analyzer := DepthAnalyzer{mixed: u.mixed}
+	if u.exports != nil {
+		u.Depth = analyzer.AnalyzeExports(roots)
+	} else {
+		u.Depth = analyzer.Analyze(roots[0])
+	}
 	// create the different assemblies
 	asm, err := u.buildAssemblyWords()
 	if err != nil {
@@ -110,8 +253,34 @@ func (u *Ulp) buildAssemblyHelper(vm *VirtualMachine, header string) (string, er
 		return "", err
 	}
 	forthSection := ".data"
-	if u.compileTarget == UlpCompileTargetSubroutine {
+	var header string
+	switch u.compileTarget {
+	case UlpCompileTargetToken, UlpCompileTargetDirect:
+		header = u.buildInterpreter()
+	case UlpCompileTargetSubroutine:
+		header = u.buildInterpreterSrt()
 		forthSection = ".text"
+	case UlpCompileTargetRiscv:
+		header = u.buildInterpreterRiscv()
+		forthSection = ".text"
+	}
+	if u.exports != nil {
+		header += u.buildExports()
+	}
+
+	text := asm
+	if u.mixed != nil {
+		hot, threads, err := u.mixed.build(u)
+		if err != nil {
+			return "", err
+		}
+		text += "\r\n" + hot
+		forth += "\r\n" + threads
+	}
+	if u.compileTarget == UlpCompileTargetDirect {
+		// the literals push themselves, they're code
+		text += "\r\n" + literals
+		literals = ""
 	}
 
 	// put assemblies together
@@ -119,7 +288,7 @@ func (u *Ulp) buildAssemblyHelper(vm *VirtualMachine, header string) (string, er
 		header,
 		"__assembly_words:",
 		".text",
-		asm,
+		text,
 		forthSection,
 		"__forth_words:",
 		forth,
@@ -129,19 +298,29 @@ func (u *Ulp) buildAssemblyHelper(vm *VirtualMachine, header string) (string, er
 		data,
 		"__data_end:",
 	}
-	return strings.Join(i, "\r\n"), nil
+	if u.compileTarget == UlpCompileTargetRiscv {
+		// the peephole pass only knows the ULP-FSM instructions
+		i = append(i, u.buildStackRiscv())
+		return strings.Join(i, "\r\n"), nil
+	}
+	// remove redundant instructions between the joined words
+	peephole := Peephole{}
+	return peephole.Optimize(strings.Join(i, "\r\n")), nil
 }
 
 // Convert list of used subroutine-threaded assembly
 // words into a string.
 func (u *Ulp) buildAssemblyWords() (string, error) {
-	asmList := make([]string, len(u.assemblyWords))
-	for i, word := range u.assemblyWords {
+	asmList := make([]string, 0, len(u.assemblyWords))
+	for _, word := range u.assemblyWords {
+		if u.mixed != nil && !u.mixed.token[word] {
+			continue // only called by subroutine threaded code
+		}
 		asm, err := word.BuildAssembly(u)
 		if err != nil {
 			return "", err
 		}
-		asmList[i] = asm
+		asmList = append(asmList, asm)
 	}
 	return strings.Join(asmList, "\r\n\r\n"), nil
 }
@@ -149,17 +328,80 @@ func (u *Ulp) buildAssemblyWords() (string, error) {
 // Convert list of subroutine-threaded forth
 // words into a string.
 func (u *Ulp) buildForthWords() (string, error) {
-	output := make([]string, len(u.forthWords))
-	for i, word := range u.forthWords {
+	u.layoutForthWords()
+	output := make([]string, 0, len(u.forthWords))
+	for _, word := range u.forthWords {
+		if u.wordTarget(word) != u.compileTarget {
+			continue // a hot word, built with the code in .text
+		}
 		asm, err := word.BuildAssembly(u)
 		if err != nil {
 			return "", err
 		}
-		output[i] = asm
+		output = append(output, asm)
 	}
 	return strings.Join(output, "\r\n\r\n"), nil
 }
 
+// Order the forth words so that a word tail called from
+// only one place comes directly after its caller. That
+// tail call then falls through instead of jumping.
+func (u *Ulp) layoutForthWords() {
+	tailCalls := make(map[*WordForth]int)
+	for _, w := range u.forthWords {
+		for _, c := range w.Cells {
+			tailCall, ok := c.(*CellTailCall)
+			if ok {
+				tailCall.fallsThrough = false
+				tailCalls[tailCall.dest] += 1
+			}
+		}
+	}
+	// find the word that each word can fall through to
+	next := make(map[*WordForth]*CellTailCall)
+	isNext := make(map[*WordForth]bool)
+	for _, w := range u.forthWords {
+		if len(w.Cells) == 0 {
+			continue
+		}
+		tailCall, ok := w.Cells[len(w.Cells)-1].(*CellTailCall)
+		if !ok || tailCall.dest == w || tailCalls[tailCall.dest] != 1 {
+			continue
+		}
+		// subroutine threaded words start with a docol if called
+		if u.wordTarget(tailCall.dest) != UlpCompileTargetToken && tailCall.dest.Entry.Flag.calls != 0 {
+			continue
+		}
+		next[w] = tailCall
+		isNext[tailCall.dest] = true
+	}
+	// place each chain of words, starting with words that
+	// aren't fallen into, then any loops that are left
+	placed := make(map[*WordForth]bool)
+	order := make([]*WordForth, 0, len(u.forthWords))
+	place := func(w *WordForth) {
+		for !placed[w] {
+			placed[w] = true
+			order = append(order, w)
+			tailCall, ok := next[w]
+			if !ok || placed[tailCall.dest] {
+				return
+			}
+			tailCall.fallsThrough = true
+			w = tailCall.dest
+		}
+	}
+	for _, w := range u.forthWords {
+		if !isNext[w] {
+			place(w)
+		}
+	}
+	for _, w := range u.forthWords {
+		place(w)
+	}
+	u.forthWords = order
+}
+
 // Convert list of data
 // words into a string.
 func (u *Ulp) buildDataWords() (string, error) {
@@ -181,28 +423,38 @@ func (u *Ulp) buildDataWords() (string, error) {
 
 func (u *Ulp) buildLiterals() (string, error) {
 	switch u.compileTarget {
-	case UlpCompileTargetToken:
-		output := make([]string, len(u.literals))
-		i := 0
-		for name, val := range u.literals {
-			output[i] = fmt.Sprintf("%s: .int %s", name, val)
-			i += 1
+	case UlpCompileTargetToken, UlpCompileTargetDirect:
+		// sort so that the output is the same every build
+		names := slices.Sorted(maps.Keys(u.literals))
+		output := make([]string, len(names))
+		for i, name := range names {
+			if u.compileTarget == UlpCompileTargetDirect {
+				output[i] = fmt.Sprintf("%s:\r\nmove r0, %s\r\njump __push", name, u.literals[name])
+			} else {
+				output[i] = fmt.Sprintf("%s: .int %s", name, u.literals[name])
+			}
 		}
 		return strings.Join(output, "\r\n"), nil
-	case UlpCompileTargetSubroutine:
+	case UlpCompileTargetSubroutine, UlpCompileTargetRiscv:
 		return "", nil // literals are compiled along the way, not as a final list
 	default:
 		return "", fmt.Errorf("unknown compile target %d, please file a bug report", u.compileTarget)
 	}
 }
 
-func (u *Ulp) buildLists(entry *DictionaryEntry) error {
+func (u *Ulp) buildLists(roots ...*DictionaryEntry) error {
 	u.forthWords = make([]*WordForth, 0)
 	u.assemblyWords = make([]*WordPrimitive, 0)
 	u.dataWords = make([]*WordForth, 0)
 	u.literals = make(map[string]string)
 
-	return entry.AddToList(u)
+	for _, root := range roots {
+		err := root.AddToList(u)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (u *Ulp) clearLists() error {
@@ -269,7 +521,11 @@ func (u *Ulp) countCalls() {
 
 // Reset the number of times that each word is called.
 func (u *Ulp) resetCalls() {
+	for _, w := range u.assemblyWords {
+		w.Entry.Flag.calls = 0
+	}
 	for _, w := range u.forthWords {
+		w.Entry.Flag.calls = 0
 		for _, c := range w.Cells {
 			switch cell := c.(type) {
 			case CellAddress:
@@ -294,13 +550,34 @@ func (u *Ulp) buildInterpreter() string {
 		"HOST_FUNC:   .int 0",
 		"HOST_PARAM0: .int 0",
 		".data",
-		"__ip:  .int __body__forth_VM.INIT", // instruction pointer starts at word VM.INIT
-		"__rsp: .int __stack_start",         // return stack pointer starts at the beginning of the stack section
-
-		// boot labels
-		".boot",
-		".global entry",
-		"entry:",
+	}
+	if u.exports != nil {
+		i = append(i,
+			"__ip:  .int 0",             // set by each exported word
+			"__rsp: .int __stack_start", // return stack pointer starts at the beginning of the stack section
+			".text",                     // the caller's assembly provides the entry
+		)
+	} else {
+		i = append(i,
+			"__ip:  .int __body__forth_VM.INIT", // instruction pointer starts at word VM.INIT
+			"__rsp: .int __stack_start",         // return stack pointer starts at the beginning of the stack section
+
+			// boot labels
+			".boot",
+			".global entry",
+			"entry:",
+		)
+	}
+	if u.mixed != nil && u.exports == nil {
+		i = append(i,
+			"add r0, r2, 0xFFFF", // will overflow unless r2 is 0
+			"jump r2, ov",        // continue the subroutine threaded code after HALT
+		)
+	}
+	if u.compileTarget == UlpCompileTargetDirect {
+		return strings.Join(append(i, u.buildNextDirect()...), "\r\n") + "\r\n"
+	}
+	i = append(i,
 		"next:",
 
 		// load the instruction
@@ -348,10 +625,65 @@ func (u *Ulp) buildInterpreter() string {
 		// it's a definite branch
 		"and r1, r0, 0x3FFF",    // get the lowest 14 bits
 		"jump __next_skip_load", // then continue vm at this newer address
+	)
+	if u.mixed != nil {
+		i = append(i, u.buildInterpreterMixed()...)
 	}
 	return strings.Join(i, "\r\n") + "\r\n"
 }
 
+// The direct threaded interpreter. Every cell is the address
+// of code, so NEXT only loads the cell and jumps to it. The
+// instruction pointer stays in r1, primitives that don't
+// continue at __next_skip_load store it first. The labels
+// match the token threaded interpreter so that both use
+// the same primitives.
+func (u *Ulp) buildNextDirect() []string {
+	return []string{
+		"next:",
+		"move r2, 0",        // r2 is 0 at the start of every loop as a global pointer
+		"__next_skip_r2:",   // address to skip loading r2
+		"ld r1, r2, __ip",   // load the instruction pointer
+		"__next_skip_load:", // address to skip loading IP
+		"add r1, r1, 1",     // increment the pointer to the next instruction
+		"ld r0, r1, -1",     // load the address of the code
+		"jump r0",           // and run it
+
+		// used by EXECUTE, r0 holds the body of a word
+		"__ins_asm:",
+		"jumpr __ins_forth, __forth_words, ge",
+		"jump r0", // it's assembly
+
+		// every called forth word starts with "jump __docol"
+		"__docol:",
+		"add r0, r0, 1", // the body is after the jump
+		"__ins_forth:",
+		"st r0, r2, __ip",     // put the body into the instruction pointer
+		"ld r0, r2, __rsp",    // load the return stack pointer
+		"add r0, r0, 1",       // increment the rsp
+		"st r1, r0, 0",        // store the instruction we were about to execute onto the return stack
+		"st r0, r2, __rsp",    // store the rsp
+		"jump __next_skip_r2", // then start the vm again at the body
+
+		// each literal moves its value into r0 then jumps here
+		"__push:",
+		"sub r3, r3, 1",         // increase the stack by 1
+		"st r0, r3, 0",          // store the number
+		"jump __next_skip_load", // next!
+
+		// a conditional branch, the destination is in the next cell
+		"__branch0:",
+		"ld r0, r3, 0",          // get value from stack
+		"add r3, r3, 1",         // decrement stack
+		"jumpr __branch, 1, lt", // branch if 0
+		"add r1, r1, 1",         // otherwise skip the destination
+		"jump __next_skip_load", // and continue
+		"__branch:",             // a definite branch, the destination is in the next cell
+		"ld r1, r1, 0",          // load the destination
+		"jump __next_skip_load", // then continue vm at this newer address
+	}
+}
+
 func (u *Ulp) buildInterpreterSrt() string {
 	i := []string{
 		// required data, will be placed at the start of .data
@@ -368,23 +700,33 @@ func (u *Ulp) buildInterpreterSrt() string {
 		"HOST_PARAM0: .int 0",
 		".data",
 		"__rsp: .int __stack_start", // return stack pointer starts at the beginning of the stack section
+	}
+	if u.exports == nil {
+		i = append(i,
+			// boot labels
+			".boot",
+			".global entry",
+			"entry:",
+
+			// registers are set to 0 when the esp32
+			// initializes the ulp program,
+			// so we can see if r2 has been set or not
+			"add r0, r2, 0xFFFF", // will overflow unless r2 is 0
+			"jump r2, ov",        // jump to next instruction if overflowed
+			// setup the pointers
+			"move r2, __body__forth_VM.INIT", // instruction pointer goes to init word
+			"move r3, __stack_end",           // set up stack pointer
+			"jump r2",                        // begin execution
+		)
+	}
+	i = append(i, ".text")
+	i = append(i, buildSrtHelpers()...)
+	return strings.Join(i, "\r\n") + "\r\n"
+}
 
-		// boot labels
-		".boot",
-		".global entry",
-		"entry:",
-
-		// registers are set to 0 when the esp32
-		// initializes the ulp program,
-		// so we can see if r2 has been set or not
-		"add r0, r2, 0xFFFF", // will overflow unless r2 is 0
-		"jump r2, ov",        // jump to next instruction if overflowed
-		// setup the pointers
-		"move r2, __body__forth_VM.INIT", // instruction pointer goes to init word
-		"move r3, __stack_end",           // set up stack pointer
-		"jump r2",                        // begin execution
-
-		".text",
+// The subroutines used by subroutine threaded code.
+func buildSrtHelpers() []string {
+	return []string{
 		// subroutine to set up the forth word return
 		"__docol:",
 		"move r0, 0",
@@ -417,5 +759,108 @@ func (u *Ulp) buildInterpreterSrt() string {
 		"move r2, r1", // copy the new address
 		"jump r2",     // and jump to it!
 	}
+}
+
+// Build the labels that other assembly calls to run
+// the exported words. The caller puts the address to
+// return to in r1 and the data stack pointer in r3.
+// A return address is pushed that leads to the code
+// that jumps back to the caller when the word exits.
+func (u *Ulp) buildExports() string {
+	i := []string{
+		".data",
+		"__export_return: .int 0", // the address to return to
+	}
+	if u.compileTarget == UlpCompileTargetToken || u.compileTarget == UlpCompileTargetDirect {
+		i = append(i,
+			"__export_thread: .int __export_exit", // a token that returns to the caller
+			".text",
+			"__export_exit:",
+			"ld r0, r2, __export_return",
+			"jump r0",
+		)
+	} else {
+		i = append(i,
+			".text",
+			"__export_exit:",
+			"halt", // never run, EXIT continues after the return address
+			"move r0, __export_return",
+			"ld r0, r0, 0",
+			"jump r0",
+		)
+	}
+	for _, entry := range u.exports {
+		label := u.exportLabel(entry)
+		i = append(i,
+			".global "+label,
+			label+":",
+			"move r2, 0",
+			"st r1, r2, __export_return", // save the return address
+			"ld r0, r2, __rsp",           // push the return address to __export_exit
+			"add r0, r0, 1",
+		)
+		if u.compileTarget == UlpCompileTargetToken || u.compileTarget == UlpCompileTargetDirect {
+			i = append(i,
+				"move r1, __export_thread",
+				"st r1, r0, 0",
+				"st r0, r2, __rsp",
+				"move r1, "+entry.BodyLabel(), // start the interpreter at the word
+				"jump __next_skip_load",
+			)
+		} else {
+			i = append(i,
+				"move r1, __export_exit",
+				"st r1, r0, 0",
+				"st r0, r2, __rsp",
+				"move r2, "+entry.BodyLabel(), // jump to the start of the word
+				"jump r2",
+			)
+		}
+	}
 	return strings.Join(i, "\r\n") + "\r\n"
 }
+
+// Sort the labels that share an address in assembly
+// created by the ulp-c assembler. The assembler outputs
+// them in a random order, this keeps the output the same
+// every build.
+func SortAssemblyLabels(asm []byte) []byte {
+	lines := strings.Split(string(asm), "\n")
+	output := make([]string, 0, len(lines))
+	group := make([][]string, 0) // the labels at the current address
+	flush := func() {
+		slices.SortFunc(group, func(a, b []string) int {
+			return strings.Compare(a[len(a)-1], b[len(b)-1])
+		})
+		for _, g := range group {
+			output = append(output, g...)
+		}
+		group = group[:0]
+	}
+	global := ""
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, ".global "):
+			global = line
+		case strings.HasSuffix(line, ":") && !strings.HasPrefix(line, " "):
+			if global != "" {
+				group = append(group, []string{global, line})
+			} else {
+				group = append(group, []string{line})
+			}
+			global = ""
+		default:
+			flush()
+			if global != "" {
+				output = append(output, global)
+				global = ""
+			}
+			output = append(output, line)
+		}
+	}
+	flush()
+	if global != "" {
+		output = append(output, global)
+	}
+	return []byte(strings.Join(output, "\n"))
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Molorius/ulp-c/pkg/asm"
+)
+
+func TestReproducible(t *testing.T) {
+	code := `
+		: PIN_INIT GPIO13.ENABLE GPIO13.OUTPUT_ENABLE GPIO13.SET_HIGH ;
+		13 GPIO_NUMBER_TO_RTC SERIAL.WRITE_9600_BAUD SERIAL.WRITE_CREATE TX
+		' TX IS EMIT
+		GLOBAL-VARIABLE COUNT
+		: MAIN
+			PIN_INIT
+			0 BEGIN
+				." Hello world! " DUP U. CR
+				1+ DUP COUNT !
+				1000 DELAY_MS
+				12345 54321 + 2 * 777 OR COUNT +!
+			AGAIN
+		;
+	`
+	// build the program the same way that the build command does
+	build := func(subroutine bool) ([]byte, []byte) {
+		var buff bytes.Buffer
+		vm := VirtualMachine{Out: &buff}
+		err := vm.Setup()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vm.BuiltinEsp32()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vm.Execute([]byte(code))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ulp := Ulp{}
+		var assembly string
+		if subroutine {
+			assembly, err = ulp.BuildAssemblySrt(&vm, "MAIN")
+		} else {
+			assembly, err = ulp.BuildAssembly(&vm, "MAIN")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		assembler := asm.Assembler{}
+		bin, err := assembler.BuildFile(assembly, "forth.S", 8176, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := assembler.BuildAssembly(assembly, "forth.S", 8176, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bin, SortAssemblyLabels(out)
+	}
+	for _, subroutine := range []bool{false, true} {
+		bin, out := build(subroutine)
+		for range 20 {
+			b, o := build(subroutine)
+			if !bytes.Equal(bin, b) {
+				t.Fatalf("the binary changed between builds, subroutine threaded: %v", subroutine)
+			}
+			if !bytes.Equal(out, o) {
+				t.Fatalf("the assembly changed between builds, subroutine threaded: %v", subroutine)
+			}
+		}
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	code := `
+		: SQUARE ( n -- n*n ) DUP * ;
+		: SUM ( a b -- a+b ) + ;
+		: PRINT ( n -- ) ESP.PRINTU16 ;
+		: FINISH ( -- ) ESP.DONE ;
+	`
+	// calls the exported words the same way that user assembly would
+	caller := `
+		.boot
+		.global entry
+		entry:
+		move r3, __stack_end
+		move r0, 7
+		sub r3, r3, 1
+		st r0, r3, 0
+		move r1, entry.0
+		jump forth_SQUARE
+		entry.0:
+		move r0, 5
+		sub r3, r3, 1
+		st r0, r3, 0
+		move r1, entry.1
+		jump forth_SUM
+		entry.1:
+		move r1, entry.2
+		jump forth_PRINT
+		entry.2:
+		move r1, entry.3
+		jump forth_FINISH
+		entry.3:
+		halt
+	`
+	exports := []string{"SQUARE", "SUM", "PRINT", "FINISH"}
+	builds := []func(*Ulp, *VirtualMachine, []string) (string, error){
+		(*Ulp).BuildLibrary,
+		(*Ulp).BuildLibrarySrt,
+		(*Ulp).BuildLibraryDirect,
+	}
+	for _, build := range builds {
+		var buff bytes.Buffer
+		vm := VirtualMachine{Out: &buff}
+		err := vm.Setup()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vm.Execute([]byte(code))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ulp := Ulp{CheckStack: true}
+		assembly, err := build(&ulp, &vm, exports)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := asm.Runner{}
+		r.SetDefaults()
+		r.Reduce = false
+		r.RunTest(t, assembly+"\r\n"+caller, "54 ")
+	}
+}
+
+func TestLibraryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		exports []string
+	}{
+		{"none", ": A ;", []string{}},
+		{"missing", ": A ;", []string{"B"}},
+		{"assembly", ": A ;", []string{"DUP"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			vm := VirtualMachine{Out: &buff}
+			err := vm.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = vm.Execute([]byte(tt.code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ulp := Ulp{}
+			_, err = ulp.BuildLibrary(&vm, tt.exports)
+			if err == nil {
+				t.Errorf("expected an error exporting %v", tt.exports)
+			}
+		})
+	}
+}
+
+func TestEntry(t *testing.T) {
+	var buff bytes.Buffer
+	vm := VirtualMachine{Out: &buff}
+	err := vm.Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vm.Execute([]byte(": START 123 ESP.PRINTU16 ESP.DONE ;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ulp := Ulp{}
+	assembly, err := ulp.BuildAssembly(&vm, "START")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := asm.Runner{}
+	r.SetDefaults()
+	r.RunTest(t, assembly, "123 ")
+}
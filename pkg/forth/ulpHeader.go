@@ -0,0 +1,116 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The names that can be used in C.
+var cIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// The functions the ULP can ask the esp32 to run
+// through HOST_FUNC, see builtin/10_print.f.
+var headerHostFunctions = []struct {
+	name  string
+	value int
+}{
+	{"ACK", 0},
+	{"DONE", 1},
+	{"PRINTU16", 2},
+	{"PRINTCHAR", 3},
+}
+
+// Create a C header for the esp32 that declares the memory
+// shared with the last build. Every word created with the
+// GLOBAL- words is declared with the "ulp_" prefix used
+// by the esp-idf, along with the mutex and host function
+// memory and helpers to take and give the mutex.
+//
+// Only the lower 16 bits of each cell are written by the ULP.
+func (u *Ulp) BuildHeader() (string, error) {
+	output := []string{
+		"/* Generated by ulp-forth, do not edit. */",
+		"#pragma once",
+		"",
+		"#include <stdint.h>",
+		"",
+		"#ifdef __cplusplus",
+		"extern \"C\" {",
+		"#endif",
+		"",
+	}
+	if u.compileTarget != UlpCompileTargetRiscv { // the ULP-RISC-V starts at main
+		output = append(output,
+			"/* The start of the program, used with ulp_run(). */",
+			"extern uint32_t ulp_entry;",
+			"",
+		)
+	}
+	output = append(output,
+		"/* The mutex shared with the ULP, use ulp_forth_mutex_take()",
+		"   and ulp_forth_mutex_give() instead of accessing directly. */",
+		"extern volatile uint32_t ulp_MUTEX_FLAG0;",
+		"extern volatile uint32_t ulp_MUTEX_FLAG1;",
+		"extern volatile uint32_t ulp_MUTEX_TURN;",
+		"",
+		"/* The function the ULP is asking the esp32 to run and its parameter. */",
+		"extern volatile uint32_t ulp_HOST_FUNC;",
+		"extern volatile uint32_t ulp_HOST_PARAM0;",
+		"",
+	)
+	for _, f := range headerHostFunctions {
+		output = append(output, fmt.Sprintf("#define ULP_FORTH_FUNC_%s %d", f.name, f.value))
+	}
+
+	globals := make([]string, 0)
+	for _, w := range u.dataWords {
+		if !w.Entry.Flag.GlobalData {
+			continue
+		}
+		name := w.Entry.ulpName
+		if !cIdentifier.MatchString(name) {
+			return "", fmt.Errorf("the global name %s can't be used in C, only letters, numbers and _ are allowed", name)
+		}
+		if len(w.Cells) == 1 {
+			globals = append(globals, fmt.Sprintf("extern volatile uint32_t ulp_%s;", name))
+		} else {
+			globals = append(globals, fmt.Sprintf("extern volatile uint32_t ulp_%s[%d];", name, len(w.Cells)))
+		}
+	}
+	if len(globals) != 0 {
+		output = append(output, "", "/* The memory created with the GLOBAL- words. */")
+		output = append(output, globals...)
+	}
+
+	output = append(output,
+		"",
+		"/* Take the mutex, waiting until the ULP gives it. */",
+		"static inline void ulp_forth_mutex_take(void)",
+		"{",
+		"    ulp_MUTEX_FLAG1 = 1;",
+		"    ulp_MUTEX_TURN = 0;",
+		"    while ((ulp_MUTEX_FLAG0 & 0xFFFF) && (ulp_MUTEX_TURN & 0xFFFF) == 0) {",
+		"    }",
+		"}",
+		"",
+		"/* Give the mutex back to the ULP. */",
+		"static inline void ulp_forth_mutex_give(void)",
+		"{",
+		"    ulp_MUTEX_FLAG1 = 0;",
+		"}",
+		"",
+		"#ifdef __cplusplus",
+		"}",
+		"#endif",
+		"",
+	)
+	return strings.Join(output, "\n"), nil
+}
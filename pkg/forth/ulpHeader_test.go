@@ -0,0 +1,88 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expect   []string
+		unwanted []string
+		err      bool
+	}{
+		{
+			name: "shared memory",
+			code: "GLOBAL-VARIABLE COUNT 10 GLOBAL-BUFFER: SAMPLES VARIABLE HIDDEN : MAIN 1 COUNT ! 2 SAMPLES ! 3 HIDDEN ! ;",
+			expect: []string{
+				"extern volatile uint32_t ulp_COUNT;",
+				"extern volatile uint32_t ulp_SAMPLES[10];",
+				"extern volatile uint32_t ulp_MUTEX_FLAG0;",
+				"extern volatile uint32_t ulp_HOST_FUNC;",
+				"static inline void ulp_forth_mutex_take(void)",
+			},
+			unwanted: []string{"HIDDEN"},
+		},
+		{
+			name: "double",
+			code: "GLOBAL-2VARIABLE D : MAIN 1 2 D 2! ;",
+			expect: []string{
+				"extern volatile uint32_t ulp_D[2];",
+			},
+		},
+		{
+			name: "not in C",
+			code: "GLOBAL-VARIABLE A.B : MAIN 1 A.B ! ;",
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buff bytes.Buffer
+			vm := VirtualMachine{Out: &buff}
+			err := vm.Setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = vm.Execute([]byte(tt.code))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ulp := Ulp{}
+			_, err = ulp.BuildAssembly(&vm, "MAIN")
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, err := ulp.BuildHeader()
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range tt.expect {
+				if !strings.Contains(header, e) {
+					t.Errorf("expected %q in the header:\n%s", e, header)
+				}
+			}
+			for _, u := range tt.unwanted {
+				if strings.Contains(header, u) {
+					t.Errorf("did not expect %q in the header:\n%s", u, header)
+				}
+			}
+		})
+	}
+}
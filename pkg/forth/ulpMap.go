@@ -0,0 +1,178 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+)
+
+// The kinds of labels in the map.
+const (
+	MapInterpreter = "interpreter" // part of the interpreter or shared memory
+	MapBoundary    = "boundary"    // the start or end of a group of words
+	MapAssembly    = "assembly"    // an assembly word
+	MapForth       = "forth"       // a forth word
+	MapData        = "data"        // a data word
+	MapLiteral     = "literal"     // a literal used by token or direct threaded code
+	MapExport      = "export"      // the label that calls an exported word
+)
+
+// The labels of the interpreter that are put in the map if they exist.
+var mapInterpreterLabels = []string{
+	"entry",
+	"MUTEX_FLAG0",
+	"MUTEX_FLAG1",
+	"MUTEX_TURN",
+	"HOST_FUNC",
+	"HOST_PARAM0",
+	"__ip",
+	"__rsp",
+	"__docol",
+	"__add_to_stack",
+	"__branch_if",
+	"__push",
+	"__branch0",
+	"__branch",
+}
+
+// A section of the assembled output.
+type UlpSection struct {
+	Name   string `json:"name"`
+	Offset int    `json:"address"` // the start of the section in bytes
+	Size   int    `json:"size"`    // the size of the section in bytes
+}
+
+// A label in the assembled output.
+type UlpSymbol struct {
+	Label   string `json:"label"`
+	Word    string `json:"word,omitempty"` // the name of the word, if any
+	Kind    string `json:"kind"`
+	Section string `json:"section"`
+	Address int    `json:"address"` // in bytes
+	Size    int    `json:"size"`    // in bytes, until the next label in the map
+}
+
+// Where every word ended up in the assembled output.
+type UlpMap struct {
+	Sections []UlpSection `json:"sections"`
+	Symbols  []UlpSymbol  `json:"symbols"`
+}
+
+// Create the map of the last build. The addresses of the labels
+// and the sections come from assembling the output.
+func (u *Ulp) Map(labels map[string]int, sections []UlpSection) UlpMap {
+	symbols := make([]UlpSymbol, 0)
+	add := func(label string, word string, kind string) {
+		addr, ok := labels[label]
+		if !ok {
+			return // optimized away or not part of this target
+		}
+		symbols = append(symbols, UlpSymbol{Label: label, Word: word, Kind: kind, Address: addr})
+	}
+	for _, label := range mapInterpreterLabels {
+		add(label, "", MapInterpreter)
+	}
+	for _, label := range []string{"__assembly_words", "__forth_words", "__data_words", "__data_end"} {
+		add(label, "", MapBoundary)
+	}
+	for _, w := range u.assemblyWords {
+		add(w.Entry.ulpName, w.Entry.Name, MapAssembly)
+	}
+	for _, w := range u.forthWords {
+		add(w.Entry.ulpName, w.Entry.Name, MapForth)
+	}
+	if u.mixed != nil { // the subroutine threaded code of mixed threading
+		for _, w := range u.assemblyWords {
+			add(w.Entry.ulpName+MixedSuffix, w.Entry.Name, MapAssembly)
+		}
+		for _, w := range u.forthWords {
+			add(w.Entry.ulpName+MixedSuffix, w.Entry.Name, MapForth)
+		}
+	}
+	for _, w := range u.dataWords {
+		add(w.Entry.ulpName, w.Entry.Name, MapData)
+	}
+	for _, e := range u.exports {
+		add(u.exportLabel(e), e.Name, MapExport)
+	}
+	for label := range u.literals {
+		add(label, "", MapLiteral)
+	}
+	slices.SortStableFunc(symbols, func(a, b UlpSymbol) int {
+		if a.Address != b.Address {
+			return a.Address - b.Address
+		}
+		if (a.Kind == MapBoundary) != (b.Kind == MapBoundary) {
+			if a.Kind == MapBoundary {
+				return -1 // boundaries come before the words they start
+			}
+			return 1
+		}
+		if a.Label < b.Label {
+			return -1
+		}
+		if a.Label > b.Label {
+			return 1
+		}
+		return 0
+	})
+
+	// each label takes up the space until the next label
+	for i := range symbols {
+		s := &symbols[i]
+		end := s.Address
+		for _, section := range sections {
+			if s.Address >= section.Offset && s.Address < section.Offset+section.Size {
+				s.Section = section.Name
+				end = section.Offset + section.Size
+				break
+			}
+		}
+		if s.Kind == MapBoundary {
+			continue // only marks a location
+		}
+		for _, next := range symbols[i+1:] {
+			if next.Address > s.Address {
+				end = min(end, next.Address)
+				break
+			}
+		}
+		s.Size = max(0, end-s.Address)
+	}
+	return UlpMap{Sections: sections, Symbols: symbols}
+}
+
+// Write the map as a human readable table.
+func (m *UlpMap) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "section\taddress\tsize")
+	for _, s := range m.Sections {
+		fmt.Fprintf(tw, "%s\t0x%04x\t%d\n", s.Name, s.Offset, s.Size)
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "address\tsize\tsection\tkind\tlabel\tword")
+	for _, s := range m.Symbols {
+		fmt.Fprintf(tw, "0x%04x\t%d\t%s\t%s\t%s\t%s\n", s.Address, s.Size, s.Section, s.Kind, s.Label, s.Word)
+	}
+	return tw.Flush()
+}
+
+// Write the map as JSON.
+func (m *UlpMap) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
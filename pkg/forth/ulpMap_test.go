@@ -0,0 +1,112 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Molorius/ulp-c/pkg/asm"
+)
+
+func TestMap(t *testing.T) {
+	var buff bytes.Buffer
+	vm := VirtualMachine{Out: &buff}
+	err := vm.Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vm.Execute([]byte("VARIABLE X : MAIN X @ 1000 + X ! ;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ulp := Ulp{}
+	assembly, err := ulp.BuildAssembly(&vm, "MAIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assembler := asm.Assembler{}
+	_, err = assembler.BuildFile(assembly, "forth.S", 8176, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := assembler.Compiler
+	labels := make(map[string]int)
+	for name, label := range c.Labels {
+		labels[name] = label.Value
+	}
+	sections := []UlpSection{
+		{Name: ".boot", Offset: c.Boot.Offset, Size: c.Boot.Size},
+		{Name: ".text", Offset: c.Text.Offset, Size: c.Text.Size},
+		{Name: ".boot.data", Offset: c.BootData.Offset, Size: c.BootData.Size},
+		{Name: ".data", Offset: c.Data.Offset, Size: c.Data.Size},
+		{Name: ".bss", Offset: c.Bss.Offset, Size: c.Bss.Size},
+		{Name: ".stack", Offset: c.Stack.Offset, Size: c.Stack.Size},
+	}
+	m := ulp.Map(labels, sections)
+
+	found := make(map[string]UlpSymbol)
+	last := -1
+	for _, s := range m.Symbols {
+		if s.Address < last {
+			t.Errorf("%s is out of order", s.Label)
+		}
+		last = s.Address
+		found[s.Label] = s
+		if s.Kind != MapBoundary && s.Size == 0 {
+			t.Errorf("%s has no size", s.Label)
+		}
+	}
+	expected := []struct {
+		label   string
+		kind    string
+		section string
+	}{
+		{"entry", MapInterpreter, ".boot"},
+		{"__forth_words", MapBoundary, ".data"},
+		{"__data_end", MapBoundary, ".stack"},
+	}
+	for _, e := range expected {
+		s, ok := found[e.label]
+		if !ok {
+			t.Errorf("expected %s in the map", e.label)
+			continue
+		}
+		if s.Kind != e.kind || s.Section != e.section {
+			t.Errorf("expected %s to be %s in %s, got %s in %s", e.label, e.kind, e.section, s.Kind, s.Section)
+		}
+	}
+	kinds := make(map[string]bool)
+	for _, s := range m.Symbols {
+		kinds[s.Kind+" "+s.Word] = true
+	}
+	// MAIN is inlined into VM.INIT
+	for _, k := range []string{MapForth + " VM.INIT", MapAssembly + " +", MapData + " " + ulp.dataWords[0].Entry.Name} {
+		if !kinds[k] {
+			t.Errorf("expected the %s word in the map", k)
+		}
+	}
+	if _, ok := found["__literal_1000"]; !ok {
+		t.Errorf("expected the literal 1000 in the map")
+	}
+
+	var out bytes.Buffer
+	err = m.WriteJSON(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded UlpMap
+	err = json.Unmarshal(out.Bytes(), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Symbols) != len(m.Symbols) || len(decoded.Sections) != len(m.Sections) {
+		t.Errorf("the JSON map does not match")
+	}
+}
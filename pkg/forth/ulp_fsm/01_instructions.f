@@ -8,8 +8,6 @@
 \ Note that words created by ASSEMBLY should NOT be used
 \ to access the return stack.
 
-0x3ff48000 CONSTANT DR_REG_RTCCNTL_BASE
-
 : RTC_ADDR_FIX ( addr -- addr-fixed )
     2 RSHIFT \ divide by 4
     0x3FF AND \ remove extra bits
@@ -56,6 +54,7 @@
     SWAP 1 + \ number of inputs
     ASSEMBLY-BOTH
     TOKEN_NEXT_SKIP_LOAD LAST SET-ULP-ASM-NEXT
+    0 1 LAST SET-STACK-EFFECT \ ( -- n )
 ;
 
 : REG_WR.BUILDER ( addr high low data -- strn..str0 n )
@@ -95,6 +94,7 @@
     WRITE_RTC_REG.BUILDER
     ASSEMBLY-BOTH \ create the assembly
     TOKEN_NEXT_SKIP_LOAD LAST SET-ULP-ASM-NEXT
+    0 0 LAST SET-STACK-EFFECT \ ( -- )
 ;
 
 \ create an assembly word that writes to two RTC registers
@@ -116,4 +116,5 @@
     WRITE_RTC_REG.BUILDER C> +
     ASSEMBLY-BOTH
     TOKEN_NEXT_SKIP_LOAD LAST SET-ULP-ASM-NEXT
+    0 0 LAST SET-STACK-EFFECT \ ( -- )
 ;
@@ -27,3 +27,4 @@ WAKE.BUILDER
 WAKE.BUILDER
 ASSEMBLY-BOTH WAKE
 TOKEN_NEXT_SKIP_LOAD LAST SET-ULP-ASM-NEXT
+0 0 LAST SET-STACK-EFFECT \ ( -- )
@@ -58,9 +58,10 @@
     SERIAL.WRITE_CREATE.BUILDER
     ASSEMBLY-BOTH
     TOKEN_NEXT_SKIP_R2 LAST SET-ULP-ASM-NEXT
+    1 0 LAST SET-STACK-EFFECT \ ( c -- )
 ;
 
-\ these were found at 21 C with a logic analyzer
+\ these were found at 21 C with a logic analyzer on the esp32
 \ 0 CONSTANT SERIAL.WRITE_4800_BAUD
 874 CONSTANT SERIAL.WRITE_9600_BAUD
 \ 0 CONSTANT SERIAL.WRITE_19200_BAUD
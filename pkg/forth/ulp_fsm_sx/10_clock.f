@@ -0,0 +1,25 @@
+\ Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+\ This Source Code Form is subject to the terms of the Mozilla Public
+\ License, v. 2.0. If a copy of the MPL was not distributed with this
+\ file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+\ Create word RTC_CLOCK to read the lower 32 bits of the rtc clock.
+\ tell rtc timer to update, the esp32-s2 and esp32-s3
+\ don't have a bit to say when the update is done
+RTC_CNTL_TIME_UPDATE_REG RTC_CNTL_TIME_UPDATE_S 1 1
+WRITE_RTC_REG.BUILDER >C
+C" sub r3, r3, 2\n"
+\ read 0..15
+RTC_CNTL_TIME0_REG 0 16
+READ_RTC_REG.BUILDER >C
+\ store result
+C" st r0, r3, 1\n"
+\ read 16..31
+RTC_CNTL_TIME0_REG 16 16
+READ_RTC_REG.BUILDER >C
+\ store result and exit
+C" st r0, r3, 0"
+3 C> C> C> + + + \ add up the strings and the built instructions
+ASSEMBLY RTC_CLOCK \ create RTC_CLOCK
+TOKEN_NEXT_SKIP_LOAD LAST SET-ULP-ASM-NEXT
+0 2 LAST SET-STACK-EFFECT \ ( -- d )
@@ -0,0 +1,71 @@
+\ Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+\ This Source Code Form is subject to the terms of the Mozilla Public
+\ License, v. 2.0. If a copy of the MPL was not distributed with this
+\ file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+\ This file contains words to create custom ULP-RISC-V
+\ assembly for hardware access. They create the same words
+\ as the ULP-FSM versions so the rest of the library is shared.
+\ Only t0, a0, and a1 are used.
+
+\ Load the full address of an RTC register into t0.
+\ Cells only hold the lower 16 bits, so the upper
+\ bits are added by the assembler.
+: RTC_ADDR.BUILDER ( addr -- objn .. obj0 n )
+    >R
+    C" li t0, " DR_REG_RTC_HIGH C" *65536+" R> C" \n"
+    5 \ number of inputs
+;
+
+\ Read a field of an RTC register into a0.
+: READ_RTC_REG.BUILDER ( addr low width -- objn .. obj0 n )
+    DUP 32 SWAP - >R ( addr low width R: 32-width )
+    + 32 SWAP - >R ( addr R: 32-width 32-low-width )
+    RTC_ADDR.BUILDER >C
+    C" lw a0, 0(t0)\n"
+    C" slli a0, a0, " R> C" \n" \ remove the bits above the field
+    C" srli a0, a0, " R> C" \n" \ move the field to the bottom
+    7 C> +
+;
+
+: READ_RTC_REG ( addr low width "<spaces>name" -- )
+    READ_RTC_REG.BUILDER
+    C" addi s0, s0, -4\nsw a0, 0(s0)" \ increase stack, store result
+    SWAP 1 + \ number of inputs
+    ASSEMBLY-RISCV
+    0 1 LAST SET-STACK-EFFECT \ ( -- n )
+;
+
+\ Write data to a field of an RTC register.
+: WRITE_RTC_REG.BUILDER ( addr low width data -- objn .. obj0 n )
+    >R >R >R ( addr R: data width low )
+    RTC_ADDR.BUILDER >C
+    C" lw a0, 0(t0)\n"
+    \ clear the field
+    C" li a1, ((1<<" 1 RPICK C" )-1)<<" 0 RPICK C" \n"
+    C" not a1, a1\n"
+    C" and a0, a0, a1\n"
+    \ set the field
+    C" li a1, (" 2 RPICK C" &((1<<" 1 RPICK C" )-1))<<" 0 RPICK C" \n"
+    C" or a0, a0, a1\n"
+    C" sw a0, 0(t0)\n"
+    R> R> R> DROP DROP DROP \ clean up return stack
+    17 C> +
+;
+
+\ create an assembly word that writes to an RTC register
+: WRITE_RTC_REG ( addr low width data "<spaces>name" -- )
+    WRITE_RTC_REG.BUILDER
+    ASSEMBLY-RISCV
+    0 0 LAST SET-STACK-EFFECT \ ( -- )
+;
+
+\ create an assembly word that writes to two RTC registers
+: 2WRITE_RTC_REG ( addr0 low0 width0 data0 addr1 low1 width1 data1 "<spaces>name" -- )
+    >R >R >R >R
+    WRITE_RTC_REG.BUILDER >C
+    R> R> R> R>
+    WRITE_RTC_REG.BUILDER C> +
+    ASSEMBLY-RISCV
+    0 0 LAST SET-STACK-EFFECT \ ( -- )
+;
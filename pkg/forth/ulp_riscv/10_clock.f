@@ -0,0 +1,23 @@
+\ Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+\ This Source Code Form is subject to the terms of the Mozilla Public
+\ License, v. 2.0. If a copy of the MPL was not distributed with this
+\ file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+\ Create word RTC_CLOCK to read the lower 32 bits of the rtc clock.
+\ tell rtc timer to update, the esp32-s2 and esp32-s3
+\ don't have a bit to say when the update is done
+RTC_CNTL_TIME_UPDATE_REG RTC_CNTL_TIME_UPDATE_S 1 1
+WRITE_RTC_REG.BUILDER >C
+\ read 0..31
+RTC_CNTL_TIME0_REG
+RTC_ADDR.BUILDER >C
+C" lw a0, 0(t0)\n"
+C" addi s0, s0, -8\n" \ increase stack by 2
+C" srli a1, a0, 16\n"
+C" sw a1, 0(s0)\n" \ store the upper 16 bits
+C" slli a0, a0, 16\n"
+C" srli a0, a0, 16\n"
+C" sw a0, 4(s0)" \ store the lower 16 bits
+7 C> C> + + \ add up the strings and the built instructions
+ASSEMBLY-RISCV RTC_CLOCK \ create RTC_CLOCK
+0 2 LAST SET-STACK-EFFECT \ ( -- d )
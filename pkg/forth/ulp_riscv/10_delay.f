@@ -0,0 +1,37 @@
+\ Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+\ This Source Code Form is subject to the terms of the Mozilla Public
+\ License, v. 2.0. If a copy of the MPL was not distributed with this
+\ file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+\ delay for d rtc_slow ticks
+: RTC_CLOCK_DELAY ( d -- )
+    RTC_CLOCK ( d cycles ) \ read the current cycles
+    BEGIN
+        2OVER 2OVER ( d cycles d cycles )
+        RTC_CLOCK ( d cycles d cycles new )
+        2SWAP D- ( d cycles d diff )
+        DU< ( d cycles bool )
+    UNTIL
+    2DROP 2DROP \ clean up stack
+;
+
+C" lw a0, 0(s0)\n"
+C" addi s0, s0, 4\n" \ decrement stack
+C" beqz a0, __busy_delay.1\n" \ don't enter loop if input is 0
+C" __busy_delay.0:\n"
+    C" addi a0, a0, -1\n"
+    C" bnez a0, __busy_delay.0\n" \ loop if not 0
+C" __busy_delay.1:"
+7 \ 7 strings
+ASSEMBLY-RISCV BUSY_DELAY
+1 0 LAST SET-STACK-EFFECT \ ( n -- )
+
+: DELAY_MS ( n -- )
+    BEGIN
+        DUP
+    WHILE \ while n is not 0
+        4375 BUSY_DELAY \ estimated, not calibrated on hardware
+        1-
+    REPEAT
+    DROP \ remove n
+;
@@ -0,0 +1,27 @@
+\ Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+\ This Source Code Form is subject to the terms of the Mozilla Public
+\ License, v. 2.0. If a copy of the MPL was not distributed with this
+\ file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+\ wake up the main cpu
+RTC_CNTL_STATE0_REG RTC_CNTL_SW_CPU_INT_S 1 1
+WRITE_RTC_REG WAKE
+
+\ The ULP-RISC-V is reset when it halts, so the state is
+\ saved and main continues after HALT when the timer starts
+\ it again. This replaces the HALT primitive.
+C" jal t0, __save_state\n"
+\ wait for the reset to happen after DONE
+RTC_CNTL_COCPU_CTRL_REG RTC_CNTL_COCPU_SHUT_2_CLK_DIS_S 8 0x3F
+WRITE_RTC_REG.BUILDER >C
+\ reset the processor when it is done
+RTC_CNTL_COCPU_CTRL_REG RTC_CNTL_COCPU_SHUT_RESET_EN_S 1 1
+WRITE_RTC_REG.BUILDER >C
+\ done! this must be the final write
+RTC_CNTL_COCPU_CTRL_REG RTC_CNTL_COCPU_DONE_S 1 1
+WRITE_RTC_REG.BUILDER >C
+C" __halt.0:\n"
+C" j __halt.0" \ wait for the reset
+3 C> C> C> + + + \ add up the strings and the built instructions
+ASSEMBLY-RISCV HALT
+0 0 LAST SET-STACK-EFFECT \ ( -- )
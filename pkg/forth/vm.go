@@ -15,6 +15,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -33,7 +34,11 @@ type VirtualMachine struct {
 	IP               *CellAddress       // The interpreter pointer.
 	Base             VMNumber           // The number base.
 	Out              io.Writer          // The output for the vm.
+	Trace            bool               // Log every executed word.
+	TraceOut         io.Writer          // The output for the trace, Out if nil.
 	repl             *readline.Instance // The repl instance
+	traceLevel       int                // The nesting of the traced words.
+	loadingTarget    bool               // The target libraries are loading, they redefine words on purpose.
 }
 
 // Set up the virtual machine.
@@ -100,28 +105,42 @@ func (vm *VirtualMachine) Setup() error {
 //go:embed builtin/*.f
 var builtins embed.FS
 
-//go:embed esp32/*.f
-var builtinsEsp32 embed.FS
+//go:embed ulp/*.f ulp_fsm/*.f ulp_fsm_sx/*.f ulp_riscv/*.f esp32/*.f esp32s2/*.f esp32s3/*.f esp32sx/*.f
+var builtinsTarget embed.FS
 
-func (vm *VirtualMachine) buildEmbed(f embed.FS, name string) error {
-	dirEntries, err := f.ReadDir(name)
-	if err != nil {
-		return errors.Join(fmt.Errorf("error while opening embedded directory"), err)
-	}
-	for _, entry := range dirEntries {
-		// don't look in subdirectories (for now)
-		if !entry.IsDir() {
-			file, err := f.Open(name + "/" + entry.Name())
-			if err != nil {
-				return err
-			}
-			defer file.Close()
-			err = vm.ExecuteFile(file)
-			if err != nil {
-				return err
+func (vm *VirtualMachine) buildEmbed(f embed.FS, names ...string) error {
+	type file struct {
+		dir  string
+		name string
+	}
+	files := make([]file, 0)
+	for _, name := range names {
+		dirEntries, err := f.ReadDir(name)
+		if err != nil {
+			return errors.Join(fmt.Errorf("error while opening embedded directory"), err)
+		}
+		for _, entry := range dirEntries {
+			// don't look in subdirectories (for now)
+			if !entry.IsDir() {
+				files = append(files, file{name, entry.Name()})
 			}
 		}
 	}
+	// the directories are run together, ordered by the file names
+	slices.SortStableFunc(files, func(a, b file) int {
+		return strings.Compare(a.name, b.name)
+	})
+	for _, entry := range files {
+		file, err := f.Open(entry.dir + "/" + entry.name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		err = vm.ExecuteFile(file)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -130,9 +149,19 @@ func (vm *VirtualMachine) builtin() error {
 	return vm.buildEmbed(builtins, "builtin")
 }
 
+// BuiltinTarget runs the files intended for accessing the hardware of the target.
+func (vm *VirtualMachine) BuiltinTarget(target Target) error {
+	if int(target) < 0 || int(target) >= len(targets) {
+		return fmt.Errorf("unknown target %s", target)
+	}
+	vm.loadingTarget = true
+	defer func() { vm.loadingTarget = false }()
+	return vm.buildEmbed(builtinsTarget, targets[target].libraries...)
+}
+
 // BuiltinEsp32 runs the files intended for accessing esp32 hardware.
 func (vm *VirtualMachine) BuiltinEsp32() error {
-	return vm.buildEmbed(builtinsEsp32, "esp32")
+	return vm.BuiltinTarget(TargetEsp32)
 }
 
 // writerNoNewline is an io.Writer that does not print newlines.
@@ -285,9 +314,67 @@ func (vm *VirtualMachine) Reset() error {
 	vm.DoStack.Reset()
 	// and the instruction pointer
 	vm.IP = nil
+	vm.traceLevel = 0
 	return nil
 }
 
+// Log the start of a forth word, the words it executes are indented.
+func (vm *VirtualMachine) traceEnter(c CellAddress) {
+	fmt.Fprintf(vm.traceOut(), "%s: %s\n", strings.Repeat("  ", vm.traceLevel), vm.traceName(c))
+	vm.traceLevel += 1
+}
+
+// Log an executed word with the return stack depth and the data stack.
+// EXIT is shown by the line of the word that returns.
+func (vm *VirtualMachine) traceExit(c CellAddress, err error) {
+	if c.Entry.Flag.isExit && err == nil {
+		return
+	}
+	result := fmt.Sprintf("r:%d %s", vm.ReturnStack.Depth(), vm.traceStack())
+	if err != nil {
+		result = "failed"
+	}
+	fmt.Fprintf(vm.traceOut(), "%s%s %s\n", strings.Repeat("  ", vm.traceLevel), vm.traceName(c), result)
+}
+
+func (vm *VirtualMachine) traceOut() io.Writer {
+	if vm.TraceOut != nil {
+		return vm.TraceOut
+	}
+	return vm.Out
+}
+
+// The data stack with the addresses shown by name.
+func (vm *VirtualMachine) traceStack() string {
+	cells := make([]string, len(vm.Stack.stack))
+	for i, c := range vm.Stack.stack {
+		if addr, ok := c.(CellAddress); ok {
+			cells[i] = vm.traceName(addr)
+		} else {
+			cells[i] = fmt.Sprint(c)
+		}
+	}
+	return "[" + strings.Join(cells, " ") + "]"
+}
+
+// The name of an executed word, with the offset that
+// DOES> and similar words execute at. Words without a
+// name are numbered by their place in the dictionary
+// so the trace is the same every time.
+func (vm *VirtualMachine) traceName(c CellAddress) string {
+	name := c.Entry.Name
+	if name == "" {
+		name = "(noname)"
+		if i := slices.Index(vm.Dictionary.Entries, c.Entry); i >= 0 {
+			name = fmt.Sprintf("(noname %d)", i)
+		}
+	}
+	if c.Offset != 0 {
+		name += fmt.Sprintf("+%d", c.Offset)
+	}
+	return name
+}
+
 func (vm *VirtualMachine) getCells(name string) ([]Cell, error) {
 	// check in dictionary for the name
 	entry, dictErr := vm.Dictionary.FindName(name)
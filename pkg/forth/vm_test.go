@@ -0,0 +1,114 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package forth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		expect string
+	}{
+		{
+			name: "nested",
+			code: ": SQ DUP * ; : CUBE DUP SQ * ; TRACE-ON 3 CUBE TRACE-OFF DROP",
+			expect: `: CUBE
+  DUP r:1 [3 3]
+  : SQ
+    DUP r:2 [3 3 3]
+    * r:2 [3 9]
+  SQ r:1 [3 9]
+  * r:1 [27]
+CUBE r:0 [27]
+`,
+		},
+		{
+			name: "off inside a word",
+			code: ": STOP 1 TRACE-OFF 2 ; TRACE-ON STOP 3 DROP DROP DROP",
+			expect: `: STOP
+`,
+		},
+		{
+			// START began before the trace
+			name: "on inside a word",
+			code: ": START 1 TRACE-ON 2 ; START SWAP TRACE-OFF DROP DROP",
+			expect: `SWAP r:0 [2 1]
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var trace bytes.Buffer
+			vm := VirtualMachine{Out: &out, TraceOut: &trace}
+			err := vm.Setup()
+			if err != nil {
+				t.Fatalf("failed to set up vm: %s", err)
+			}
+			err = vm.Execute([]byte(tt.code))
+			if err != nil {
+				t.Fatalf("failed to execute test code: %s", err)
+			}
+			if trace.String() != tt.expect {
+				t.Errorf("expected trace:\n%s\ngot:\n%s", tt.expect, trace.String())
+			}
+			if out.Len() != 0 {
+				t.Errorf("expected the trace to only go to TraceOut, got %q", out.String())
+			}
+			if vm.Stack.Depth() != 0 {
+				t.Errorf("expected an empty stack, got %s", vm.Stack)
+			}
+		})
+	}
+}
+
+func TestTraceVariable(t *testing.T) {
+	var trace bytes.Buffer
+	vm := VirtualMachine{TraceOut: &trace}
+	err := vm.Setup()
+	if err != nil {
+		t.Fatalf("failed to set up vm: %s", err)
+	}
+	err = vm.Execute([]byte("VARIABLE X 5 X ! TRACE-ON X @ TRACE-OFF DROP"))
+	if err != nil {
+		t.Fatalf("failed to execute test code: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	last := lines[len(lines)-1]
+	if last != "@ r:0 [5]" {
+		t.Errorf("expected the fetch to be traced last, got %q in:\n%s", last, trace.String())
+	}
+}
+
+func TestTraceStable(t *testing.T) {
+	traces := make([]string, 2)
+	for i := range traces {
+		var trace bytes.Buffer
+		vm := VirtualMachine{TraceOut: &trace}
+		err := vm.Setup()
+		if err != nil {
+			t.Fatalf("failed to set up vm: %s", err)
+		}
+		err = vm.Execute([]byte("VARIABLE X :NONAME X ; TRACE-ON DUP EXECUTE TRACE-OFF DROP DROP"))
+		if err != nil {
+			t.Fatalf("failed to execute test code: %s", err)
+		}
+		traces[i] = trace.String()
+	}
+	if traces[0] != traces[1] {
+		t.Errorf("expected the same trace every run, got:\n%s\nand:\n%s", traces[0], traces[1])
+	}
+	if !strings.Contains(traces[0], ": (noname ") || strings.Contains(traces[0], "0x") {
+		t.Errorf("expected the words to be named, got:\n%s", traces[0])
+	}
+}
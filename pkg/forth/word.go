@@ -115,6 +115,11 @@ func (w *WordForth) BuildAssembly(u *Ulp) (string, error) {
 	output := make([]string, 1)
 	label := w.Entry.ulpName + ":"
 	bodyLabel := w.Entry.BodyLabel() + ":"
+	if u.mixed != nil && u.mixed.hot[w] {
+		// the token threaded stub has the normal labels
+		label = w.Entry.ulpName + MixedSuffix + ":"
+		bodyLabel = w.Entry.BodyLabel() + MixedSuffix + ":"
+	}
 	output[0] = label
 	if w.Entry.Flag.Data { // data word
 		output = append(output, bodyLabel)
@@ -123,31 +128,81 @@ func (w *WordForth) BuildAssembly(u *Ulp) (string, error) {
 			if err != nil {
 				return "", err
 			}
-			_, ok := cell.(CellAddress)
-			if ok {
+			address, ok := cell.(CellAddress)
+			if ok && u.compileTarget == UlpCompileTargetRiscv {
+				// the address is divided into cells when the program starts
+				fixup := u.name("fixup", "", true)
+				u.fixups = append(u.fixups, fixup)
+				output = append(output, fixup+":")
+				ref = address.riscvReference()
+			} else if ok {
 				ref = "__body" + ref
 			}
 			val := ".int " + ref
 			output = append(output, val)
 		}
+	} else if u.compileTarget == UlpCompileTargetRiscv { // executable forth word on the ULP-RISC-V
+		if w.Entry.Flag.calls != 0 { // if this word is directly called
+			output = append(output, riscvDocol...)
+		}
+		// literal addresses are read by >BODY, keep them aligned
+		output = append(output, ".balign 4", bodyLabel)
+		for _, cell := range w.Cells {
+			asm, err := cell.BuildExecution(u)
+			if err != nil {
+				return "", err
+			}
+			output = append(output, asm)
+		}
 	} else { // executable forth word
-		if u.compileTarget == UlpCompileTargetSubroutine {
+		if u.compileTarget == UlpCompileTargetSubroutine || u.compileTarget == UlpCompileTargetDirect {
 			if w.Entry.Flag.calls != 0 { // if this word is directly called
 				output = append(output, "jump __docol")
 			}
 		}
 		output = append(output, bodyLabel)
+		stale := false // r2 does not hold the address of the current cell
 		for _, cell := range w.Cells {
+			if stale && srtNeedsAddress(cell) {
+				// inlined primitives don't update r2, restore it
+				label := u.name("inline", "", true)
+				output = append(output, "move r2, "+label, label+":")
+				stale = false
+			}
 			asm, err := cell.BuildExecution(u)
 			if err != nil {
 				return "", err
 			}
 			output = append(output, asm)
+			switch cell.(type) {
+			case *CellInline:
+				stale = true
+			case *CellDestination:
+				// a branch here sets r2 to this address,
+				// so only the fallthrough path matters
+			default:
+				stale = false
+			}
 		}
 	}
 	return strings.Join(output, "\r\n"), nil
 }
 
+// Check if the subroutine threaded code of a cell
+// requires r2 to hold the address of that code.
+func srtNeedsAddress(c Cell) bool {
+	switch cell := c.(type) {
+	case *CellInline, *CellDestination, *CellBranch:
+		return false
+	case *CellTailCall:
+		return cell.fallsThrough // the destination starts executing here
+	case CellAddress:
+		return !cell.Entry.Flag.isExit
+	default:
+		return true
+	}
+}
+
 func (w *WordForth) IsRecursive(check *WordForth) bool {
 	if w == check {
 		return true
@@ -180,8 +235,9 @@ const (
 // The ULP assembly for a primitive Word that uses token threading.
 // type PrimitiveUlp []string
 type PrimitiveUlp struct {
-	Asm  []string
-	Next TokenNextType
+	Asm    []string
+	Next   TokenNextType
+	Direct []string // the assembly used by direct threading instead, if it needs to be different
 }
 
 // The ULP assembly for a primitive Word that uses subroutine threading
@@ -190,12 +246,23 @@ type PrimitiveUlpSrt struct {
 	NonStandardNext bool     // this word uses a nonstandard NEXT ending
 }
 
+// The RISC-V assembly for a primitive Word, used by the
+// ULP-RISC-V of the esp32-s2 and esp32-s3. Primitives are
+// called with the return address in ra.
+type PrimitiveUlpRiscv struct {
+	Asm             []string // the assembly, not including the return if standard
+	NonStandardNext bool     // this word uses a nonstandard return
+}
+
 // A Word defined using Go and ULP assembly.
 type WordPrimitive struct {
-	Go     PrimitiveGo      // The Go function to be executed.
-	Ulp    PrimitiveUlp     // The ULP assembly to be compiled.
-	UlpSrt PrimitiveUlpSrt  // The ULP assembly using subroutine threading to be compiled.
-	Entry  *DictionaryEntry // The associated dictionary entry.
+	Go           PrimitiveGo       // The Go function to be executed.
+	Ulp          PrimitiveUlp      // The ULP assembly to be compiled.
+	UlpSrt       PrimitiveUlpSrt   // The ULP assembly using subroutine threading to be compiled.
+	UlpRiscv     PrimitiveUlpRiscv // The RISC-V assembly to be compiled for the ULP-RISC-V.
+	Effect       StackEffect       // The declared stack effect.
+	ReturnEffect StackEffect       // The declared return stack effect, if it uses the return stack.
+	Entry        *DictionaryEntry  // The associated dictionary entry.
 }
 
 func (w *WordPrimitive) Execute(vm *VirtualMachine) error {
@@ -219,11 +286,23 @@ func (w *WordPrimitive) BuildAssembly(u *Ulp) (string, error) {
 	bodyLabel := w.Entry.BodyLabel() + ":\r\n"
 	asm := make([]string, 0)
 	switch u.compileTarget {
-	case UlpCompileTargetToken:
+	case UlpCompileTargetToken, UlpCompileTargetDirect: // the direct threaded interpreter has the same labels
 		if len(w.Ulp.Asm) == 0 {
 			return "", EntryError(w.Entry, "does not have any subroutine threaded assembly")
 		}
-		asm = append(asm, w.Ulp.Asm...)
+		if u.compileTarget == UlpCompileTargetDirect && w.Ulp.Next != TokenNextSkipLoad {
+			// direct threading doesn't store the instruction pointer
+			// before every primitive, only before the ones that need it
+			asm = append(asm, "st r1, r2, __ip")
+		}
+		// the body is written by hand, only the code around it is optimized
+		asm = append(asm, peepholeFixedStart)
+		if u.compileTarget == UlpCompileTargetDirect && len(w.Ulp.Direct) != 0 {
+			asm = append(asm, w.Ulp.Direct...)
+		} else {
+			asm = append(asm, w.Ulp.Asm...)
+		}
+		asm = append(asm, peepholeFixedEnd)
 		switch w.Ulp.Next {
 		case TokenNextNonstandard:
 		case TokenNextNormal:
@@ -239,7 +318,9 @@ func (w *WordPrimitive) BuildAssembly(u *Ulp) (string, error) {
 		if len(w.UlpSrt.Asm) == 0 {
 			return "", EntryError(w.Entry, "does not have any subroutine threaded assembly")
 		}
+		asm = append(asm, peepholeFixedStart)
 		asm = append(asm, w.UlpSrt.Asm...)
+		asm = append(asm, peepholeFixedEnd)
 		if !w.UlpSrt.NonStandardNext {
 			standardNext := []string{
 				"add r2, r2, 1",
@@ -247,6 +328,16 @@ func (w *WordPrimitive) BuildAssembly(u *Ulp) (string, error) {
 			}
 			asm = append(asm, standardNext...)
 		}
+	case UlpCompileTargetRiscv:
+		if len(w.UlpRiscv.Asm) == 0 {
+			return "", EntryError(w.Entry, "does not have any RISC-V assembly")
+		}
+		asm = append(asm, w.UlpRiscv.Asm...)
+		if !w.UlpRiscv.NonStandardNext {
+			asm = append(asm, "ret")
+		}
+		// execution tokens are in cells, keep the start aligned
+		label = ".balign 4\r\n" + label
 	default:
 		return "", fmt.Errorf("unknown compile target %d, please file a bug report", u.compileTarget)
 	}
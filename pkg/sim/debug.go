@@ -0,0 +1,402 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Molorius/ulp-forth/pkg/forth"
+)
+
+// Runs a program in the emulator a word or an instruction at a time,
+// using the labels of the build to find the words.
+type Debugger struct {
+	Sim *Simulator
+
+	labels      map[string]int    // the byte address of every label
+	words       []forth.UlpSymbol // the words, sorted by address
+	tokens      map[int]string    // the name of the word of each token
+	code        map[int]string    // the name of the word whose code starts at each cell
+	bodies      map[int]string    // the name of the forth word whose code body starts at each cell
+	threads     map[int]string    // the name of the forth word whose cells start at each cell
+	breakpoints map[string]bool   // the upper case names of words to stop at
+	starting    string            // the word that is about to start, or empty
+	entered     string            // the word that last started, its body is not a new start
+	dispatch    int               // the cell where the token interpreter runs a token, or -1
+	load        int               // the cell where the interpreter loads the cell before r1, or -1
+	ip          int               // the cell of the token instruction pointer, or -1
+	rsp         int               // the cell of the return stack pointer, or -1
+	stackStart  int               // the first cell of the stack section
+	stackEnd    int               // the cell after the stack section
+}
+
+// Create a debugger for the simulator. The labels are the byte
+// addresses of every label in the assembled program, the map
+// is created from the same labels by the build.
+func NewDebugger(s *Simulator, labels map[string]int, m forth.UlpMap) *Debugger {
+	d := Debugger{
+		Sim:         s,
+		labels:      labels,
+		words:       make([]forth.UlpSymbol, 0),
+		tokens:      make(map[int]string),
+		code:        make(map[int]string),
+		bodies:      make(map[int]string),
+		threads:     make(map[int]string),
+		breakpoints: make(map[string]bool),
+	}
+	cell := func(label string) int {
+		addr, ok := labels[label]
+		if !ok {
+			return -1
+		}
+		return addr / 4
+	}
+	d.dispatch = cell("__ins_asm")
+	d.load = -1
+	if next := cell("__next_skip_load"); next >= 0 {
+		d.load = next + 1 // after moving r1 past the cell
+	}
+	if cell("__push") >= 0 {
+		// direct threading runs the code of every word,
+		// it only dispatches a token for EXECUTE
+		d.dispatch = -1
+	}
+	d.ip = cell("__ip")
+	d.rsp = cell("__rsp")
+	d.stackStart = cell("__stack_start")
+	d.stackEnd = cell("__stack_end")
+	for _, symbol := range m.Symbols {
+		switch symbol.Kind {
+		case forth.MapForth, forth.MapAssembly, forth.MapData:
+		default:
+			continue
+		}
+		d.words = append(d.words, symbol)
+		d.tokens[symbol.Address/4] = symbol.Word
+		if symbol.Kind == forth.MapData {
+			continue
+		}
+		// the token interpreter only runs code directly in
+		// the subroutine threaded words of a mixed build
+		srt := d.load < 0 || strings.HasSuffix(symbol.Label, forth.MixedSuffix)
+		if d.dispatch < 0 || srt {
+			d.code[symbol.Address/4] = symbol.Word
+		}
+		// tail calls and EXECUTE go straight to the body
+		body, ok := labels["__body"+symbol.Label]
+		if symbol.Kind != forth.MapForth || !ok {
+			continue
+		}
+		if srt {
+			d.bodies[body/4] = symbol.Word
+		} else {
+			d.threads[body/4] = symbol.Word
+		}
+	}
+	slices.SortStableFunc(d.words, func(a, b forth.UlpSymbol) int {
+		return a.Address - b.Address
+	})
+	d.update()
+	return &d
+}
+
+// Stop when the word starts. Returns an error if the
+// word is not part of the program.
+func (d *Debugger) Break(name string) error {
+	name = strings.ToUpper(name)
+	for _, w := range d.words {
+		if strings.ToUpper(w.Word) == name {
+			d.breakpoints[name] = true
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not in the program", name)
+}
+
+// Stop stopping at the word.
+func (d *Debugger) Delete(name string) {
+	delete(d.breakpoints, strings.ToUpper(name))
+}
+
+// The names of the words with breakpoints, sorted.
+func (d *Debugger) Breakpoints() []string {
+	names := make([]string, 0, len(d.breakpoints))
+	for name := range d.breakpoints {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// The word that is about to start, if any. With token threading
+// this is the token that the interpreter is about to run,
+// otherwise it is the word whose code is about to run. A tail
+// call or EXECUTE into the body of a word also starts it.
+func (d *Debugger) Starting() (string, bool) {
+	return d.starting, d.starting != ""
+}
+
+// Find the word that is about to start after an instruction runs.
+// Calling a word starts it once, not again when its body starts.
+func (d *Debugger) update() {
+	ip := int(d.Sim.Emu.IP)
+	d.starting = ""
+	if ip == d.load {
+		// a branch or falling through into the cells of a word
+		if name, ok := d.threads[int(d.Sim.Emu.R[1])-1]; ok {
+			if name != d.entered {
+				d.starting = name
+			}
+			d.entered = ""
+		}
+		return
+	}
+	if ip == d.dispatch {
+		d.starting = d.tokens[int(d.Sim.Emu.R[0])]
+		d.entered = d.starting
+		return
+	}
+	if name, ok := d.bodies[ip]; ok {
+		if name == d.entered {
+			d.entered = ""
+			return
+		}
+		d.starting = name
+		return
+	}
+	if name, ok := d.code[ip]; ok {
+		d.starting = name
+		d.entered = name
+	}
+}
+
+// Run a single instruction.
+func (d *Debugger) StepInstruction() error {
+	err := d.Sim.Step()
+	d.update()
+	return err
+}
+
+// Run until the next word starts.
+func (d *Debugger) StepWord() error {
+	return d.runUntil(func(name string) bool {
+		return true
+	})
+}
+
+// Run until a word with a breakpoint starts.
+func (d *Debugger) Continue() error {
+	return d.runUntil(d.isBreakpoint)
+}
+
+// Check if the word has a breakpoint.
+func (d *Debugger) isBreakpoint(name string) bool {
+	return d.breakpoints[strings.ToUpper(name)]
+}
+
+// Run until a word that matches starts.
+func (d *Debugger) runUntil(match func(name string) bool) error {
+	return d.Sim.RunUntil(d.starts(match))
+}
+
+// A stop condition that is true when a word that matches starts.
+// It must be checked after every instruction.
+func (d *Debugger) starts(match func(name string) bool) func() bool {
+	return func() bool {
+		d.update()
+		name, ok := d.Starting()
+		return ok && match(name)
+	}
+}
+
+// The word that contains the cell, with the offset into it,
+// such as MAIN+2. Returns an empty string if there isn't one.
+func (d *Debugger) Name(cell int) string {
+	// the last word that starts at or before the cell
+	i := sort.Search(len(d.words), func(i int) bool {
+		return d.words[i].Address > cell*4
+	}) - 1
+	if i < 0 {
+		return ""
+	}
+	w := d.words[i]
+	if cell*4 >= w.Address+max(w.Size, 4) {
+		return ""
+	}
+	offset := cell - w.Address/4
+	if offset == 0 {
+		return w.Word
+	}
+	return fmt.Sprintf("%s+%d", w.Word, offset)
+}
+
+// The name of the word with the token or body at the
+// cell, such as an execution token on the data stack.
+func (d *Debugger) word(cell int) string {
+	if name, ok := d.tokens[cell]; ok {
+		return name
+	}
+	if name, ok := d.bodies[cell]; ok {
+		return name
+	}
+	return d.threads[cell]
+}
+
+// The data stack, the top of the stack is last.
+func (d *Debugger) DataStack() []uint16 {
+	top := int(d.Sim.Emu.R[3])
+	if d.stackEnd < 0 || top > d.stackEnd || top < d.stackStart {
+		return nil
+	}
+	stack := make([]uint16, 0)
+	for cell := d.stackEnd - 1; cell >= top; cell-- {
+		stack = append(stack, d.cell(cell))
+	}
+	return stack
+}
+
+// The return stack, the top of the stack is last.
+func (d *Debugger) ReturnStack() []uint16 {
+	if d.rsp < 0 || d.stackStart < 0 {
+		return nil
+	}
+	top := int(d.cell(d.rsp))
+	if top < d.stackStart || top >= d.stackEnd {
+		return nil
+	}
+	stack := make([]uint16, 0)
+	for cell := d.stackStart + 1; cell <= top; cell++ {
+		stack = append(stack, d.cell(cell))
+	}
+	return stack
+}
+
+// The lower 16 bits of a cell of memory.
+func (d *Debugger) cell(cell int) uint16 {
+	if cell < 0 || cell >= len(d.Sim.Emu.Memory) {
+		return 0
+	}
+	return uint16(d.Sim.Emu.Memory[cell])
+}
+
+// Describe where the program stopped and its stacks.
+func (d *Debugger) Status(w io.Writer) {
+	ip := int(d.Sim.Emu.IP)
+	where := d.Name(ip)
+	if name, ok := d.Starting(); ok {
+		where = "starting " + name
+	}
+	if where != "" {
+		where = " " + where
+	}
+	fmt.Fprintf(w, "ip 0x%04X%s, %d cycles\n", ip, where, d.Sim.Cycles)
+	if d.ip >= 0 {
+		fmt.Fprintf(w, "__ip 0x%04X %s\n", d.cell(d.ip), d.Name(int(d.cell(d.ip))))
+	}
+	fmt.Fprintf(w, "r0 0x%04X r1 0x%04X r2 0x%04X r3 0x%04X\n", d.Sim.Emu.R[0], d.Sim.Emu.R[1], d.Sim.Emu.R[2], d.Sim.Emu.R[3])
+	fmt.Fprint(w, "data:")
+	for _, n := range d.DataStack() {
+		fmt.Fprintf(w, " %d", n)
+		if name := d.word(int(n)); name != "" {
+			fmt.Fprintf(w, "<%s>", name)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprint(w, "return:")
+	for _, n := range d.ReturnStack() {
+		name := d.Name(int(n))
+		if name == "" {
+			name = fmt.Sprintf("0x%04X", n)
+		}
+		fmt.Fprintf(w, " %s", name)
+	}
+	fmt.Fprintln(w)
+}
+
+const debugHelp = `break WORD   (b)  stop when WORD starts
+delete WORD  (d)  remove the breakpoint on WORD
+breakpoints       list the breakpoints
+step [N]     (s)  run until the next N words start
+stepi [N]    (si) run N instructions
+continue     (c)  run until a breakpoint, the end or a limit
+status            show where the program is and its stacks
+quit         (q)  stop debugging
+`
+
+// Read debugger commands until the input ends or quit is entered.
+// Returns nil once the program finishes, or the error that stopped it.
+func (d *Debugger) Repl(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(out, "(debug) ")
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			fmt.Fprint(out, "(debug) ")
+			continue
+		}
+		command, args := fields[0], fields[1:]
+		count := 1
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err == nil && n > 0 {
+				count = n
+			}
+		}
+		var err error
+		switch command {
+		case "break", "b":
+			for _, name := range args {
+				err = d.Break(name)
+				if err != nil {
+					fmt.Fprintln(out, err)
+				}
+			}
+			err = nil
+		case "delete", "d":
+			for _, name := range args {
+				d.Delete(name)
+			}
+		case "breakpoints":
+			fmt.Fprintln(out, strings.Join(d.Breakpoints(), " "))
+		case "step", "s":
+			for i := 0; i < count && err == nil && !d.Sim.Done; i++ {
+				err = d.StepWord()
+			}
+			d.Status(out)
+		case "stepi", "si":
+			for i := 0; i < count && err == nil && !d.Sim.Done; i++ {
+				err = d.StepInstruction()
+			}
+			d.Status(out)
+		case "continue", "c":
+			err = d.Continue()
+			d.Status(out)
+		case "status":
+			d.Status(out)
+		case "quit", "q":
+			return nil
+		default:
+			fmt.Fprint(out, debugHelp)
+		}
+		if err != nil {
+			return err
+		}
+		if d.Sim.Done {
+			fmt.Fprintln(out, "the program finished")
+			return nil
+		}
+		fmt.Fprint(out, "(debug) ")
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Molorius/ulp-c/pkg/asm"
+	"github.com/Molorius/ulp-forth/pkg/forth"
+)
+
+const debugCode = `
+: SQ DUP * ;
+: MAIN 3 SQ u. 4 SQ u. ESP.DONE ;
+`
+
+// The threading models that the debugger follows.
+var debugBuilders = []struct {
+	name  string
+	build func(*forth.Ulp, *forth.VirtualMachine, string) (string, error)
+}{
+	{"token", (*forth.Ulp).BuildAssembly},
+	{"subroutine", (*forth.Ulp).BuildAssemblySrt},
+	{"direct", (*forth.Ulp).BuildAssemblyDirect},
+	{"mixed", (*forth.Ulp).BuildAssemblyMixed},
+}
+
+// Build the code and load it into a debugger.
+func debugger(t *testing.T, code string, subroutine bool) (*Debugger, *bytes.Buffer) {
+	t.Helper()
+	if subroutine {
+		return debuggerBuild(t, code, (*forth.Ulp).BuildAssemblySrt)
+	}
+	return debuggerBuild(t, code, (*forth.Ulp).BuildAssembly)
+}
+
+// Build the code with the builder and load it into a debugger.
+func debuggerBuild(t *testing.T, code string, build func(*forth.Ulp, *forth.VirtualMachine, string) (string, error)) (*Debugger, *bytes.Buffer) {
+	t.Helper()
+	vm := setupVm(t, code)
+	ulp := forth.Ulp{}
+	assembly, err := build(&ulp, vm, "MAIN")
+	if err != nil {
+		t.Fatalf("failed to generate assembly: %s", err)
+	}
+	assembler := asm.Assembler{}
+	bin, err := assembler.BuildFile(assembly, "test.S", 8176, false)
+	if err != nil {
+		t.Fatalf("failed to assemble: %s", err)
+	}
+	c := assembler.Compiler
+	labels := make(map[string]int)
+	for name, label := range c.Labels {
+		labels[name] = label.Value
+	}
+	sections := []forth.UlpSection{
+		{Name: ".boot", Offset: c.Boot.Offset, Size: c.Boot.Size},
+		{Name: ".text", Offset: c.Text.Offset, Size: c.Text.Size},
+		{Name: ".data", Offset: c.Data.Offset, Size: c.Data.Size},
+	}
+	out := bytes.Buffer{}
+	s := Simulator{Out: &out, MaxCycles: 1_000_000}
+	err = s.Load(bin)
+	if err != nil {
+		t.Fatalf("failed to load: %s", err)
+	}
+	return NewDebugger(&s, labels, ulp.Map(labels, sections)), &out
+}
+
+func TestDebugBreak(t *testing.T) {
+	for _, subroutine := range []bool{false, true} {
+		d, out := debugger(t, debugCode, subroutine)
+		err := d.Break("sq")
+		if err != nil {
+			t.Fatalf("failed to set breakpoint: %s", err)
+		}
+		err = d.Break("NOT-A-WORD")
+		if err == nil {
+			t.Errorf("expected an error for a missing word")
+		}
+		for _, expect := range []uint16{3, 4} {
+			err = d.Continue()
+			if err != nil {
+				t.Fatalf("failed to continue: %s", err)
+			}
+			name, ok := d.Starting()
+			if !ok || name != "SQ" {
+				t.Fatalf("subroutine %v: expected to stop at SQ, stopped at %q", subroutine, name)
+			}
+			stack := d.DataStack()
+			if len(stack) != 1 || stack[0] != expect {
+				t.Errorf("subroutine %v: expected data stack [%d], got %v", subroutine, expect, stack)
+			}
+		}
+		// the next word uses the value before SQ returns
+		err = d.StepWord()
+		if err != nil {
+			t.Fatalf("failed to step: %s", err)
+		}
+		if _, ok := d.Starting(); !ok {
+			t.Errorf("subroutine %v: expected a word to be starting", subroutine)
+		}
+		returns := d.ReturnStack()
+		if len(returns) == 0 || d.Name(int(returns[len(returns)-1])) == "" {
+			t.Errorf("subroutine %v: expected a named return address, got %v", subroutine, returns)
+		}
+		d.Delete("SQ")
+		err = d.Continue()
+		if err != nil {
+			t.Fatalf("failed to continue: %s", err)
+		}
+		if !d.Sim.Done {
+			t.Errorf("subroutine %v: expected the program to finish", subroutine)
+		}
+		if got := strings.TrimSpace(out.String()); got != "9 16" {
+			t.Errorf("subroutine %v: expected output \"9 16\", got %q", subroutine, got)
+		}
+	}
+}
+
+func TestDebugTailCall(t *testing.T) {
+	code := `
+		: SQ DUP * ;
+		: TWO 2 + SQ ;
+		: RUN EXECUTE u. ;
+		: MAIN 3 TWO u. 4 TWO u. 5 SQ u. 6 ['] SQ RUN ESP.DONE ;
+	`
+	for _, b := range debugBuilders {
+		t.Run(b.name, func(t *testing.T) {
+			d, out := debuggerBuild(t, code, b.build)
+			err := d.Break("SQ")
+			if err != nil {
+				t.Fatalf("failed to set breakpoint: %s", err)
+			}
+			// through TWO, directly, then through EXECUTE
+			for _, expect := range []uint16{5, 6, 5, 6} {
+				err = d.Continue()
+				if err != nil {
+					t.Fatalf("failed to continue: %s", err)
+				}
+				if name, ok := d.Starting(); !ok || name != "SQ" {
+					t.Fatalf("expected to stop at SQ, stopped at %q", name)
+				}
+				stack := d.DataStack()
+				if len(stack) == 0 || stack[len(stack)-1] != expect {
+					t.Errorf("expected %d on top of the data stack, got %v", expect, stack)
+				}
+			}
+			err = d.Continue()
+			if err != nil {
+				t.Fatalf("failed to continue: %s", err)
+			}
+			if !d.Sim.Done {
+				t.Errorf("expected the program to finish, stopped at %s", d.Name(int(d.Sim.Emu.IP)))
+			}
+			if got := strings.TrimSpace(out.String()); got != "25 36 25 36" {
+				t.Errorf("expected output \"25 36 25 36\", got %q", got)
+			}
+		})
+	}
+}
+
+func TestDebugStatusToken(t *testing.T) {
+	d, _ := debugger(t, ": SQ DUP * ; : MAIN 3 ['] SQ EXECUTE u. ESP.DONE ;", false)
+	err := d.Break("EXECUTE")
+	if err != nil {
+		t.Fatalf("failed to set breakpoint: %s", err)
+	}
+	err = d.Continue()
+	if err != nil {
+		t.Fatalf("failed to continue: %s", err)
+	}
+	status := bytes.Buffer{}
+	d.Status(&status)
+	if !strings.Contains(status.String(), "<SQ>\n") {
+		t.Errorf("expected the token on the stack to be named:\n%s", status.String())
+	}
+}
+
+func TestDebugStepInstruction(t *testing.T) {
+	d, _ := debugger(t, debugCode, false)
+	for i := 1; i <= 10; i++ {
+		err := d.StepInstruction()
+		if err != nil {
+			t.Fatalf("failed to step: %s", err)
+		}
+		if d.Sim.Cycles == 0 {
+			t.Fatalf("expected cycles to pass")
+		}
+	}
+}
+
+func TestDebugRepl(t *testing.T) {
+	d, _ := debugger(t, debugCode, false)
+	in := strings.NewReader("break SQ\nbreakpoints\ncontinue\nstep\nhelp\nquit\n")
+	out := bytes.Buffer{}
+	err := d.Repl(in, &out)
+	if err != nil {
+		t.Fatalf("repl failed: %s", err)
+	}
+	for _, expect := range []string{") SQ\n", "starting SQ", "data: 3\n", "starting DUP", "return: VM.INIT+", "stepi [N]"} {
+		if !strings.Contains(out.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, out.String())
+		}
+	}
+	in = strings.NewReader("delete SQ\ncontinue\n")
+	out.Reset()
+	err = d.Repl(in, &out)
+	if err != nil {
+		t.Fatalf("repl failed: %s", err)
+	}
+	if !strings.Contains(out.String(), "the program finished") {
+		t.Errorf("expected the program to finish:\n%s", out.String())
+	}
+}
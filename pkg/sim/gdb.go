@@ -0,0 +1,463 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// The signals sent to GDB when the program stops.
+const (
+	gdbSigInt  = 2  // interrupted with ctrl-c
+	gdbSigIll  = 4  // the emulator failed to run an instruction
+	gdbSigTrap = 5  // a breakpoint or a single step
+	gdbSigXcpu = 24 // the cycle limit or the timeout was reached
+)
+
+// The number of registers sent to GDB: r0 to r3 then the pc.
+const gdbRegisters = 5
+
+// Describes the registers to GDB. The pc is a byte address
+// so that it matches the labels and memory addresses.
+// The registers, there is no <architecture> element
+// because GDB does not have one for the ULP-FSM.
+const gdbTargetXml = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.gnu.gdb.ulp.core">
+    <reg name="r0" bitsize="32" regnum="0"/>
+    <reg name="r1" bitsize="32"/>
+    <reg name="r2" bitsize="32"/>
+    <reg name="r3" bitsize="32"/>
+    <reg name="pc" bitsize="32" type="code_ptr"/>
+  </feature>
+</target>
+`
+
+const gdbMonitorHelp = `symbols [PREFIX]  list the labels that start with PREFIX and their addresses
+break WORD        stop when WORD starts
+delete WORD       remove the breakpoint on WORD
+status            show where the program is and its stacks
+`
+
+// Serves a subset of the GDB remote serial protocol for a
+// debugger: reading and writing registers and memory, software
+// breakpoints, stepping and continuing. Addresses are in bytes.
+// With token threading a breakpoint on the address of a word
+// also stops when the interpreter is about to run the word.
+// The labels are available with "monitor symbols", and
+// breakpoints can be set on word names with "monitor break".
+type GdbServer struct {
+	Debugger *Debugger
+
+	breakpoints map[int]bool // the cells with breakpoints
+	interrupted atomic.Bool  // GDB sent ctrl-c
+	noAck       bool         // GDB turned off acknowledgments
+	w           *bufio.Writer
+}
+
+// Create a server for the debugger.
+func NewGdbServer(d *Debugger) *GdbServer {
+	return &GdbServer{
+		Debugger:    d,
+		breakpoints: make(map[int]bool),
+	}
+}
+
+// Serve GDB over the connection until it detaches, kills the
+// program or closes the connection.
+func (g *GdbServer) Serve(conn io.ReadWriter) error {
+	g.w = bufio.NewWriter(conn)
+	packets := make(chan gdbPacket)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- g.read(bufio.NewReader(conn), packets)
+		close(packets)
+	}()
+	for packet := range packets {
+		if !packet.valid {
+			if !g.noAck {
+				g.w.WriteByte('-') // ask for it again
+				g.w.Flush()
+			}
+			continue
+		}
+		if !g.noAck {
+			g.w.WriteByte('+')
+		}
+		reply, done := g.handle(packet.data)
+		err := g.send(reply)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+	err := <-errs
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// A packet read from GDB.
+type gdbPacket struct {
+	data  string
+	valid bool // the checksum matched
+}
+
+// Read packets from GDB until the connection closes. Ctrl-c
+// is handled right away so that it can stop a running program.
+func (g *GdbServer) read(r *bufio.Reader, packets chan<- gdbPacket) error {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case 0x03:
+			g.interrupted.Store(true)
+			continue
+		case '$':
+		default:
+			continue // acknowledgments and noise
+		}
+		data, err := r.ReadString('#')
+		if err != nil {
+			return err
+		}
+		data = data[:len(data)-1]
+		sum := make([]byte, 2)
+		_, err = io.ReadFull(r, sum)
+		if err != nil {
+			return err
+		}
+		expect, err := strconv.ParseUint(string(sum), 16, 8)
+		packets <- gdbPacket{
+			data:  data,
+			valid: err == nil && uint8(expect) == checksum(data),
+		}
+	}
+}
+
+// Send a reply packet.
+func (g *GdbServer) send(data string) error {
+	fmt.Fprintf(g.w, "$%s#%02x", data, checksum(data))
+	return g.w.Flush()
+}
+
+// The sum of the bytes of a packet, modulo 256.
+func checksum(data string) uint8 {
+	sum := uint8(0)
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// Handle a packet and create the reply. Also returns true
+// when GDB is finished with the program.
+func (g *GdbServer) handle(packet string) (string, bool) {
+	if packet == "" {
+		return "", false
+	}
+	emu := &g.Debugger.Sim.Emu
+	args := packet[1:]
+	switch packet[0] {
+	case '?':
+		return g.stopReply(nil, false), false
+	case 'g':
+		s := ""
+		for i := 0; i < gdbRegisters; i++ {
+			s += hexUint32(g.register(i))
+		}
+		return s, false
+	case 'G':
+		b, err := hex.DecodeString(args)
+		if err != nil || len(b) != 4*gdbRegisters {
+			return "E01", false
+		}
+		for i := 0; i < gdbRegisters; i++ {
+			g.setRegister(i, le32(b[4*i:]))
+		}
+		return "OK", false
+	case 'p':
+		n, err := strconv.ParseUint(args, 16, 32)
+		if err != nil || n >= gdbRegisters {
+			return "E01", false
+		}
+		return hexUint32(g.register(int(n))), false
+	case 'P':
+		reg, value, ok := strings.Cut(args, "=")
+		n, err := strconv.ParseUint(reg, 16, 32)
+		b, hexErr := hex.DecodeString(value)
+		if !ok || err != nil || hexErr != nil || n >= gdbRegisters || len(b) != 4 {
+			return "E01", false
+		}
+		g.setRegister(int(n), le32(b))
+		return "OK", false
+	case 'm':
+		addr, length, ok := addressLength(args)
+		if !ok || addr+length > 4*len(emu.Memory) {
+			return "E01", false
+		}
+		b := make([]byte, length)
+		for i := range b {
+			b[i] = g.memoryByte(addr + i)
+		}
+		return hex.EncodeToString(b), false
+	case 'M':
+		location, value, _ := strings.Cut(args, ":")
+		addr, length, ok := addressLength(location)
+		b, err := hex.DecodeString(value)
+		if !ok || err != nil || len(b) != length || addr+length > 4*len(emu.Memory) {
+			return "E01", false
+		}
+		for i, v := range b {
+			g.setMemoryByte(addr+i, v)
+		}
+		return "OK", false
+	case 'Z', 'z':
+		// software and hardware breakpoints are the same
+		fields := strings.Split(args, ",")
+		if len(fields) < 2 || (fields[0] != "0" && fields[0] != "1") {
+			return "", false
+		}
+		addr, err := strconv.ParseUint(fields[1], 16, 32)
+		if err != nil {
+			return "E01", false
+		}
+		if packet[0] == 'Z' {
+			g.breakpoints[int(addr/4)] = true
+		} else {
+			delete(g.breakpoints, int(addr/4))
+		}
+		return "OK", false
+	case 's':
+		g.resume(args)
+		err := g.Debugger.StepInstruction()
+		return g.stopReply(err, false), false
+	case 'c':
+		g.resume(args)
+		g.interrupted.Store(false)
+		err := g.Debugger.Sim.RunUntil(g.stop())
+		return g.stopReply(err, g.interrupted.Load()), false
+	case 'H':
+		return "OK", false // there is only one thread
+	case 'D':
+		return "OK", true
+	case 'k':
+		return "", true
+	case 'q', 'Q':
+		return g.query(packet), false
+	}
+	return "", false
+}
+
+// Handle the general queries and settings.
+func (g *GdbServer) query(packet string) string {
+	switch {
+	case strings.HasPrefix(packet, "qSupported"):
+		return "PacketSize=4000;qXfer:features:read+;QStartNoAckMode+"
+	case packet == "QStartNoAckMode":
+		g.noAck = true
+		return "OK"
+	case packet == "qAttached":
+		return "1"
+	case packet == "qC":
+		return "QC1"
+	case packet == "qfThreadInfo":
+		return "m1"
+	case packet == "qsThreadInfo":
+		return "l"
+	case strings.HasPrefix(packet, "qXfer:features:read:target.xml:"):
+		return xferReply(gdbTargetXml, strings.TrimPrefix(packet, "qXfer:features:read:target.xml:"))
+	case strings.HasPrefix(packet, "qRcmd,"):
+		command, err := hex.DecodeString(strings.TrimPrefix(packet, "qRcmd,"))
+		if err != nil {
+			return "E01"
+		}
+		out := g.monitor(string(command))
+		if out == "" {
+			return "OK"
+		}
+		return hex.EncodeToString([]byte(out))
+	}
+	return ""
+}
+
+// Run a command sent with "monitor" and return its output.
+func (g *GdbServer) monitor(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return gdbMonitorHelp
+	}
+	d := g.Debugger
+	out := bytes.Buffer{}
+	switch fields[0] {
+	case "symbols":
+		prefix := ""
+		if len(fields) > 1 {
+			prefix = fields[1]
+		}
+		labels := d.labels
+		names := make([]string, 0)
+		for name := range labels {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+		slices.SortFunc(names, func(a, b string) int {
+			if labels[a] != labels[b] {
+				return labels[a] - labels[b]
+			}
+			return strings.Compare(a, b)
+		})
+		for _, name := range names {
+			fmt.Fprintf(&out, "0x%04x %s\n", labels[name], name)
+		}
+	case "break":
+		for _, name := range fields[1:] {
+			err := d.Break(name)
+			if err != nil {
+				fmt.Fprintln(&out, err)
+			}
+		}
+	case "delete":
+		for _, name := range fields[1:] {
+			d.Delete(name)
+		}
+	case "status":
+		d.Status(&out)
+	default:
+		return gdbMonitorHelp
+	}
+	return out.String()
+}
+
+// The stop condition for continuing: a breakpoint on the
+// instruction, on the token about to run or on the word
+// about to start, or an interrupt from GDB.
+func (g *GdbServer) stop() func() bool {
+	d := g.Debugger
+	word := d.starts(d.isBreakpoint)
+	return func() bool {
+		started := word() // follows the words on every instruction
+		if g.interrupted.Load() {
+			return true
+		}
+		ip := int(d.Sim.Emu.IP)
+		if g.breakpoints[ip] || (ip == d.dispatch && g.breakpoints[int(d.Sim.Emu.R[0])]) {
+			return true
+		}
+		return started
+	}
+}
+
+// Continue or step from a new address, if one was sent.
+func (g *GdbServer) resume(addr string) {
+	if addr == "" {
+		return
+	}
+	n, err := strconv.ParseUint(addr, 16, 32)
+	if err == nil {
+		g.Debugger.Sim.Emu.IP = uint16(n / 4)
+	}
+}
+
+// The reply describing why the program stopped.
+func (g *GdbServer) stopReply(err error, interrupted bool) string {
+	switch {
+	case g.Debugger.Sim.Done:
+		return "W00"
+	case errors.Is(err, ErrCycleLimit) || errors.Is(err, ErrTimeout):
+		return fmt.Sprintf("S%02x", gdbSigXcpu)
+	case err != nil:
+		return fmt.Sprintf("S%02x", gdbSigIll)
+	case interrupted:
+		return fmt.Sprintf("S%02x", gdbSigInt)
+	}
+	return fmt.Sprintf("S%02x", gdbSigTrap)
+}
+
+// The value of a register in the order sent to GDB.
+func (g *GdbServer) register(n int) uint32 {
+	emu := &g.Debugger.Sim.Emu
+	if n < 4 {
+		return uint32(emu.R[n])
+	}
+	return uint32(emu.IP) * 4
+}
+
+// Set a register in the order sent to GDB.
+func (g *GdbServer) setRegister(n int, value uint32) {
+	emu := &g.Debugger.Sim.Emu
+	if n < 4 {
+		emu.R[n] = uint16(value)
+		return
+	}
+	emu.IP = uint16(value / 4)
+}
+
+// A byte of memory, the cells are little endian.
+func (g *GdbServer) memoryByte(addr int) byte {
+	return byte(g.Debugger.Sim.Emu.Memory[addr/4] >> (8 * (addr % 4)))
+}
+
+// Set a byte of memory.
+func (g *GdbServer) setMemoryByte(addr int, value byte) {
+	cell := &g.Debugger.Sim.Emu.Memory[addr/4]
+	shift := 8 * (addr % 4)
+	*cell = *cell&^(0xFF<<shift) | uint32(value)<<shift
+}
+
+// Parse the "addr,length" of a memory packet.
+func addressLength(s string) (int, int, bool) {
+	a, l, ok := strings.Cut(s, ",")
+	addr, err := strconv.ParseUint(a, 16, 32)
+	length, lengthErr := strconv.ParseUint(l, 16, 32)
+	if !ok || err != nil || lengthErr != nil {
+		return 0, 0, false
+	}
+	return int(addr), int(length), true
+}
+
+// The reply to a qXfer read of "offset,length" from the document.
+func xferReply(document string, offsetLength string) string {
+	offset, length, ok := addressLength(offsetLength)
+	if !ok {
+		return "E01"
+	}
+	if offset >= len(document) {
+		return "l"
+	}
+	if offset+length >= len(document) {
+		return "l" + document[offset:]
+	}
+	return "m" + document[offset:offset+length]
+}
+
+// The hex of a little endian 32 bit value.
+func hexUint32(v uint32) string {
+	return hex.EncodeToString([]byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)})
+}
+
+// A little endian 32 bit value.
+func le32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
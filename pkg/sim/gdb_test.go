@@ -0,0 +1,205 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// A GDB client connected to a server on a local socket.
+type gdbClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Start a server for the debugger on a local socket and connect to it.
+func connectGdb(t *testing.T, g *GdbServer) *gdbClient {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	errs := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		listener.Close()
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer conn.Close()
+		errs <- g.Serve(conn)
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		err := <-errs
+		if err != nil {
+			t.Errorf("server failed: %s", err)
+		}
+	})
+	return &gdbClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// Send a packet and return the reply.
+func (c *gdbClient) send(packet string) string {
+	c.t.Helper()
+	_, err := fmt.Fprintf(c.conn, "$%s#%02x", packet, checksum(packet))
+	if err != nil {
+		c.t.Fatalf("failed to send %q: %s", packet, err)
+	}
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			c.t.Fatalf("failed to read the reply to %q: %s", packet, err)
+		}
+		if b == '$' {
+			break
+		}
+	}
+	reply, err := c.r.ReadString('#')
+	if err != nil {
+		c.t.Fatalf("failed to read the reply to %q: %s", packet, err)
+	}
+	sum := make([]byte, 2)
+	_, err = c.r.Read(sum)
+	if err != nil {
+		c.t.Fatalf("failed to read the checksum of %q: %s", packet, err)
+	}
+	reply = reply[:len(reply)-1]
+	if fmt.Sprintf("%02x", checksum(reply)) != string(sum) {
+		c.t.Errorf("bad checksum %s for %q", sum, reply)
+	}
+	c.conn.Write([]byte("+"))
+	return reply
+}
+
+// Send the packet and check the reply.
+func (c *gdbClient) expect(packet string, expect string) {
+	c.t.Helper()
+	reply := c.send(packet)
+	if reply != expect {
+		c.t.Errorf("expected %q in reply to %q, got %q", expect, packet, reply)
+	}
+}
+
+// Run a monitor command and return the output.
+func (c *gdbClient) monitor(command string) string {
+	c.t.Helper()
+	reply := c.send("qRcmd," + hex.EncodeToString([]byte(command)))
+	if reply == "OK" {
+		return ""
+	}
+	out, err := hex.DecodeString(reply)
+	if err != nil {
+		c.t.Fatalf("bad monitor reply %q: %s", reply, err)
+	}
+	return string(out)
+}
+
+// The byte address of the top of the data stack, from r3.
+func (c *gdbClient) stackAddress() int {
+	c.t.Helper()
+	reply := c.send("p3")
+	b, err := hex.DecodeString(reply)
+	if err != nil || len(b) != 4 {
+		c.t.Fatalf("bad register reply %q", reply)
+	}
+	return 4 * int(le32(b))
+}
+
+// The address of the label that starts with the prefix.
+func labelAddress(t *testing.T, labels map[string]int, prefix string) int {
+	t.Helper()
+	for name, addr := range labels {
+		if strings.HasPrefix(name, prefix) {
+			return addr
+		}
+	}
+	t.Fatalf("no label starts with %s", prefix)
+	return 0
+}
+
+func TestGdb(t *testing.T) {
+	for _, subroutine := range []bool{false, true} {
+		d, out := debugger(t, debugCode, subroutine)
+		c := connectGdb(t, NewGdbServer(d))
+
+		if reply := c.send("qSupported:multiprocess+"); !strings.Contains(reply, "qXfer:features:read+") {
+			t.Errorf("expected the target description to be supported, got %q", reply)
+		}
+		if reply := c.send("qXfer:features:read:target.xml:0,1000"); !strings.HasPrefix(reply, "l<?xml") {
+			t.Errorf("expected the target description, got %q", reply)
+		}
+		c.expect("?", "S05")
+		c.expect("g", strings.Repeat("00", 4*gdbRegisters))
+
+		symbols := c.monitor("symbols __forth_SQ")
+		sq := labelAddress(t, d.labels, "__forth_SQ")
+		if !strings.Contains(symbols, fmt.Sprintf("0x%04x __forth_SQ", sq)) {
+			t.Errorf("expected SQ in the symbols, got %q", symbols)
+		}
+
+		// step a single instruction
+		c.expect("s", "S05")
+		if reply := c.send("p4"); reply == "00000000" {
+			t.Errorf("expected the pc to move")
+		}
+
+		// stop at the word and read the top of the stack,
+		// the upper half of each cell is set by st
+		c.expect(fmt.Sprintf("Z0,%x,4", sq), "OK")
+		for _, expect := range []string{"0300", "0400"} {
+			c.expect("c", "S05")
+			if status := c.monitor("status"); !strings.Contains(status, "starting SQ") {
+				t.Errorf("subroutine %v: expected to stop at SQ, got %q", subroutine, status)
+			}
+			c.expect(fmt.Sprintf("m%x,2", c.stackAddress()), expect)
+		}
+		c.expect(fmt.Sprintf("z0,%x,4", sq), "OK")
+
+		// change the top of the stack
+		top := c.stackAddress()
+		c.expect(fmt.Sprintf("M%x,4:05000000", top), "OK")
+		c.expect(fmt.Sprintf("m%x,4", top), "05000000")
+		c.expect("m10000,4", "E01")
+
+		c.expect("c", "W00")
+		if got := strings.TrimSpace(out.String()); got != "9 25" {
+			t.Errorf("subroutine %v: expected output \"9 25\", got %q", subroutine, got)
+		}
+		c.expect("D", "OK")
+	}
+}
+
+func TestGdbMonitorBreak(t *testing.T) {
+	d, _ := debugger(t, debugCode, false)
+	c := connectGdb(t, NewGdbServer(d))
+	if out := c.monitor("break SQ"); out != "" {
+		t.Errorf("expected no output, got %q", out)
+	}
+	c.expect("c", "S05")
+	if status := c.monitor("status"); !strings.Contains(status, "starting SQ") {
+		t.Errorf("expected to stop at SQ, got %q", status)
+	}
+	if out := c.monitor("break NOT-A-WORD"); !strings.Contains(out, "not in the program") {
+		t.Errorf("expected an error, got %q", out)
+	}
+	c.monitor("delete SQ")
+	c.expect("c", "W00")
+	c.expect("k", "")
+}
@@ -0,0 +1,435 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Molorius/ulp-forth/pkg/forth"
+)
+
+// A device on the simulated I2C bus. The bus handles the bits,
+// the device only sees whole bytes.
+type I2cDevice interface {
+	Address() uint8       // the 7 bit address
+	Start(read bool) bool // the device was addressed, return true to ack
+	Write(b uint8) bool   // the master wrote a byte, return true to ack
+	Read() uint8          // the next byte that the master reads
+	Stretch() int         // the number of clock reads to hold the clock low for after an ack
+}
+
+// The kind of an I2C event.
+type I2cKind int
+
+const (
+	I2cStart   I2cKind = iota // a start or repeated start condition
+	I2cStop                   // a stop condition
+	I2cWrite                  // the master wrote a byte, including the address
+	I2cRead                   // the master read a byte
+	I2cStretch                // a device held the clock low
+)
+
+// An event on the I2C bus.
+type I2cEvent struct {
+	Kind  I2cKind
+	Byte  uint8 // the byte written or read
+	Ack   bool  // the byte was acknowledged
+	Polls int   // the number of times the clock was read while stretched
+}
+
+func (e I2cEvent) String() string {
+	ack := "NACK"
+	if e.Ack {
+		ack = "ACK"
+	}
+	switch e.Kind {
+	case I2cStart:
+		return "START"
+	case I2cStop:
+		return "STOP"
+	case I2cWrite:
+		return fmt.Sprintf("W 0x%02X %s", e.Byte, ack)
+	case I2cRead:
+		return fmt.Sprintf("R 0x%02X %s", e.Byte, ack)
+	case I2cStretch:
+		return fmt.Sprintf("STRETCH %d", e.Polls)
+	}
+	return "UNKNOWN"
+}
+
+// The state of the devices on the bus.
+type i2cState int
+
+const (
+	i2cIdle      i2cState = iota // waiting for a start condition
+	i2cAddress                   // receiving the address
+	i2cWrite                     // receiving a byte from the master
+	i2cAck                       // the device is sending an ack
+	i2cRead                      // sending a byte to the master
+	i2cMasterAck                 // the master is sending an ack
+)
+
+// A simulated open drain I2C bus with pullups. The master
+// releases or pulls down each line, the devices are the
+// slaves. It can be driven by the I2C deferred words in the
+// host virtual machine or by RTC GPIO in the emulator.
+type I2cBus struct {
+	Devices []I2cDevice
+	Log     []I2cEvent // the events on the bus
+	Err     error      // the first protocol error by the master
+	Sda     int        // the RTC GPIO of the data line, when used by the emulator
+	Scl     int        // the RTC GPIO of the clock line, when used by the emulator
+
+	setup     bool
+	masterSda bool // the master released the data line
+	masterScl bool // the master released the clock line
+	deviceSda bool // the device released the data line
+	stretch   int  // the clock reads left until the device releases the clock
+	polls     int  // the clock reads during this stretch
+	state     i2cState
+	bits      int
+	shift     uint8
+	ack       bool
+	read      bool
+	device    I2cDevice
+}
+
+// Create a bus with both lines released.
+func (b *I2cBus) init() {
+	if b.setup {
+		return
+	}
+	b.setup = true
+	b.masterSda = true
+	b.masterScl = true
+	b.deviceSda = true
+}
+
+// The level of the data line.
+func (b *I2cBus) sda() bool {
+	b.init()
+	return b.masterSda && b.deviceSda
+}
+
+// The level of the clock line.
+func (b *I2cBus) scl() bool {
+	b.init()
+	return b.masterScl && b.stretch == 0
+}
+
+// Release (true) or pull down (false) the data line.
+func (b *I2cBus) SetSda(high bool) {
+	prev := b.sda()
+	b.masterSda = high
+	now := b.sda()
+	if prev == now || !b.scl() {
+		return
+	}
+	if now {
+		b.stop()
+	} else {
+		b.start()
+	}
+}
+
+// Release (true) or pull down (false) the clock line.
+func (b *I2cBus) SetScl(high bool) {
+	prev := b.scl()
+	if !high && b.masterScl && b.stretch != 0 && b.Err == nil {
+		b.Err = fmt.Errorf("the clock was lowered while a device was stretching it")
+	}
+	b.masterScl = high
+	now := b.scl()
+	if prev == now {
+		return
+	}
+	if now {
+		b.rise()
+	} else {
+		b.fall()
+	}
+}
+
+// Read the data line.
+func (b *I2cBus) GetSda() bool {
+	return b.sda()
+}
+
+// Read the clock line. A stretching device releases the
+// clock after it has been read enough times.
+func (b *I2cBus) GetScl() bool {
+	level := b.scl()
+	b.poll()
+	return level
+}
+
+// Count a read of the clock line while it is stretched.
+func (b *I2cBus) poll() {
+	if !b.masterScl || b.stretch == 0 {
+		return
+	}
+	b.polls += 1
+	b.stretch -= 1
+	if b.stretch == 0 {
+		b.Log = append(b.Log, I2cEvent{Kind: I2cStretch, Polls: b.polls})
+		b.rise()
+	}
+}
+
+func (b *I2cBus) start() {
+	b.Log = append(b.Log, I2cEvent{Kind: I2cStart})
+	b.state = i2cAddress
+	b.bits = 0
+	b.shift = 0
+	b.device = nil
+	b.deviceSda = true
+}
+
+func (b *I2cBus) stop() {
+	b.Log = append(b.Log, I2cEvent{Kind: I2cStop})
+	b.state = i2cIdle
+	b.device = nil
+	b.deviceSda = true
+}
+
+// The clock went high, sample the data line.
+func (b *I2cBus) rise() {
+	switch b.state {
+	case i2cAddress, i2cWrite:
+		b.shift <<= 1
+		if b.sda() {
+			b.shift |= 1
+		}
+		b.bits += 1
+	case i2cMasterAck:
+		b.ack = !b.sda()
+	}
+}
+
+// The clock went low, the devices change the data line.
+func (b *I2cBus) fall() {
+	switch b.state {
+	case i2cAddress:
+		if b.bits != 8 {
+			return
+		}
+		b.read = b.shift&1 != 0
+		b.device = b.find(b.shift >> 1)
+		b.ack = b.device != nil && b.device.Start(b.read)
+		b.received()
+	case i2cWrite:
+		if b.bits != 8 {
+			return
+		}
+		b.ack = b.device.Write(b.shift)
+		b.received()
+	case i2cAck:
+		b.deviceSda = true
+		switch {
+		case !b.ack:
+			b.state = i2cIdle
+		case b.read:
+			b.send()
+		default:
+			b.state = i2cWrite
+			b.bits = 0
+			b.shift = 0
+		}
+		if b.ack {
+			b.stretch = b.device.Stretch()
+			b.polls = 0
+		}
+	case i2cRead:
+		b.bits += 1
+		if b.bits == 8 {
+			b.deviceSda = true // let the master ack
+			b.state = i2cMasterAck
+			return
+		}
+		b.deviceSda = b.shift&(0x80>>b.bits) != 0
+	case i2cMasterAck:
+		b.Log = append(b.Log, I2cEvent{Kind: I2cRead, Byte: b.shift, Ack: b.ack})
+		if b.ack {
+			b.send()
+		} else {
+			b.state = i2cIdle
+		}
+	}
+}
+
+// A byte was received, send the ack.
+func (b *I2cBus) received() {
+	b.Log = append(b.Log, I2cEvent{Kind: I2cWrite, Byte: b.shift, Ack: b.ack})
+	b.deviceSda = !b.ack
+	b.state = i2cAck
+}
+
+// Start sending the next byte to the master.
+func (b *I2cBus) send() {
+	b.shift = b.device.Read()
+	b.bits = 0
+	b.deviceSda = b.shift&0x80 != 0
+	b.state = i2cRead
+}
+
+// The device with the address, or nil.
+func (b *I2cBus) find(address uint8) I2cDevice {
+	for _, d := range b.Devices {
+		if d.Address() == address {
+			return d
+		}
+	}
+	return nil
+}
+
+// The log as one event per line.
+func (b *I2cBus) String() string {
+	lines := make([]string, len(b.Log))
+	for i, e := range b.Log {
+		lines[i] = e.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Bind the I2C deferred words of the virtual machine to the bus,
+// so the I2C words can run on the host.
+func (b *I2cBus) BindVm(vm *forth.VirtualMachine) error {
+	set := func(name string, f func(bool)) error {
+		return b.addPrimitive(vm, name, func(vm *forth.VirtualMachine, entry *forth.DictionaryEntry) error {
+			f(strings.HasSuffix(name, "HIGH"))
+			return nil
+		})
+	}
+	get := func(name string, f func() bool) error {
+		return b.addPrimitive(vm, name, func(vm *forth.VirtualMachine, entry *forth.DictionaryEntry) error {
+			n := uint16(0)
+			if f() {
+				n = 1
+			}
+			return vm.Stack.Push(forth.CellNumber{Number: n})
+		})
+	}
+	errs := []error{
+		set("SDA_HIGH", b.SetSda),
+		set("SDA_LOW", b.SetSda),
+		get("SDA_GET", b.GetSda),
+		set("SCL_HIGH", b.SetScl),
+		set("SCL_LOW", b.SetScl),
+		get("SCL_GET", b.GetScl),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add a host primitive named SIM.I2C.name and make I2C.name use it.
+func (b *I2cBus) addPrimitive(vm *forth.VirtualMachine, name string, f forth.PrimitiveGo) error {
+	entry := &forth.DictionaryEntry{Name: "SIM.I2C." + name}
+	entry.Word = &forth.WordPrimitive{Go: f, Entry: entry}
+	err := vm.Dictionary.AddEntry(entry)
+	if err != nil {
+		return err
+	}
+	return vm.Execute([]byte(fmt.Sprintf("' SIM.I2C.%s IS I2C.%s", name, name)))
+}
+
+// Bind the I2C deferred words of the virtual machine to the RTC GPIO
+// words of the pins, and use the pins for the bus. The bus is used
+// by the emulator once it is set as the I2c of the RTC model.
+func (b *I2cBus) BindRtc(vm *forth.VirtualMachine, sda int, scl int) error {
+	b.Sda = sda
+	b.Scl = scl
+	code := fmt.Sprintf(`
+		' RTC_GPIO%[1]d.OUTPUT_DISABLE IS I2C.SDA_HIGH
+		' RTC_GPIO%[1]d.OUTPUT_ENABLE IS I2C.SDA_LOW
+		' RTC_GPIO%[1]d.GET IS I2C.SDA_GET
+		' RTC_GPIO%[2]d.OUTPUT_DISABLE IS I2C.SCL_HIGH
+		' RTC_GPIO%[2]d.OUTPUT_ENABLE IS I2C.SCL_LOW
+		' RTC_GPIO%[2]d.GET IS I2C.SCL_GET
+	`, sda, scl)
+	return vm.Execute([]byte(code))
+}
+
+// An EEPROM with a register file. The first byte written after
+// the address sets the register, the rest are written to the
+// registers in order. Reads start at the register.
+type Eeprom struct {
+	Addr      uint8
+	Memory    [256]uint8
+	Register  uint8
+	addressed bool // the register was set in this write
+}
+
+func (e *Eeprom) Address() uint8 { return e.Addr }
+
+func (e *Eeprom) Start(read bool) bool {
+	e.addressed = read
+	return true
+}
+
+func (e *Eeprom) Write(b uint8) bool {
+	if !e.addressed {
+		e.Register = b
+		e.addressed = true
+		return true
+	}
+	e.Memory[e.Register] = b
+	e.Register += 1
+	return true
+}
+
+func (e *Eeprom) Read() uint8 {
+	b := e.Memory[e.Register]
+	e.Register += 1
+	return b
+}
+
+func (e *Eeprom) Stretch() int { return 0 }
+
+// A sensor that holds the clock low after every byte
+// it acknowledges, such as while taking a measurement.
+// Its registers work like the Eeprom.
+type Sensor struct {
+	Eeprom
+	Polls int // the number of clock reads to hold the clock low for
+}
+
+func (s *Sensor) Stretch() int { return s.Polls }
+
+// A device that acknowledges its address and the first
+// bytes written to it, then stops acknowledging.
+type NackDevice struct {
+	Addr    uint8
+	Accept  int     // the number of bytes acknowledged after each start
+	Written []uint8 // the bytes that were acknowledged
+	count   int
+}
+
+func (n *NackDevice) Address() uint8 { return n.Addr }
+
+func (n *NackDevice) Start(read bool) bool {
+	n.count = 0
+	return true
+}
+
+func (n *NackDevice) Write(b uint8) bool {
+	if n.count >= n.Accept {
+		return false
+	}
+	n.count += 1
+	n.Written = append(n.Written, b)
+	return true
+}
+
+func (n *NackDevice) Read() uint8 { return 0xFF }
+
+func (n *NackDevice) Stretch() int { return 0 }
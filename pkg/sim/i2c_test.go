@@ -0,0 +1,196 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Molorius/ulp-forth/pkg/forth"
+)
+
+const i2cCode = `
+: EE.WRITE ( n reg -- )
+	0x50 I2C.START_WRITE DROP I2C.WRITE DROP I2C.WRITE DROP I2C.STOP ;
+: READ2 ( reg addr -- hi lo )
+	DUP >R I2C.START_WRITE DROP I2C.WRITE DROP
+	R> I2C.START_READ DROP I2C.READ I2C.ACK I2C.READ I2C.NACK I2C.STOP ;
+: NACKS ( -- ack ack ack )
+	0x20 I2C.START_WRITE 1 I2C.WRITE 2 I2C.WRITE I2C.STOP ;
+: TEST
+	0xAB 0x10 EE.WRITE
+	0x10 0x50 READ2 SWAP U. U.
+	0 0x40 READ2 SWAP U. U.
+	NACKS ROT U. SWAP U. U.
+	0x33 I2C.START_WRITE I2C.STOP U. ;
+`
+
+const i2cOutput = "171 0 18 52 65535 65535 0 0 "
+
+const i2cLog = `START
+W 0xA0 ACK
+W 0x10 ACK
+W 0xAB ACK
+STOP
+START
+W 0xA0 ACK
+W 0x10 ACK
+START
+W 0xA1 ACK
+R 0xAB ACK
+R 0x00 NACK
+STOP
+START
+W 0x80 ACK
+STRETCH 3
+W 0x00 ACK
+STRETCH 3
+START
+W 0x81 ACK
+STRETCH 3
+R 0x12 ACK
+R 0x34 NACK
+STOP
+START
+W 0x40 ACK
+W 0x01 ACK
+W 0x02 NACK
+STOP
+START
+W 0x66 NACK
+STOP`
+
+// A bus with an EEPROM at 0x50, a stretching sensor at 0x40
+// and a device at 0x20 that only accepts one byte.
+func testBus() (*I2cBus, *Eeprom, *NackDevice) {
+	eeprom := &Eeprom{Addr: 0x50}
+	sensor := &Sensor{Eeprom: Eeprom{Addr: 0x40}, Polls: 3}
+	sensor.Memory[0] = 0x12
+	sensor.Memory[1] = 0x34
+	nack := &NackDevice{Addr: 0x20, Accept: 1}
+	return &I2cBus{Devices: []I2cDevice{eeprom, sensor, nack}}, eeprom, nack
+}
+
+func checkBus(t *testing.T, bus *I2cBus, eeprom *Eeprom, nack *NackDevice) {
+	t.Helper()
+	if bus.Err != nil {
+		t.Error(bus.Err)
+	}
+	if bus.String() != i2cLog {
+		t.Errorf("expected log:\n%s\ngot:\n%s", i2cLog, bus.String())
+	}
+	if eeprom.Memory[0x10] != 0xAB {
+		t.Errorf("expected 0xAB in the eeprom got 0x%02X", eeprom.Memory[0x10])
+	}
+	if len(nack.Written) != 1 || nack.Written[0] != 1 {
+		t.Errorf("expected the device to accept [1] got %v", nack.Written)
+	}
+}
+
+func TestI2cHost(t *testing.T) {
+	var out bytes.Buffer
+	vm := forth.VirtualMachine{Out: &out}
+	err := vm.Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vm.BuiltinEsp32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus, eeprom, nack := testBus()
+	err = bus.BindVm(&vm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vm.Execute([]byte(i2cCode + " TEST"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != i2cOutput {
+		t.Errorf("expected \"%s\" got \"%s\"", i2cOutput, out.String())
+	}
+	checkBus(t, bus, eeprom, nack)
+}
+
+func TestI2cEmulator(t *testing.T) {
+	for _, subroutine := range []bool{false, true} {
+		vm := setupVm(t, i2cCode+": MAIN TEST ESP.DONE ;")
+		bus, eeprom, nack := testBus()
+		err := bus.BindRtc(vm, 7, 17)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		s := Simulator{Out: &out, MaxCycles: 1_000_000}
+		s.Rtc.I2c = bus
+		err = s.Load(buildVm(t, vm, subroutine))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != i2cOutput {
+			t.Errorf("expected \"%s\" got \"%s\"", i2cOutput, out.String())
+		}
+		checkBus(t, bus, eeprom, nack)
+	}
+}
+
+func TestI2cScan(t *testing.T) {
+	code, err := os.ReadFile("../../util/i2c_scan.f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// print to the host instead of serial
+	vm := setupVm(t, string(code)+" ' ESP.PRINTCHAR IS EMIT : MAIN MAIN ESP.DONE ;")
+	bus, _, _ := testBus()
+	bus.Sda = 7  // gpio26
+	bus.Scl = 17 // gpio27
+	var out bytes.Buffer
+	s := Simulator{Out: &out, MaxCycles: 10_000_000}
+	s.Rtc.I2c = bus
+	err = s.Load(buildVm(t, vm, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bus.Err != nil {
+		t.Error(bus.Err)
+	}
+	found := make([]uint8, 0)
+	probes := 0
+	for _, e := range bus.Log {
+		if e.Kind != I2cWrite {
+			continue
+		}
+		if e.Byte != uint8(probes)<<1 {
+			t.Fatalf("expected a write to 0x%02X got %s", probes, e)
+		}
+		probes += 1
+		if e.Ack {
+			found = append(found, e.Byte>>1)
+		}
+	}
+	if probes != 0x78 {
+		t.Errorf("expected 0x78 addresses to be probed, got 0x%02X", probes)
+	}
+	if len(found) != 3 || found[0] != 0x20 || found[1] != 0x40 || found[2] != 0x50 {
+		t.Errorf("expected devices at 0x20, 0x40 and 0x50, found %v", found)
+	}
+	if strings.Count(out.String(), "Device found") != 3 || !strings.Contains(out.String(), "Done.") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
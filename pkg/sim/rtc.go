@@ -0,0 +1,272 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+// The clocks used to convert ULP cycles to RTC timer ticks.
+const (
+	UlpClock     = 8_000_000 // the approximate frequency of the ULP
+	RtcSlowClock = 150_000   // the default RTC slow clock of the esp32
+)
+
+// The number of RTC GPIO pins on the esp32.
+const RtcGpioCount = 18
+
+// The esp32 RTC registers, as the 10 bit word address used by
+// reg_rd and reg_wr. These match the constants in esp32/02_reg_rtc.f
+// and esp32/03_reg_rtcio.f.
+const (
+	regTimeUpdate   = 0x003 // RTC_CNTL_TIME_UPDATE_REG
+	regTime0        = 0x004 // RTC_CNTL_TIME0_REG
+	regTime1        = 0x005 // RTC_CNTL_TIME1_REG
+	regLowPowerSt   = 0x030 // RTC_CNTL_LOW_POWER_ST_REG
+	regGpioOut      = 0x100 // RTCIO_RTC_GPIO_OUT_REG
+	regGpioOutW1ts  = 0x101 // RTCIO_RTC_GPIO_OUT_W1TS_REG
+	regGpioOutW1tc  = 0x102 // RTCIO_RTC_GPIO_OUT_W1TC_REG
+	regGpioEnable   = 0x103 // RTCIO_RTC_GPIO_ENABLE_REG
+	regGpioEnW1ts   = 0x104 // RTCIO_RTC_GPIO_ENABLE_W1TS_REG
+	regGpioEnW1tc   = 0x105 // RTCIO_RTC_GPIO_ENABLE_W1TC_REG
+	regGpioIn       = 0x109 // RTCIO_RTC_GPIO_IN_REG
+	regPadDac1      = 0x121 // RTCIO_PAD_DAC1_REG
+	regPadDac2      = 0x122 // RTCIO_PAD_DAC2_REG
+	regXtal32kPad   = 0x123 // RTCIO_XTAL_32K_PAD_REG
+	regTouchPad0    = 0x125 // RTCIO_TOUCH_PAD0_REG
+	gpioShift       = 14    // the bit of RTC GPIO 0 in the GPIO registers
+	timeUpdateBit   = 31    // RTC_CNTL_TIME_UPDATE_S
+	timeValidBit    = 30    // RTC_CNTL_TIME_VALID_S
+	stateInIdleBit  = 27    // RTC_CNTL_MAIN_STATE_IN_IDLE_S
+	rdyForWakeupBit = 19    // RTC_CNTL_RTC_RDY_FOR_WAKEUP_S
+)
+
+// The pad register and the pullup bit of an RTC GPIO.
+type pad struct {
+	reg    uint32
+	pullup uint32
+}
+
+// The pads of the RTC GPIO with pullups, pins 0 to 5 have none.
+var pads = map[int]pad{
+	6:  {regPadDac1, 27},
+	7:  {regPadDac2, 27},
+	8:  {regXtal32kPad, 27},
+	9:  {regXtal32kPad, 22},
+	10: {regTouchPad0, 27},
+	11: {regTouchPad0 + 1, 27},
+	12: {regTouchPad0 + 2, 27},
+	13: {regTouchPad0 + 3, 27},
+	14: {regTouchPad0 + 4, 27},
+	15: {regTouchPad0 + 5, 27},
+	16: {regTouchPad0 + 6, 27},
+	17: {regTouchPad0 + 7, 27},
+}
+
+// A scripted change to the level that is driven onto an input pin.
+type PinInput struct {
+	Cycle uint64 // the cycle that the change happens at
+	Pin   int    // the RTC GPIO number
+	Level bool   // the level driven onto the pin
+	Float bool   // stop driving the pin so it reads the pull resistors
+}
+
+// A recorded change to the output of a pin.
+type PinChange struct {
+	Cycle  uint64 // the cycle that the change happened at
+	Pin    int    // the RTC GPIO number
+	Level  bool   // the output level
+	Driven bool   // the output is enabled
+}
+
+// A model of the esp32 RTC_CNTL and RTCIO registers and the
+// RTC timer, as seen by the ULP. Registers that aren't modeled
+// keep the last value that was written to them.
+type Rtc struct {
+	Inputs  []PinInput  // the scripted inputs, in order of cycle
+	Changes []PinChange // the changes to the outputs
+	Wakes   []uint64    // the cycles that the ULP woke the main processor at
+	Awake   bool        // the main processor is awake
+	I2c     *I2cBus     // the I2C bus on the pins, if any
+
+	regs   map[uint32]uint32
+	next   int                  // the next scripted input
+	driven [RtcGpioCount]bool   // an input is driven onto the pin
+	levels [RtcGpioCount]bool   // the level driven onto the pin
+	output [RtcGpioCount]uint32 // the last recorded output, one bit each for level and driven
+}
+
+// Clear everything except the scripted inputs.
+func (r *Rtc) Reset() {
+	r.Changes = nil
+	r.Wakes = nil
+	r.Awake = false
+	r.regs = make(map[uint32]uint32)
+	r.next = 0
+	r.driven = [RtcGpioCount]bool{}
+	r.levels = [RtcGpioCount]bool{}
+	r.output = [RtcGpioCount]uint32{}
+}
+
+// Read bits low through high of a register, like reg_rd.
+func (r *Rtc) Read(addr uint32, high uint32, low uint32, cycle uint64) uint16 {
+	r.update(cycle)
+	value := r.register(addr) >> low
+	field := mask(high, low) << low
+	if r.I2c != nil && addr == regGpioIn && field&(1<<(gpioShift+r.I2c.Scl)) != 0 {
+		r.I2c.poll() // the clock was read
+	}
+	return uint16(value & mask(high, low))
+}
+
+// Write the data to bits low through high of a register, like reg_wr.
+func (r *Rtc) Write(addr uint32, high uint32, low uint32, data uint32, cycle uint64) {
+	r.update(cycle)
+	if r.regs == nil {
+		r.regs = make(map[uint32]uint32)
+	}
+	bits := (data & mask(high, low)) << low
+	field := mask(high, low) << low
+	switch addr {
+	case regGpioOutW1ts:
+		r.regs[regGpioOut] |= bits
+	case regGpioOutW1tc:
+		r.regs[regGpioOut] &^= bits
+	case regGpioEnW1ts:
+		r.regs[regGpioEnable] |= bits
+	case regGpioEnW1tc:
+		r.regs[regGpioEnable] &^= bits
+	case regTimeUpdate:
+		if bits&(1<<timeUpdateBit) != 0 {
+			// latch the timer, it is valid right away
+			ticks := cycle * RtcSlowClock / UlpClock
+			r.regs[regTime0] = uint32(ticks)
+			r.regs[regTime1] = uint32(ticks>>32) & 0xFFFF
+			r.regs[regTimeUpdate] |= 1 << timeValidBit
+		}
+	default:
+		r.regs[addr] = r.regs[addr]&^field | bits
+	}
+	r.record(cycle)
+	if r.I2c != nil {
+		r.I2c.SetSda(r.released(r.I2c.Sda))
+		r.I2c.SetScl(r.released(r.I2c.Scl))
+	}
+}
+
+// Check if a pin lets an open drain line float high,
+// which is anything other than driving it low.
+func (r *Rtc) released(pin int) bool {
+	level, driven := r.Output(pin)
+	return level || !driven
+}
+
+// The ULP woke the main processor.
+func (r *Rtc) Wake(cycle uint64) {
+	r.Wakes = append(r.Wakes, cycle)
+	r.Awake = true
+}
+
+// The output level of a pin, and if the output is enabled.
+func (r *Rtc) Output(pin int) (bool, bool) {
+	bit := uint32(1) << (gpioShift + pin)
+	level := r.regs[regGpioOut]&bit != 0
+	driven := r.regs[regGpioEnable]&bit != 0
+	return level, driven
+}
+
+// The level read from a pin. An I2C line reads the bus and an
+// output reads its own level, otherwise it reads the scripted
+// input or the pull resistors.
+func (r *Rtc) Level(pin int) bool {
+	if r.I2c != nil && pin == r.I2c.Sda {
+		return r.I2c.sda()
+	}
+	if r.I2c != nil && pin == r.I2c.Scl {
+		return r.I2c.scl()
+	}
+	level, driven := r.Output(pin)
+	if driven {
+		return level
+	}
+	if r.driven[pin] {
+		return r.levels[pin]
+	}
+	p, ok := pads[pin]
+	if ok {
+		return r.regs[p.reg]&(1<<p.pullup) != 0
+	}
+	return false
+}
+
+// The value of a register, including the ones computed from the model.
+func (r *Rtc) register(addr uint32) uint32 {
+	value := r.regs[addr]
+	switch addr {
+	case regGpioIn:
+		value = 0
+		for pin := 0; pin < RtcGpioCount; pin++ {
+			if r.Level(pin) {
+				value |= 1 << (gpioShift + pin)
+			}
+		}
+	case regLowPowerSt:
+		value &^= 1<<stateInIdleBit | 1<<rdyForWakeupBit
+		if r.Awake {
+			value |= 1 << stateInIdleBit
+		} else {
+			value |= 1 << rdyForWakeupBit
+		}
+	}
+	return value
+}
+
+// Apply the scripted inputs up to the cycle.
+func (r *Rtc) update(cycle uint64) {
+	for r.next < len(r.Inputs) && r.Inputs[r.next].Cycle <= cycle {
+		input := r.Inputs[r.next]
+		r.next += 1
+		if input.Pin < 0 || input.Pin >= RtcGpioCount {
+			continue
+		}
+		r.driven[input.Pin] = !input.Float
+		r.levels[input.Pin] = input.Level
+	}
+}
+
+// Record the outputs that changed.
+func (r *Rtc) record(cycle uint64) {
+	for pin := 0; pin < RtcGpioCount; pin++ {
+		level, driven := r.Output(pin)
+		output := uint32(0)
+		if level {
+			output |= 1
+		}
+		if driven {
+			output |= 2
+		}
+		if output == r.output[pin] {
+			continue
+		}
+		r.output[pin] = output
+		r.Changes = append(r.Changes, PinChange{
+			Cycle:  cycle,
+			Pin:    pin,
+			Level:  level,
+			Driven: driven,
+		})
+	}
+}
+
+// The mask for a field that is high-low+1 bits wide.
+func mask(high uint32, low uint32) uint32 {
+	if high < low {
+		return 0
+	}
+	width := high - low + 1
+	if width >= 32 {
+		return 0xFFFFFFFF
+	}
+	return 1<<width - 1
+}
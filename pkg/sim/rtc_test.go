@@ -0,0 +1,123 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+// The number of ULP cycles in a millisecond.
+const ms = UlpClock / 1000
+
+// Run an example until the cycle limit.
+func runExample(t *testing.T, name string, inputs []PinInput, cycles uint64) *Simulator {
+	t.Helper()
+	code, err := os.ReadFile("../../example/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Simulator{Out: &bytes.Buffer{}, MaxCycles: cycles}
+	s.Rtc.Inputs = inputs
+	err = s.Load(build(t, string(code), false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Run()
+	if !errors.Is(err, ErrCycleLimit) {
+		t.Fatalf("expected the cycle limit, got %v", err)
+	}
+	return &s
+}
+
+// The changes to the output of a pin.
+func pinChanges(s *Simulator, pin int) []PinChange {
+	changes := make([]PinChange, 0)
+	for _, c := range s.Rtc.Changes {
+		if c.Pin == pin {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// Check that the cycle is within 10% after the expected cycle.
+func checkCycle(t *testing.T, what string, cycle uint64, expect uint64) {
+	t.Helper()
+	if cycle < expect || cycle > expect+expect/10+1000 {
+		t.Errorf("expected %s at about cycle %d, got %d", what, expect, cycle)
+	}
+}
+
+func TestBlink(t *testing.T) {
+	s := runExample(t, "blink.f", nil, 3500*ms)
+	changes := pinChanges(s, 12) // gpio2
+	if len(changes) != 5 {
+		t.Fatalf("expected 5 changes, got %v", changes)
+	}
+	if !changes[0].Driven || changes[0].Level {
+		t.Errorf("expected the output to be enabled low, got %v", changes[0])
+	}
+	for i, c := range changes[1:] {
+		if !c.Driven || c.Level != (i%2 == 0) {
+			t.Errorf("unexpected change %v", c)
+		}
+		checkCycle(t, "a change", c.Cycle-changes[1].Cycle, uint64(i)*1000*ms)
+	}
+}
+
+func TestReadPin(t *testing.T) {
+	inputs := []PinInput{
+		{Cycle: 100 * ms, Pin: 9, Level: false}, // press the button on gpio32
+		{Cycle: 200 * ms, Pin: 9, Float: true},  // release it, the pullup reads high
+		{Cycle: 300 * ms, Pin: 9, Level: false},
+	}
+	s := runExample(t, "read_pin.f", inputs, 400*ms)
+	changes := pinChanges(s, 12) // gpio2
+	if len(changes) != 4 {
+		t.Fatalf("expected 4 changes, got %v", changes)
+	}
+	if !changes[0].Driven || changes[0].Level || changes[0].Cycle > ms {
+		t.Errorf("expected the led to start off, got %v", changes[0])
+	}
+	for i, c := range changes[1:] {
+		if !c.Driven || c.Level != (i%2 == 0) {
+			t.Errorf("unexpected change %v", c)
+		}
+		checkCycle(t, "a change", c.Cycle, inputs[i].Cycle)
+	}
+}
+
+func TestWake(t *testing.T) {
+	s := runExample(t, "wake.f", nil, 2500*ms)
+	if len(s.Rtc.Wakes) != 3 {
+		t.Fatalf("expected 3 wakes, got %v", s.Rtc.Wakes)
+	}
+	for i, cycle := range s.Rtc.Wakes {
+		checkCycle(t, "a wake", cycle, uint64(i)*1000*ms)
+	}
+	if !s.Rtc.Awake {
+		t.Errorf("expected the main processor to be awake")
+	}
+}
+
+func TestRtcClock(t *testing.T) {
+	// 15000 ticks of the slow clock is 100 ms
+	s := Simulator{Out: &bytes.Buffer{}, MaxCycles: 200 * ms}
+	err := s.Load(build(t, ": MAIN 15000 0 RTC_CLOCK_DELAY ESP.DONE ;", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCycle(t, "the end of the delay", s.Cycles, 100*ms)
+}
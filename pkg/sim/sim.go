@@ -0,0 +1,223 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+
+// Package sim runs cross compiled ULP programs in the ulp-c
+// emulator, servicing the requests that the ULP makes to the
+// host the same way that the esp32 test application does.
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Molorius/ulp-c/pkg/emu"
+	"github.com/Molorius/ulp-forth/pkg/forth"
+)
+
+// The functions that the ULP asks the host to run. The function
+// is written to HOST_FUNC and its parameter to HOST_PARAM0.
+const (
+	HostFuncAck       = 0 // the host finished the last request
+	HostFuncDone      = 1 // the program is finished
+	HostFuncPrintU16  = 2 // print the parameter as an unsigned number
+	HostFuncPrintChar = 3 // print the lower byte of the parameter
+)
+
+// The cells of the shared memory at the start of .boot.data,
+// in the order that the interpreter places them.
+const (
+	mutexFlag0 = 0
+	hostFunc   = 3
+	hostParam0 = 4
+)
+
+// The ULP-FSM instructions that the emulator doesn't run,
+// and wake which is also passed to the RTC model.
+const (
+	opRegWr = 1
+	opRegRd = 2
+	opWake  = 9
+	opHalt  = 11
+)
+
+// The other ULP-FSM instructions with their own cycle counts.
+const (
+	opWait = 4
+	opSt   = 6
+	opAlu  = 7
+	opJump = 8
+	opLd   = 13
+)
+
+// The number of instructions run between checks of the timeout.
+const timeoutCheck = 4096
+
+var (
+	ErrCycleLimit = errors.New("exceeded the cycle limit")
+	ErrTimeout    = errors.New("exceeded the timeout")
+)
+
+// Runs a ULP binary in the emulator.
+type Simulator struct {
+	Emu       emu.UlpEmu
+	Rtc       Rtc           // the RTC registers and pins
+	Out       io.Writer     // where the printed output is written
+	MaxCycles uint64        // stop after this many cycles, 0 for no limit
+	Timeout   time.Duration // stop after this much real time, 0 for no limit
+	Cycles    uint64        // the number of cycles run so far
+	Done      bool          // the program sent DONE
+	Halts     int           // the number of times the program halted
+
+	dataOffset int    // the cell that .boot.data starts at
+	prevFlag   uint32 // the last value of MUTEX_FLAG0
+}
+
+// Load the binary created by the assembler and start from the entry.
+func (s *Simulator) Load(bin []byte) error {
+	if len(bin) < 12 {
+		return fmt.Errorf("the ULP binary is too short")
+	}
+	err := s.Emu.LoadBinary(bin)
+	if err != nil {
+		return err
+	}
+	textSize := int(bin[6]) | int(bin[7])<<8
+	s.dataOffset = textSize / 4
+	s.Cycles = 0
+	s.Done = false
+	s.Halts = 0
+	s.prevFlag = 0
+	s.Rtc.Reset()
+	return nil
+}
+
+// Run until the program sends DONE or a limit is reached.
+func (s *Simulator) Run() error {
+	return s.RunUntil(func() bool { return false })
+}
+
+// Run until the program sends DONE, a limit is reached,
+// or stop returns true after an instruction.
+func (s *Simulator) RunUntil(stop func() bool) error {
+	start := time.Now()
+	for steps := 0; !s.Done; steps++ {
+		if s.MaxCycles != 0 && s.Cycles >= s.MaxCycles {
+			return fmt.Errorf("%w of %d cycles", ErrCycleLimit, s.MaxCycles)
+		}
+		if s.Timeout != 0 && steps%timeoutCheck == 0 && time.Since(start) > s.Timeout {
+			return fmt.Errorf("%w of %s after %d cycles", ErrTimeout, s.Timeout, s.Cycles)
+		}
+		err := s.Step()
+		if err != nil {
+			return err
+		}
+		if stop() {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Run a single instruction, then service any request
+// that the ULP made to the host.
+func (s *Simulator) Step() error {
+	instr, err := s.Emu.Fetch()
+	if err != nil {
+		return fmt.Errorf("emulation error at 0x%X: %w", s.Emu.IP, err)
+	}
+	switch instr >> 28 {
+	case opHalt:
+		// the ULP timer starts the program at the entry again
+		s.Emu.IP = 0
+		s.Halts += 1
+	case opRegWr:
+		s.Rtc.Write(instr&0x3FF, bits(instr, 23, 5), bits(instr, 18, 5), bits(instr, 10, 8), s.Cycles)
+		s.Emu.IP += 1
+	case opRegRd:
+		s.Emu.R[0] = s.Rtc.Read(instr&0x3FF, bits(instr, 23, 5), bits(instr, 18, 5), s.Cycles)
+		s.Emu.IP += 1
+	default:
+		err = s.Emu.DecodeExecute(instr)
+		if err != nil {
+			return fmt.Errorf("emulation error at 0x%X: %w", s.Emu.IP, err)
+		}
+		if instr>>28 == opWake {
+			s.Rtc.Wake(s.Cycles)
+		}
+	}
+	s.Cycles += instructionCycles(instr)
+	return s.service()
+}
+
+// Service a request when the ULP gives up the mutex,
+// like the esp32 test application.
+func (s *Simulator) service() error {
+	flag := s.cell(mutexFlag0)
+	given := s.prevFlag == 1 && flag == 0
+	s.prevFlag = flag
+	if !given {
+		return nil
+	}
+	fn := s.cell(hostFunc)
+	param := s.cell(hostParam0)
+	s.Emu.Memory[s.dataOffset+hostFunc] = 0 // acknowledge it
+	switch fn {
+	case HostFuncAck:
+	case HostFuncDone:
+		s.Done = true
+	case HostFuncPrintU16:
+		fmt.Fprintf(s.Out, "%d ", param)
+	case HostFuncPrintChar:
+		fmt.Fprintf(s.Out, "%c", param&0xFF)
+	default:
+		return fmt.Errorf("unknown host function %d", fn)
+	}
+	return nil
+}
+
+// The lower 16 bits of a cell of .boot.data.
+func (s *Simulator) cell(offset int) uint32 {
+	return s.Emu.Memory[s.dataOffset+offset] & 0xFFFF
+}
+
+// The number of cycles that an instruction takes,
+// from the same table as the cross compiler timing.
+func instructionCycles(instr uint32) uint64 {
+	name := "move" // the peripherals take at least as long as this
+	switch instr >> 28 {
+	case opAlu:
+		name = "add"
+	case opJump:
+		name = "jump"
+	case opSt:
+		name = "st"
+	case opLd:
+		name = "ld"
+	case opRegRd:
+		name = "reg_rd"
+	case opRegWr:
+		name = "reg_wr"
+	case opWake:
+		name = "wake"
+		if bits(instr, 25, 3) == 1 {
+			name = "sleep"
+		}
+	case opWait:
+		return uint64(forth.InstructionCycles["wait"]) + uint64(instr&0xFFFF)
+	case opHalt:
+		name = "halt"
+	}
+	return uint64(forth.InstructionCycles[name])
+}
+
+// The bits of an instruction starting at the offset.
+func bits(instr uint32, offset uint32, width uint32) uint32 {
+	return (instr >> offset) & (1<<width - 1)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Molorius/ulp-c/pkg/asm"
+	"github.com/Molorius/ulp-forth/pkg/forth"
+)
+
+// Set up a virtual machine with the esp32 words and execute the code.
+func setupVm(t *testing.T, code string) *forth.VirtualMachine {
+	t.Helper()
+	vm := forth.VirtualMachine{}
+	err := vm.Setup()
+	if err != nil {
+		t.Fatalf("failed to set up vm: %s", err)
+	}
+	err = vm.BuiltinEsp32()
+	if err != nil {
+		t.Fatalf("failed to set up esp32 words: %s", err)
+	}
+	err = vm.Execute([]byte(code))
+	if err != nil {
+		t.Fatalf("failed to execute test code: %s", err)
+	}
+	return &vm
+}
+
+// Cross compile MAIN and assemble it.
+func buildVm(t *testing.T, vm *forth.VirtualMachine, subroutine bool) []byte {
+	t.Helper()
+	ulp := forth.Ulp{}
+	var assembly string
+	var err error
+	if subroutine {
+		assembly, err = ulp.BuildAssemblySrt(vm, "MAIN")
+	} else {
+		assembly, err = ulp.BuildAssembly(vm, "MAIN")
+	}
+	if err != nil {
+		t.Fatalf("failed to generate assembly: %s", err)
+	}
+	assembler := asm.Assembler{}
+	bin, err := assembler.BuildFile(assembly, "test.S", 8176, false)
+	if err != nil {
+		t.Fatalf("failed to assemble: %s", err)
+	}
+	return bin
+}
+
+// Cross compile the forth code and assemble it.
+func build(t *testing.T, code string, subroutine bool) []byte {
+	t.Helper()
+	return buildVm(t, setupVm(t, code), subroutine)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		expect string
+		halts  int
+	}{
+		{
+			name:   "print",
+			code:   ": MAIN 1 u. 2 u. 'A' EMIT ESP.DONE ;",
+			expect: "1 2 A",
+		},
+		{
+			name:   "halt",
+			code:   ": MAIN 1 u. HALT 2 u. HALT HALT 3 u. ESP.DONE ;",
+			expect: "1 2 3 ",
+			halts:  3,
+		},
+	}
+	for _, tt := range tests {
+		for _, subroutine := range []bool{false, true} {
+			name := tt.name + " token threaded"
+			if subroutine {
+				name = tt.name + " subroutine threaded"
+			}
+			t.Run(name, func(t *testing.T) {
+				var out bytes.Buffer
+				s := Simulator{Out: &out, MaxCycles: 1_000_000}
+				err := s.Load(build(t, tt.code, subroutine))
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = s.Run()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if out.String() != tt.expect {
+					t.Errorf("expected \"%s\" got \"%s\"", tt.expect, out.String())
+				}
+				if s.Halts != tt.halts {
+					t.Errorf("expected %d halts got %d", tt.halts, s.Halts)
+				}
+				if !s.Done || s.Cycles == 0 {
+					t.Errorf("expected the program to finish, done %t after %d cycles", s.Done, s.Cycles)
+				}
+			})
+		}
+	}
+}
+
+func TestCycleLimit(t *testing.T) {
+	s := Simulator{Out: &bytes.Buffer{}, MaxCycles: 10_000}
+	err := s.Load(build(t, ": MAIN BEGIN AGAIN ;", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Run()
+	if !errors.Is(err, ErrCycleLimit) {
+		t.Fatalf("expected the cycle limit, got %v", err)
+	}
+	if s.Done || s.Cycles < 10_000 {
+		t.Errorf("stopped early, done %t after %d cycles", s.Done, s.Cycles)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// The nanoseconds in a cycle of the ULP clock.
+const cycleNs = 1_000_000_000 / UlpClock
+
+// The gpio number of each RTC GPIO on the esp32,
+// the reverse of GPIO_NUMBER_TO_RTC.
+var gpioNumbers = [RtcGpioCount]int{36, 37, 38, 39, 34, 35, 25, 26, 33, 32, 4, 0, 2, 15, 13, 12, 14, 27}
+
+// Write the changes to the outputs as a value change dump that
+// can be opened by GTKWave. Only the pins that changed are
+// included, they are high impedance until the output is enabled.
+// The times are in nanoseconds, the dump ends at the cycle.
+func (r *Rtc) WriteVcd(w io.Writer, end uint64) error {
+	b := bufio.NewWriter(w)
+	used := make([]bool, RtcGpioCount)
+	for _, c := range r.Changes {
+		used[c.Pin] = true
+	}
+	fmt.Fprintln(b, "$version ulp-forth $end")
+	fmt.Fprintln(b, "$timescale 1ns $end")
+	fmt.Fprintln(b, "$scope module esp32 $end")
+	for pin, ok := range used {
+		if ok {
+			fmt.Fprintf(b, "$var wire 1 %s gpio%d $end\n", vcdId(pin), gpioNumbers[pin])
+		}
+	}
+	fmt.Fprintln(b, "$upscope $end")
+	fmt.Fprintln(b, "$enddefinitions $end")
+	fmt.Fprintln(b, "#0")
+	fmt.Fprintln(b, "$dumpvars")
+	for pin, ok := range used {
+		if ok {
+			fmt.Fprintf(b, "z%s\n", vcdId(pin))
+		}
+	}
+	fmt.Fprintln(b, "$end")
+	time := uint64(0)
+	for _, c := range r.Changes {
+		if c.Cycle != time {
+			time = c.Cycle
+			fmt.Fprintf(b, "#%d\n", time*cycleNs)
+		}
+		value := "z"
+		if c.Driven && c.Level {
+			value = "1"
+		} else if c.Driven {
+			value = "0"
+		}
+		fmt.Fprintf(b, "%s%s\n", value, vcdId(c.Pin))
+	}
+	if end > time {
+		fmt.Fprintf(b, "#%d\n", end*cycleNs)
+	}
+	return b.Flush()
+}
+
+// The identifier of a pin in the value change dump.
+func vcdId(pin int) string {
+	return string(rune('!' + pin))
+}
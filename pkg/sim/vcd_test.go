@@ -0,0 +1,204 @@
+/*
+Copyright 2024-2025 Blake Felt blake.w.felt@gmail.com
+
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+*/
+package sim
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A value of a signal in a value change dump.
+type vcdValue struct {
+	time  uint64
+	value byte
+}
+
+// Read the values of the signal with the name from a value change dump.
+func readVcd(t *testing.T, vcd string, name string) []vcdValue {
+	t.Helper()
+	id := ""
+	time := uint64(0)
+	values := make([]vcdValue, 0)
+	for _, line := range strings.Split(vcd, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 6 && fields[0] == "$var" && fields[4] == name:
+			id = fields[3]
+		case strings.HasPrefix(line, "#"):
+			n, err := strconv.ParseUint(line[1:], 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			time = n
+		case id != "" && len(line) == 1+len(id) && line[1:] == id:
+			values = append(values, vcdValue{time, line[0]})
+		}
+	}
+	if id == "" {
+		t.Fatalf("%s is not in the dump", name)
+	}
+	return values
+}
+
+// The value of the signal at the time.
+func valueAt(values []vcdValue, time uint64) byte {
+	value := byte('x')
+	for _, v := range values {
+		if v.time > time {
+			break
+		}
+		value = v.value
+	}
+	return value
+}
+
+func TestVcd(t *testing.T) {
+	r := Rtc{Changes: []PinChange{
+		{Cycle: 0, Pin: 12, Level: false, Driven: true},
+		{Cycle: 0, Pin: 14, Level: true, Driven: true},
+		{Cycle: 8, Pin: 12, Level: true, Driven: true},
+		{Cycle: 16, Pin: 14, Level: true, Driven: false},
+	}}
+	var b bytes.Buffer
+	err := r.WriteVcd(&b, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := strings.Join([]string{
+		"$version ulp-forth $end",
+		"$timescale 1ns $end",
+		"$scope module esp32 $end",
+		"$var wire 1 - gpio2 $end",
+		"$var wire 1 / gpio13 $end",
+		"$upscope $end",
+		"$enddefinitions $end",
+		"#0",
+		"$dumpvars",
+		"z-",
+		"z/",
+		"$end",
+		"0-",
+		"1/",
+		"#1000",
+		"1-",
+		"#2000",
+		"z/",
+		"#10000",
+		"",
+	}, "\n")
+	if b.String() != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, b.String())
+	}
+}
+
+// The serial write pads the 0 bits so they take as long as
+// the 1 bits, every bit must change on a period boundary.
+func TestSerialWaveform(t *testing.T) {
+	for _, subroutine := range []bool{false, true} {
+		t.Run(fmt.Sprintf("subroutine %v", subroutine), func(t *testing.T) {
+			serialWaveform(t, subroutine)
+		})
+	}
+}
+
+func serialWaveform(t *testing.T, subroutine bool) {
+	code := `
+		14 SERIAL.WRITE_9600_BAUD SERIAL.WRITE_CREATE TX9600
+		14 SERIAL.WRITE_115200_BAUD SERIAL.WRITE_CREATE TX115200
+		: MAIN
+			GPIO13.ENABLE GPIO13.OUTPUT_ENABLE GPIO13.SET_HIGH
+			'U' TX9600 'A' TX115200 'z' TX9600
+			ESP.DONE
+		;
+		SERIAL.WRITE_9600_BAUD
+		SERIAL.WRITE_115200_BAUD
+	`
+	vm := setupVm(t, code)
+	wait115200, err := vm.Stack.PopNumber()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait9600, err := vm.Stack.PopNumber()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Simulator{Out: &bytes.Buffer{}, MaxCycles: 1_000_000}
+	err = s.Load(buildVm(t, vm, subroutine))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	err = s.Rtc.WriteVcd(&b, s.Cycles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := readVcd(t, b.String(), "gpio13")
+
+	// the time from one write to the next, see SERIAL.WRITE_CREATE
+	periods := []uint64{
+		(42 + uint64(wait9600)) * cycleNs,
+		(42 + uint64(wait115200)) * cycleNs,
+		(42 + uint64(wait9600)) * cycleNs,
+	}
+	expect := "UAz"
+	time := valueAtHigh(t, values)
+	for i, period := range periods {
+		// find the start bit
+		start := uint64(0)
+		for _, v := range values {
+			if v.time > time && v.value == '0' {
+				start = v.time
+				break
+			}
+		}
+		if start == 0 {
+			t.Fatalf("no start bit for character %d", i)
+		}
+		// every bit is written at the same point in the loop
+		for _, v := range values {
+			if v.time >= start && v.time < start+10*period && (v.time-start)%period != 0 {
+				t.Errorf("character %d changed off of a bit boundary at %d ns", i, v.time)
+			}
+		}
+		c := 0
+		for bit := uint64(0); bit < 10; bit++ {
+			value := valueAt(values, start+bit*period+period/2)
+			switch {
+			case bit == 0 && value != '0':
+				t.Errorf("character %d has no start bit", i)
+			case bit == 9 && value != '1':
+				t.Errorf("character %d has no stop bit", i)
+			case bit > 0 && bit < 9 && value == '1':
+				c |= 1 << (bit - 1)
+			}
+		}
+		if c != int(expect[i]) {
+			t.Errorf("expected character %q got %q", expect[i], c)
+		}
+		time = start + 9*period
+	}
+}
+
+// The time that the serial line first goes idle.
+func valueAtHigh(t *testing.T, values []vcdValue) uint64 {
+	t.Helper()
+	for _, v := range values {
+		if v.value == '1' {
+			return v.time
+		}
+	}
+	t.Fatalf("the serial line never goes high")
+	return 0
+}
@@ -24,6 +24,7 @@ c" __safe_jump:\n"
 2 \ number of assembly objects to compile
 assembly-both asm-pulse
 token_next_skip_load last set-ulp-asm-next
+0 0 last set-stack-effect
 
 : main
     pin_init